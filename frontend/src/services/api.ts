@@ -4,7 +4,14 @@ import type {
   SearchFilters,
   DashboardStats,
   RecommendationPrediction,
+  ReviewItem,
+  ReviewOrder,
+  ReviewSession,
+  SavedSearch,
+  BulkVote,
+  BulkVoteResult,
   PaginatedResponse,
+  SellerListings,
 } from '../types';
 
 const API_BASE_URL = 'http://localhost:8000';
@@ -57,7 +64,9 @@ class ApiService {
   async searchListings(filters: SearchFilters): Promise<PaginatedResponse<Listing>> {
     const params = new URLSearchParams();
     Object.entries(filters).forEach(([key, value]) => {
-      if (value !== undefined && value !== '') {
+      if (Array.isArray(value)) {
+        value.forEach((item) => params.append(key, item));
+      } else if (value !== undefined && value !== '') {
         params.append(key, value.toString());
       }
     });
@@ -77,12 +86,41 @@ class ApiService {
     return this.request<string[]>(`/autocomplete/styles/?term=${encodeURIComponent(term)}`);
   }
 
+  async getTagAutocomplete(term: string): Promise<string[]> {
+    return this.request<string[]>(`/autocomplete/tag/?term=${encodeURIComponent(term)}`);
+  }
+
+  // Saved searches
+  async saveSearch(name: string, filters: SearchFilters): Promise<SavedSearch> {
+    // Saved searches keep the filters, not the page
+    const params: SearchFilters = { ...filters };
+    delete params.page;
+    return this.request<SavedSearch>('/searches/', {
+      method: 'POST',
+      body: JSON.stringify({ name, params }),
+    });
+  }
+
+  async getSavedSearches(): Promise<SavedSearch[]> {
+    return this.request<SavedSearch[]>('/searches/');
+  }
+
+  async runSavedSearch(searchId: number, page = 1): Promise<PaginatedResponse<Listing>> {
+    return this.request<PaginatedResponse<Listing>>(`/searches/${searchId}/run?page=${page}`);
+  }
+
+  // Listing endpoints
+  async getListingsByIds(listingIds: number[]): Promise<Listing[]> {
+    return this.request<Listing[]>(`/listings/?ids=${listingIds.join(',')}`);
+  }
+
   // Seller endpoints
   async searchSellerListings(sellerName: string): Promise<Listing[]> {
-    return this.request<Listing[]>('/by-seller/search/', {
+    const response = await this.request<SellerListings>('/by-seller/search/', {
       method: 'POST',
       body: JSON.stringify({ seller: sellerName }),
     });
+    return response.listings;
   }
 
   async triggerSellerScrape(sellerName: string): Promise<{ message: string }> {
@@ -103,18 +141,38 @@ class ApiService {
     return this.request<RecommendationPrediction[]>(`/recommendation-predictions/?${params}`);
   }
 
-  async submitRecommendations(listingIds: number[], keeperIds: number[]): Promise<{ success: boolean }> {
+  async getReviewQueue(size = 20, order: ReviewOrder = 'probability'): Promise<ReviewItem[]> {
+    return this.request<ReviewItem[]>(`/recommendations/queue/?size=${size}&order=${order}`);
+  }
+
+  async submitRecommendations(listingIds: number[], keeperIds: number[]): Promise<{ success: boolean; session_id?: number }> {
     const formData = new FormData();
     listingIds.forEach(id => formData.append('listing_ids', id.toString()));
     keeperIds.forEach(id => formData.append('keeper_ids', id.toString()));
 
-    return this.request<{ success: boolean }>('/submit-scoring-selections/', {
+    return this.request<{ success: boolean; session_id?: number }>('/submit-scoring-selections/', {
       method: 'POST',
       headers: {}, // Remove Content-Type to let browser set it for FormData
       body: formData,
     });
   }
 
+  async getReviewSessions(limit = 50): Promise<ReviewSession[]> {
+    return this.request<ReviewSession[]>(`/recommendations/sessions/?limit=${limit}`);
+  }
+
+  async undoRecommendations(sessionId: number, forget = false): Promise<{ success: boolean; undone: number; undone_sessions: number[] }> {
+    const formData = new FormData();
+    formData.append('session_id', sessionId.toString());
+    formData.append('forget', forget.toString());
+
+    return this.request<{ success: boolean; undone: number; undone_sessions: number[] }>('/recommendations/undo/', {
+      method: 'POST',
+      headers: {},
+      body: formData,
+    });
+  }
+
   async getModelPerformanceStats(): Promise<{
     accuracy: number;
     total_sessions: number;
@@ -157,6 +215,25 @@ class ApiService {
     });
   }
 
+  // Record notes and tags
+  async getRecords(tag?: string, limit = 50): Promise<Record[]> {
+    const params = new URLSearchParams({ limit: limit.toString() });
+    if (tag) {
+      params.append('tag', tag);
+    }
+    return this.request<Record[]>(`/records/?${params}`);
+  }
+
+  async updateRecordNotes(
+    recordId: number,
+    changes: { notes?: string; tags?: string[] }
+  ): Promise<Record> {
+    return this.request<Record>(`/records/${recordId}/notes`, {
+      method: 'PATCH',
+      body: JSON.stringify(changes),
+    });
+  }
+
   // Record of the Day voting
   async voteRecordOfTheDay(
     recordId: number,
@@ -173,6 +250,19 @@ class ApiService {
       body: formData,
     });
   }
+
+  // Rate several past records of the day at once
+  async voteRecordsOfTheDay(
+    votes: BulkVote[]
+  ): Promise<{ accepted: number; rejected: number; results: BulkVoteResult[] }> {
+    return this.request<{ accepted: number; rejected: number; results: BulkVoteResult[] }>(
+      '/vote-record-of-the-day/bulk/',
+      {
+        method: 'POST',
+        body: JSON.stringify({ votes }),
+      }
+    );
+  }
 }
 
 export const apiService = new ApiService();
@@ -4,6 +4,7 @@ export interface Record {
   artist: string;
   title: string;
   format: string;
+  format_descriptions: string[];
   label: string;
   catno: string | null;
   wants: number;
@@ -13,12 +14,17 @@ export interface Record {
   styles: string[];
   suggested_price: string;
   year: number | null;
+  metadata: { [key: string]: unknown };
+  notes: string;
+  tags: string[];
 }
 
 export interface Seller {
   id: number;
   name: string;
   currency: string;
+  rating: number | null;
+  feedback_count: number;
 }
 
 export interface Listing {
@@ -31,6 +37,13 @@ export interface Listing {
   kept: boolean;
   evaluated: boolean;
   predicted_keeper: boolean;
+  ships_from: string;
+}
+
+export interface SellerListings {
+  sellers: string[];
+  listings: Listing[];
+  truncated: boolean;
 }
 
 export interface RecommendationModel {
@@ -55,6 +68,8 @@ export interface RecordOfTheDay {
   date: string;
   listing: Listing;
   created_at: string;
+  breakdown: ThermodynamicBreakdown;
+  // Deprecated: only set for picks made before breakdown was stored whole
   model_score: number;
   entropy_measure: number;
   system_temperature: number;
@@ -104,6 +119,8 @@ export interface SearchFilters {
   max_price?: string;
   condition?: string;
   seller?: string;
+  tag?: string[];
+  tag_match?: 'any' | 'all';
   sort?: string;
   page?: number;
 }
@@ -121,6 +138,43 @@ export interface RecommendationPrediction {
   probability: number;
 }
 
+export interface ReviewItem extends Listing {
+  prediction: boolean;
+  probability: number;
+}
+
+export type ReviewOrder = 'probability' | 'score' | 'demand' | 'random';
+
+export interface ReviewSession {
+  id: number;
+  listing_ids: number[];
+  keeper_ids: number[];
+  accuracy: number | null;
+  created_at: string;
+  undone_at: string | null;
+}
+
+export interface SavedSearch {
+  id: number;
+  name: string;
+  params: { [key: string]: string[] };
+  created_at: string;
+}
+
+export interface BulkVote {
+  id: number;
+  desirability: number;
+  novelty: number;
+}
+
+export interface BulkVoteResult {
+  id: number;
+  success: boolean;
+  error?: string;
+  average_desirability?: number;
+  average_novelty?: number;
+}
+
 export interface ApiError {
   error: string;
   details?: string;
@@ -1,38 +1,73 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"discogs-api/internal/config"
 	"discogs-api/internal/database"
+	"discogs-api/internal/scraper"
 	"discogs-api/internal/services"
-
-	"github.com/joho/godotenv"
 )
 
+// userList collects usernames from repeated or comma-separated -user flags
+type userList []string
+
+func (u *userList) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *userList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*u = append(*u, name)
+		}
+	}
+	return nil
+}
+
 func main() {
 	// Command line flags
+	var usernames userList
+	flag.Var(&usernames, "user", "Discogs username to scrape (comma-separated or repeated)")
 	var (
-		username = flag.String("user", "", "Discogs username to scrape")
-		test     = flag.Bool("test", false, "Test connection to Discogs API")
-		stats    = flag.Bool("stats", false, "Show scraper statistics")
+		test        = flag.Bool("test", false, "Test connection to Discogs API")
+		stats       = flag.Bool("stats", false, "Show scraper statistics")
+		sellersFile = flag.String("sellers-file", "", "File of newline-separated usernames to scrape (# starts a comment)")
+		fullScan    = flag.Bool("full", false, "Scan the whole inventory and remove listings no longer for sale")
+		timeout     = flag.Duration("timeout", 0, "Per-request Discogs timeout (overrides SCRAPER_REQUEST_TIMEOUT)")
+		envFile     = flag.String("env", "", "Path to the .env file (overrides ENV_FILE; defaults to ../../.env)")
 	)
 	flag.Parse()
 
 	// Load environment variables
-	if err := godotenv.Load("../../.env"); err != nil {
-		log.Println("Warning: .env file not found, using system environment variables")
+	if err := config.LoadEnvFile(*envFile, "../../.env"); err != nil {
+		log.Fatal(err)
+	}
+
+	if *sellersFile != "" {
+		fromFile, err := readSellersFile(*sellersFile)
+		if err != nil {
+			log.Fatal("Failed to read sellers file:", err)
+		}
+		usernames = append(usernames, fromFile...)
 	}
 
 	// Initialize configuration
 	cfg := config.Load()
+	if *timeout > 0 {
+		cfg.Scraper.RequestTimeout = *timeout
+	}
 
-	// Check if required environment variables are set
-	if cfg.External.DiscogsConsumerKey == "" || cfg.External.DiscogsConsumerSecret == "" {
-		log.Fatal("DISCOGS_CONSUMER_KEY and DISCOGS_CONSUMER_SECRET must be set in environment variables")
+	// Check if required environment variables are set; without a token the
+	// CLI asks for authorization on stdin and saves the one it gets
+	if missing := cfg.External.MissingDiscogsConsumerCredentials(); len(missing) > 0 {
+		log.Fatal(strings.Join(missing, " and ") + " must be set in environment variables")
 	}
 
 	// Initialize database (optional for CLI tool)
@@ -56,18 +91,23 @@ func main() {
 		testConnection(cfg)
 	case *stats:
 		showStats(scraperService)
-	case *username != "":
-		scrapeUser(*username, scraperService)
+	case len(usernames) > 0:
+		scrapeUsers(usernames, *fullScan, scraperService)
 	default:
 		fmt.Println("Discogs Go Scraper CLI")
 		fmt.Println("Usage:")
-		fmt.Println("  -user <username>  Scrape a user's inventory")
+		fmt.Println("  -user <username>  Scrape a user's inventory (comma-separated or repeated for several)")
+		fmt.Println("  -sellers-file <f> Scrape every username listed in a file, one per line")
+		fmt.Println("  -full             With -user, scan every page and remove sold listings")
 		fmt.Println("  -test             Test connection to Discogs API")
 		fmt.Println("  -stats            Show scraper statistics")
+		fmt.Println("  -timeout <dur>    Per-request timeout, e.g. 90s (default 60s)")
 		fmt.Println()
 		fmt.Println("Examples:")
 		fmt.Println("  go run main.go -test")
 		fmt.Println("  go run main.go -user someuser")
+		fmt.Println("  go run main.go -user alice,bob -user carol")
+		fmt.Println("  go run main.go -sellers-file sellers.txt")
 		fmt.Println("  go run main.go -stats")
 	}
 }
@@ -107,29 +147,135 @@ func showStats(scraperService *services.ScraperService) {
 	fmt.Printf("  Total Sellers: %v\n", stats["total_sellers"])
 	fmt.Printf("  Current Requests: %v\n", stats["current_requests"])
 	fmt.Printf("  Current Sleep Time: %v\n", stats["current_sleep_time"])
+	if criteria, ok := stats["keeper_criteria"].(scraper.KeeperCriteria); ok {
+		fmt.Printf("  Keeper Wants Comparison: %s", criteria.WantsComparison)
+		if criteria.WantsComparison == scraper.WantsMinRatio {
+			fmt.Printf(" (min ratio %.2f)", criteria.MinWantsRatio)
+		}
+		fmt.Println()
+		if criteria.MaxKeeperPrice > 0 {
+			fmt.Printf("  Keeper Max Price: %.2f\n", criteria.MaxKeeperPrice)
+		}
+		if len(criteria.AlwaysKeep) > 0 || len(criteria.NeverKeep) > 0 {
+			fmt.Printf("  Keeper Overrides: %d always kept, %d never kept\n", len(criteria.AlwaysKeep), len(criteria.NeverKeep))
+		}
+	}
+	if decisions, ok := stats["keeper_decisions"].(map[scraper.KeeperRule]int); ok && len(decisions) > 0 {
+		fmt.Println("  Keeper Decisions:")
+		for _, rule := range []scraper.KeeperRule{scraper.KeptAlways, scraper.RejectedNever, scraper.DecidedByCriteria} {
+			if n := decisions[rule]; n > 0 {
+				fmt.Printf("    %s: %d\n", rule, n)
+			}
+		}
+	}
+	if rejections, ok := stats["keeper_rejections"].(map[scraper.RejectReason]int); ok && len(rejections) > 0 {
+		fmt.Println("  Keeper Rejections:")
+		for _, reason := range []scraper.RejectReason{scraper.RejectFormat, scraper.RejectCondition, scraper.RejectWants, scraper.RejectPrice, scraper.RejectNeverKeep} {
+			if n := rejections[reason]; n > 0 {
+				fmt.Printf("    %s: %d\n", reason, n)
+			}
+		}
+	}
 }
 
-func scrapeUser(username string, scraperService *services.ScraperService) {
+// scrapeUsers scrapes each user in turn with the same service, so they share
+// one rate limiter. A failure is reported and the next user is still scraped.
+func scrapeUsers(usernames []string, fullScan bool, scraperService *services.ScraperService) {
 	if scraperService == nil {
 		log.Fatal("Database connection required for scraping")
 	}
 
+	var totalRecords, newRecords, newKeepers, markedUnavailable int
+	var failed []string
+	failures := make(map[string]error)
+	for i, username := range usernames {
+		if len(usernames) > 1 {
+			fmt.Printf("\n🔄 Scraping %d of %d\n", i+1, len(usernames))
+		}
+		result, err := scrapeUser(username, fullScan, scraperService)
+		if err != nil {
+			fmt.Printf("\n❌ Scraping failed for %s: %v\n", username, err)
+			failed = append(failed, username)
+			failures[username] = err
+			continue
+		}
+		totalRecords += result.TotalRecords
+		newRecords += result.NewRecords
+		newKeepers += result.NewKeepers
+		markedUnavailable += result.MarkedUnavailable
+	}
+
+	if len(usernames) > 1 {
+		fmt.Println("\n📦 Summary:")
+		fmt.Printf("  Users Scraped: %d/%d\n", len(usernames)-len(failed), len(usernames))
+		fmt.Printf("  Keepers Found: %d\n", totalRecords)
+		fmt.Printf("  New Releases: %d (%d keepers)\n", newRecords, newKeepers)
+		if fullScan {
+			fmt.Printf("  Marked Unavailable: %d\n", markedUnavailable)
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Println("\n❌ Failed sellers:")
+		for _, username := range failed {
+			fmt.Printf("  • %s: %v\n", username, failures[username])
+		}
+		os.Exit(1)
+	}
+}
+
+// readSellersFile reads one username per line, skipping blank lines and # comments
+func readSellersFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var usernames []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		usernames = append(usernames, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return usernames, nil
+}
+
+func scrapeUser(username string, fullScan bool, scraperService *services.ScraperService) (*scraper.ScraperResult, error) {
 	fmt.Printf("🎵 Starting scrape for user: %s\n", username)
 	fmt.Println("This may take several minutes depending on inventory size...")
 	
-	result, err := scraperService.ScrapeUserInventory(username)
+	result, err := scraperService.ScrapeUserInventory(username, scraper.ScrapeOptions{FullScan: fullScan})
 	if err != nil {
-		log.Fatal("Scraping failed:", err)
+		return nil, err
 	}
 
 	if !result.Success {
-		log.Fatal("Scraping failed:", result.Error)
+		return nil, errors.New(result.Error)
 	}
 
 	fmt.Println("\n✅ Scraping completed successfully!")
 	fmt.Printf("  Username: %s\n", result.Username)
-	fmt.Printf("  Total Records Found: %d\n", result.TotalRecords)
-	fmt.Printf("  New Records: %d\n", result.NewRecords)
+	if result.Note != "" {
+		fmt.Printf("  Note: %s\n", result.Note)
+	}
+	if result.BlockedAtPage > 0 {
+		fmt.Printf("  Refused at page: %d\n", result.BlockedAtPage)
+	}
+	fmt.Printf("  Keepers Found: %d\n", result.TotalRecords)
+	if result.ListingCapReached {
+		fmt.Println("  Stopped at SCRAPER_MAX_LISTINGS")
+	}
+	fmt.Printf("  New Releases: %d (%d keepers)\n", result.NewRecords, result.NewKeepers)
+	if result.FullScan {
+		fmt.Printf("  Marked Unavailable: %d\n", result.MarkedUnavailable)
+	}
 	
 	if len(result.Listings) > 0 {
 		fmt.Println("\n🎯 Sample of scraped listings:")
@@ -137,11 +283,17 @@ func scrapeUser(username string, scraperService *services.ScraperService) {
 			if i >= 5 { // Show only first 5
 				break
 			}
-			fmt.Printf("  • %s - %s (%s) - $%.2f\n", 
-				listing.Artist, listing.Title, listing.MediaCondition, listing.RecordPrice)
+			marker := ""
+			if listing.IsNew {
+				marker = " [new]"
+			}
+			fmt.Printf("  • %s - %s (%s) - $%.2f%s\n",
+				listing.Artist, listing.Title, listing.MediaCondition, listing.RecordPrice, marker)
 		}
 		if len(result.Listings) > 5 {
 			fmt.Printf("  ... and %d more listings\n", len(result.Listings)-5)
 		}
 	}
+
+	return result, nil
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/database"
+)
+
+func main() {
+	force := flag.Bool("force", false, "Seed even when the database holds sellers or records that weren't seeded")
+	envFile := flag.String("env", "", "Path to the .env file (overrides ENV_FILE; defaults to ../../.env)")
+	flag.Parse()
+
+	// Load environment variables
+	if err := config.LoadEnvFile(*envFile, "../../.env"); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	if err := database.AutoMigrate(db); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	result, err := database.Seed(db, *force)
+	if errors.Is(err, database.ErrDatabaseNotEmpty) {
+		log.Fatal("Refusing to seed: ", err, "; pass -force to seed anyway")
+	}
+	if err != nil {
+		log.Fatal("Failed to seed database:", err)
+	}
+
+	fmt.Printf("Seeded %d sellers, %d records and %d listings\n", result.Sellers, result.Records, result.Listings)
+}
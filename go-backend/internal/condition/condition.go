@@ -0,0 +1,111 @@
+// Package condition ranks Discogs media conditions so they can be compared
+package condition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Canonical Discogs condition names, best first
+const (
+	Mint         = "Mint (M)"
+	NearMint     = "Near Mint (NM or M-)"
+	VeryGoodPlus = "Very Good Plus (VG+)"
+	VeryGood     = "Very Good (VG)"
+	GoodPlus     = "Good Plus (G+)"
+	Good         = "Good (G)"
+	Fair         = "Fair (F)"
+	Poor         = "Poor (P)"
+)
+
+type grade struct {
+	name    string
+	short   string
+	aliases []string
+}
+
+// grades is ordered best first; a grade's rank is len(grades) minus its index
+var grades = []grade{
+	{Mint, "M", []string{"mint", "m"}},
+	{NearMint, "NM", []string{"near mint", "nm", "m-", "nm or m-", "nm/m-"}},
+	{VeryGoodPlus, "VG+", []string{"very good plus", "vg+"}},
+	{VeryGood, "VG", []string{"very good", "vg"}},
+	{GoodPlus, "G+", []string{"good plus", "g+"}},
+	{Good, "G", []string{"good", "g"}},
+	{Fair, "F", []string{"fair", "f"}},
+	{Poor, "P", []string{"poor", "p"}},
+}
+
+var lookup = func() map[string]int {
+	m := make(map[string]int)
+	for i, g := range grades {
+		m[strings.ToLower(g.name)] = i
+		for _, alias := range g.aliases {
+			m[alias] = i
+		}
+	}
+	return m
+}()
+
+func find(condition string) (int, bool) {
+	i, ok := lookup[strings.ToLower(strings.TrimSpace(condition))]
+	return i, ok
+}
+
+// Normalize returns the canonical name for a condition or one of its
+// abbreviations, e.g. "vg+" becomes "Very Good Plus (VG+)". Unknown
+// conditions are returned trimmed but otherwise unchanged.
+func Normalize(condition string) string {
+	if i, ok := find(condition); ok {
+		return grades[i].name
+	}
+	return strings.TrimSpace(condition)
+}
+
+// Rank orders conditions from Poor (1) to Mint (8). Unknown conditions rank 0.
+func Rank(condition string) int {
+	if i, ok := find(condition); ok {
+		return len(grades) - i
+	}
+	return 0
+}
+
+// Compare returns -1, 0 or 1 as a ranks below, equal to or above b
+func Compare(a, b string) int {
+	ra, rb := Rank(a), Rank(b)
+	switch {
+	case ra < rb:
+		return -1
+	case ra > rb:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast returns every stored spelling of the conditions ranked at or above
+// min: the canonical names plus their short codes. It returns nil if min is
+// not a known condition.
+func AtLeast(min string) []string {
+	i, ok := find(min)
+	if !ok {
+		return nil
+	}
+	var conditions []string
+	for _, g := range grades[:i+1] {
+		conditions = append(conditions, g.name, g.short)
+	}
+	return conditions
+}
+
+// RankSQL returns a SQL CASE expression giving the rank of the condition held
+// in column, matching the canonical names and short codes. Anything else
+// ranks 0.
+func RankSQL(column string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE %s", column)
+	for i, g := range grades {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d WHEN '%s' THEN %d", g.name, len(grades)-i, g.short, len(grades)-i)
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package condition
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRankOrdering(t *testing.T) {
+	ordered := []string{Mint, NearMint, VeryGoodPlus, VeryGood, GoodPlus, Good, Fair, Poor}
+	for i := 1; i < len(ordered); i++ {
+		if Compare(ordered[i-1], ordered[i]) != 1 {
+			t.Errorf("expected %q to rank above %q", ordered[i-1], ordered[i])
+		}
+	}
+	if Rank("Generic") != 0 {
+		t.Errorf("unknown condition should rank 0, got %d", Rank("Generic"))
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"VG+":                  VeryGoodPlus,
+		" nm ":                 NearMint,
+		"M-":                   NearMint,
+		"Near Mint (NM or M-)": NearMint,
+		"very good":            VeryGood,
+		"g+":                   GoodPlus,
+		"Generic":              "Generic",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	got := AtLeast("vg+")
+	want := []string{Mint, "M", NearMint, "NM", VeryGoodPlus, "VG+"}
+	if len(got) != len(want) {
+		t.Fatalf("AtLeast(vg+) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("AtLeast(vg+)[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if AtLeast("unknown") != nil {
+		t.Error("AtLeast of an unknown condition should be nil")
+	}
+}
+
+func TestRankSQL(t *testing.T) {
+	sql := RankSQL("media_condition")
+	for _, want := range []string{"CASE media_condition", "WHEN 'Mint (M)' THEN 8", "WHEN 'VG+' THEN 6", "WHEN 'Poor (P)' THEN 1", "ELSE 0 END"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("RankSQL() = %q, missing %q", sql, want)
+		}
+	}
+}
@@ -1,16 +1,34 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	External ExternalConfig
+	Database       DatabaseConfig
+	Server         ServerConfig
+	External       ExternalConfig
+	Recommendation RecommendationConfig
+	Scraper        ScraperConfig
 }
 
+// Database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type DatabaseConfig struct {
+	// Driver is DriverPostgres or DriverSQLite
+	Driver string
+	// Path is the SQLite database file
+	Path     string
 	Host     string
 	Port     string
 	User     string
@@ -22,19 +40,242 @@ type DatabaseConfig struct {
 type ServerConfig struct {
 	Port string
 	Host string
+	// AutocompleteCacheTTL is how long autocomplete suggestions are reused; zero disables caching
+	AutocompleteCacheTTL time.Duration
+	// AutocompleteLimit is the most suggestions an autocomplete endpoint returns
+	AutocompleteLimit int
+	// MaxResultRows caps the rows an unpaginated list endpoint returns; zero disables the cap
+	MaxResultRows int
+	// IdempotencyKeyTTL is how long an Idempotency-Key's response is replayed; zero ignores the header
+	IdempotencyKeyTTL time.Duration
+	// ResponseCacheTTL is how long dashboard and stats responses are reused; zero disables caching
+	ResponseCacheTTL time.Duration
+	// PprofEnabled serves the net/http/pprof profiling endpoints under /debug/pprof/
+	// on PprofAddr
+	PprofEnabled bool
+	// PprofAddr is the address the profiling endpoints listen on, apart from the API
+	PprofAddr string
 }
 
 type ExternalConfig struct {
 	ScraperServiceURL      string
 	RecommenderServiceURL  string
+	ExchangeRateServiceURL string
 	ExchangeRateAPIKey     string
 	DiscogsConsumerKey     string
 	DiscogsConsumerSecret  string
+	// BaseCurrency is the currency search price bounds are normalized to
+	BaseCurrency string
+}
+
+// DiscogsTokenFile is the file in Scraper.DataDir the scraper keeps the
+// OAuth token it obtained in, as scraper.TokenFile
+const DiscogsTokenFile = "discogs_token.json"
+
+// MissingDiscogsConsumerCredentials names the Discogs consumer key variables
+// that aren't set; the Go scraper can't authenticate without them
+func (e ExternalConfig) MissingDiscogsConsumerCredentials() []string {
+	var missing []string
+	if e.DiscogsConsumerKey == "" {
+		missing = append(missing, "DISCOGS_CONSUMER_KEY")
+	}
+	if e.DiscogsConsumerSecret == "" {
+		missing = append(missing, "DISCOGS_CONSUMER_SECRET")
+	}
+	return missing
+}
+
+// HasDiscogsToken reports whether the scraper has an OAuth token to use, from
+// DISCOGS_CREDS or the token file. Without one it asks for authorization on
+// stdin, which only the CLI can answer.
+func (c *Config) HasDiscogsToken() bool {
+	if os.Getenv("DISCOGS_CREDS") != "" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(c.Scraper.DataDir, DiscogsTokenFile))
+	return err == nil
+}
+
+// MissingDiscogsCredentials names the Discogs credentials the server lacks:
+// the consumer key variables and, without a token, DISCOGS_CREDS
+func (c *Config) MissingDiscogsCredentials() []string {
+	missing := c.External.MissingDiscogsConsumerCredentials()
+	if !c.HasDiscogsToken() {
+		missing = append(missing, "DISCOGS_CREDS (or a "+DiscogsTokenFile+" in DATA_DIR)")
+	}
+	return missing
+}
+
+type RecommendationConfig struct {
+	// KeeperThreshold is the minimum model probability for a listing to be
+	// stored as a predicted keeper
+	KeeperThreshold float64
+	// ThermodynamicEnabled sends record of the day selection to the
+	// recommender service; when false the highest scoring listing is used
+	ThermodynamicEnabled bool
+	// ScoreWeights is how much each factor counts towards a listing's score
+	ScoreWeights ScoreWeights
+	// CandidatePool narrows the listings the record of the day is picked from
+	CandidatePool CandidatePool
+	// VoteMin and VoteMax bound record of the day desirability and novelty
+	// votes; see VoteRange
+	VoteMin float64
+	VoteMax float64
+	// ExcludeEvaluated leaves listings already evaluated out of predictions,
+	// unless a request asks for them with exclude_evaluated=false. Off by
+	// default, so predictions cover every listing asked about.
+	ExcludeEvaluated bool
+}
+
+// Default vote range, matching the 1-5 check on RecordOfTheDayFeedback ratings
+const (
+	DefaultVoteMin = 1
+	DefaultVoteMax = 5
+)
+
+// VoteRange returns the accepted vote range, inclusive. An empty range falls
+// back to DefaultVoteMin-DefaultVoteMax; see ValidateVoteRange.
+func (r RecommendationConfig) VoteRange() (min, max float64) {
+	if r.ValidateVoteRange() == nil {
+		return r.VoteMin, r.VoteMax
+	}
+	return DefaultVoteMin, DefaultVoteMax
+}
+
+// ValidateVoteRange reports a vote range that accepts no votes
+func (r RecommendationConfig) ValidateVoteRange() error {
+	if r.VoteMin >= r.VoteMax {
+		return fmt.Errorf("VOTE_MIN (%g) must be below VOTE_MAX (%g)", r.VoteMin, r.VoteMax)
+	}
+	return nil
+}
+
+// CandidatePool restricts which listings can be picked as the record of the
+// day, by the thermodynamic service and the local fallback alike. Zero
+// values leave the pool unrestricted.
+type CandidatePool struct {
+	KeptOnly        bool `json:"kept_only,omitempty"`
+	UnevaluatedOnly bool `json:"unevaluated_only,omitempty"`
+	// MinPrice and MaxPrice bound the price in the seller's currency
+	MinPrice float64 `json:"min_price,omitempty"`
+	MaxPrice float64 `json:"max_price,omitempty"`
+	MinScore float64 `json:"min_score,omitempty"`
+}
+
+// ScoreWeights weights the factors a listing is scored on. Only their
+// proportions matter: a listing's score is the weighted mean of the factors.
+type ScoreWeights struct {
+	// WantsHaves weights the record's community wants/haves ratio
+	WantsHaves float64 `json:"wants_haves"`
+	// Deal weights the price against the Discogs suggested price
+	Deal float64 `json:"deal"`
+	// Condition weights the media condition
+	Condition float64 `json:"condition"`
+}
+
+// Validate checks every weight is a finite, non-negative number and at least
+// one of them counts
+func (w ScoreWeights) Validate() error {
+	weights := []struct {
+		name  string
+		value float64
+	}{{"wants_haves", w.WantsHaves}, {"deal", w.Deal}, {"condition", w.Condition}}
+
+	total := 0.0
+	for _, weight := range weights {
+		if math.IsNaN(weight.value) || math.IsInf(weight.value, 0) {
+			return fmt.Errorf("score weight %s must be finite", weight.name)
+		}
+		if weight.value < 0 {
+			return fmt.Errorf("score weight %s must not be negative", weight.name)
+		}
+		total += weight.value
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one score weight must be positive")
+	}
+	return nil
+}
+
+// DefaultScoreWeights counts every factor equally
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{WantsHaves: 1, Deal: 1, Condition: 1}
+}
+
+type ScraperConfig struct {
+	// RequestTimeout bounds each Discogs request; zero uses the scraper default
+	RequestTimeout time.Duration
+	// ParseDelayMin/Max add random jitter before parsing each keeper; zero disables it
+	ParseDelayMin time.Duration
+	ParseDelayMax time.Duration
+	// PageTimeout bounds the time spent on one inventory page, retries included; zero disables it
+	PageTimeout time.Duration
+	// PageDelay is the pause between inventory pages; zero disables it
+	PageDelay time.Duration
+	// ReleaseDetails fetches each new keeper's release for the record's metadata
+	ReleaseDetails bool
+	// UserAgent is sent with every Discogs request; empty uses the scraper default
+	UserAgent string
+	// Accept is sent as the Accept header with every Discogs request; empty sends none
+	Accept string
+	// MaxListings stops a scrape once it has this many keepers; zero means no cap
+	MaxListings int
+	// BaseURL is the Discogs API root; empty uses the real API
+	BaseURL string
+	// InventorySort, InventorySortOrder and InventoryStatus are passed to the
+	// Discogs inventory endpoint; empty leaves Discogs' default
+	InventorySort      string
+	InventorySortOrder string
+	InventoryStatus    string
+	// KeeperWantsComparison is greater, greater_or_equal or ratio; empty keeps the default (greater)
+	KeeperWantsComparison string
+	// KeeperMinWantsRatio is the minimum wants/haves ratio in ratio mode
+	KeeperMinWantsRatio float64
+	// KeeperMaxPrice rejects listings priced above it in the seller's currency; 0 means no limit
+	KeeperMaxPrice float64
+	// KeeperMinSellerRating skips sellers whose positive feedback percentage
+	// is below it; 0 means no limit
+	KeeperMinSellerRating float64
+	// KeeperRequiredDescriptions and KeeperExcludedDescriptions match format
+	// descriptions such as "Album" or "Promo"; empty means no check
+	KeeperRequiredDescriptions []string
+	KeeperExcludedDescriptions []string
+	// KeeperAlwaysKeep and KeeperNeverKeep are Discogs release IDs kept, or
+	// rejected, before the other keeper rules are checked
+	KeeperAlwaysKeep []int
+	KeeperNeverKeep  []int
+	// DataDir holds the token and inventory tracking files; empty is the working directory
+	DataDir string
+
+	// Schedule is a cron expression for scraping stale sellers in the
+	// background, e.g. "0 3 * * *"; empty disables the scheduler
+	Schedule string
+	// StaleAfter is how long since its last scrape a seller is due on a
+	// scheduled run
+	StaleAfter time.Duration
+	// MaxConcurrentJobs is how many sellers a refresh of all sellers scrapes
+	// at once; the rest wait their turn
+	MaxConcurrentJobs int
+
+	// SaveRetries is how many times a listing save that hit a transient
+	// database error, such as a deadlock, is retried before the listing is
+	// kept as a failure
+	SaveRetries int
+	// SaveRetryDelay is the wait before the first retry, doubling after each
+	SaveRetryDelay time.Duration
 }
 
+// Default retries of listing saves that hit a transient database error
+const (
+	DefaultSaveRetries    = 3
+	DefaultSaveRetryDelay = 100 * time.Millisecond
+)
+
 func Load() *Config {
 	return &Config{
 		Database: DatabaseConfig{
+			Driver:   strings.ToLower(getEnv("DB_DRIVER", DriverPostgres)),
+			Path:     getEnv("DB_PATH", "records.db"),
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
 			User:     getEnv("DB_USER", "app"),
@@ -45,13 +286,78 @@ func Load() *Config {
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8000"),
 			Host: getEnv("HOST", "localhost"),
+
+			AutocompleteCacheTTL: getEnvDuration("AUTOCOMPLETE_CACHE_TTL", time.Minute),
+			AutocompleteLimit:    getEnvInt("AUTOCOMPLETE_LIMIT", 10),
+			MaxResultRows:        getEnvInt("MAX_RESULT_ROWS", 10000),
+			IdempotencyKeyTTL:    getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			ResponseCacheTTL:     getEnvDuration("RESPONSE_CACHE_TTL", 30*time.Second),
+			PprofEnabled:         getEnvBool("PPROF_ENABLED", false),
+			PprofAddr:            getEnv("PPROF_ADDR", "localhost:6060"),
 		},
 		External: ExternalConfig{
 			ScraperServiceURL:      getEnv("SCRAPER_SERVICE_URL", "http://localhost:8001"),
 			RecommenderServiceURL:  getEnv("RECOMMENDER_SERVICE_URL", "http://localhost:8002"),
+			ExchangeRateServiceURL: getEnv("EXCHANGE_RATE_SERVICE_URL", "https://v6.exchangerate-api.com/v6"),
 			ExchangeRateAPIKey:     getEnv("EXCHANGE_RATE_API_KEY", ""),
 			DiscogsConsumerKey:     getEnv("DISCOGS_CONSUMER_KEY", ""),
 			DiscogsConsumerSecret:  getEnv("DISCOGS_CONSUMER_SECRET", ""),
+			BaseCurrency:           getEnv("BASE_CURRENCY", "USD"),
+		},
+		Recommendation: RecommendationConfig{
+			KeeperThreshold:      getEnvFloat("KEEPER_THRESHOLD", 0.5),
+			ThermodynamicEnabled: getEnvBool("THERMODYNAMIC_ENABLED", true),
+			ScoreWeights: ScoreWeights{
+				WantsHaves: getEnvFloat("SCORE_WEIGHT_WANTS_HAVES", 1),
+				Deal:       getEnvFloat("SCORE_WEIGHT_DEAL", 1),
+				Condition:  getEnvFloat("SCORE_WEIGHT_CONDITION", 1),
+			},
+			CandidatePool: CandidatePool{
+				KeptOnly:        getEnvBool("ROTD_KEPT_ONLY", false),
+				UnevaluatedOnly: getEnvBool("ROTD_UNEVALUATED_ONLY", false),
+				MinPrice:        getEnvFloat("ROTD_MIN_PRICE", 0),
+				MaxPrice:        getEnvFloat("ROTD_MAX_PRICE", 0),
+				MinScore:        getEnvFloat("ROTD_MIN_SCORE", 0),
+			},
+			VoteMin: getEnvFloat("VOTE_MIN", DefaultVoteMin),
+			VoteMax: getEnvFloat("VOTE_MAX", DefaultVoteMax),
+
+			ExcludeEvaluated: getEnvBool("PREDICTIONS_EXCLUDE_EVALUATED", false),
+		},
+		Scraper: ScraperConfig{
+			RequestTimeout: getEnvDuration("SCRAPER_REQUEST_TIMEOUT", 60*time.Second),
+			ParseDelayMin:  getEnvDuration("SCRAPER_PARSE_DELAY_MIN", 0),
+			ParseDelayMax:  getEnvDuration("SCRAPER_PARSE_DELAY_MAX", 0),
+			PageTimeout:    getEnvDuration("SCRAPER_PAGE_TIMEOUT", 0),
+			PageDelay:      getEnvDuration("SCRAPER_PAGE_DELAY", time.Second),
+			ReleaseDetails: getEnvBool("SCRAPER_RELEASE_DETAILS", false),
+			UserAgent:      getEnv("SCRAPER_USER_AGENT", ""),
+			Accept:         getEnv("SCRAPER_ACCEPT", ""),
+			MaxListings:    getEnvInt("SCRAPER_MAX_LISTINGS", 0),
+			BaseURL:        getEnv("DISCOGS_BASE_URL", ""),
+
+			InventorySort:      getEnv("SCRAPER_INVENTORY_SORT", ""),
+			InventorySortOrder: getEnv("SCRAPER_INVENTORY_SORT_ORDER", ""),
+			InventoryStatus:    getEnv("SCRAPER_INVENTORY_STATUS", ""),
+
+			KeeperWantsComparison:      getEnv("KEEPER_WANTS_COMPARISON", ""),
+			KeeperMinWantsRatio:        getEnvFloat("KEEPER_MIN_WANTS_RATIO", 0),
+			KeeperMaxPrice:             getEnvFloat("KEEPER_MAX_PRICE", 0),
+			KeeperMinSellerRating:      getEnvFloat("KEEPER_MIN_SELLER_RATING", 0),
+			KeeperRequiredDescriptions: getEnvList("KEEPER_REQUIRED_DESCRIPTIONS"),
+			KeeperExcludedDescriptions: getEnvList("KEEPER_EXCLUDED_DESCRIPTIONS"),
+			KeeperAlwaysKeep:           getEnvIntList("KEEPER_ALWAYS_KEEP"),
+			KeeperNeverKeep:            getEnvIntList("KEEPER_NEVER_KEEP"),
+
+			DataDir: getEnv("DATA_DIR", ""),
+
+			Schedule:   getEnv("SCRAPE_SCHEDULE", ""),
+			StaleAfter: getEnvDuration("SCRAPE_STALE_AFTER", 24*time.Hour),
+
+			MaxConcurrentJobs: getEnvInt("SCRAPE_MAX_CONCURRENT_JOBS", 1),
+
+			SaveRetries:    getEnvInt("SCRAPER_SAVE_RETRIES", DefaultSaveRetries),
+			SaveRetryDelay: getEnvDuration("SCRAPER_SAVE_RETRY_DELAY", DefaultSaveRetryDelay),
 		},
 	}
 }
@@ -62,3 +368,62 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvIntList splits a comma-separated variable of integers, dropping
+// entries that aren't one
+func getEnvIntList(key string) []int {
+	var list []int
+	for _, item := range getEnvList(key) {
+		if parsed, err := strconv.Atoi(item); err == nil {
+			list = append(list, parsed)
+		}
+	}
+	return list
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMissingDiscogsCredentialsNeedsToken(t *testing.T) {
+	t.Setenv("DISCOGS_CREDS", "")
+	cfg := &Config{
+		External: ExternalConfig{DiscogsConsumerKey: "key", DiscogsConsumerSecret: "secret"},
+		Scraper:  ScraperConfig{DataDir: t.TempDir()},
+	}
+
+	// The consumer key alone would leave the server prompting on stdin
+	want := []string{"DISCOGS_CREDS (or a discogs_token.json in DATA_DIR)"}
+	if got := cfg.MissingDiscogsCredentials(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingDiscogsCredentials() = %q, want %q", got, want)
+	}
+	if cfg.HasDiscogsToken() {
+		t.Error("HasDiscogsToken() = true without DISCOGS_CREDS or a token file")
+	}
+
+	// A token file saved by the CLI is enough
+	path := filepath.Join(cfg.Scraper.DataDir, DiscogsTokenFile)
+	if err := os.WriteFile(path, []byte(`{"token": "token", "secret": "secret"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.MissingDiscogsCredentials(); len(got) != 0 {
+		t.Errorf("MissingDiscogsCredentials() = %q with a token file, want none", got)
+	}
+
+	// So is DISCOGS_CREDS
+	os.Remove(path)
+	t.Setenv("DISCOGS_CREDS", `{"token": "token", "secret": "secret"}`)
+	if got := cfg.MissingDiscogsCredentials(); len(got) != 0 {
+		t.Errorf("MissingDiscogsCredentials() = %q with DISCOGS_CREDS, want none", got)
+	}
+
+	// The CLI can authorize itself and only needs the consumer key
+	cfg.External = ExternalConfig{}
+	want = []string{"DISCOGS_CONSUMER_KEY", "DISCOGS_CONSUMER_SECRET"}
+	if got := cfg.External.MissingDiscogsConsumerCredentials(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingDiscogsConsumerCredentials() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvFileVar names the variable that points the binaries at a .env file
+const EnvFileVar = "ENV_FILE"
+
+// LoadEnvFile loads a .env file into the environment, leaving variables that
+// are already set alone. The file is path if given (the -env flag), else
+// $ENV_FILE, else defaultPath; relative paths are from the working directory.
+// A missing default file only means the system environment is used, but a
+// file asked for by path or ENV_FILE must load. Either way it logs which
+// file, if any, was loaded.
+func LoadEnvFile(path, defaultPath string) error {
+	explicit := true
+	if path == "" {
+		path = os.Getenv(EnvFileVar)
+	}
+	if path == "" {
+		path, explicit = defaultPath, false
+	}
+
+	if err := godotenv.Load(path); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			log.Printf("No .env file at %s, using system environment variables (set %s or -env to use another file)",
+				absPath(path), EnvFileVar)
+			return nil
+		}
+		return fmt.Errorf("failed to load env file %s: %w", absPath(path), err)
+	}
+	log.Printf("Loaded environment variables from %s", absPath(path))
+	return nil
+}
+
+// absPath makes log messages say where a relative path actually points
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeEnvFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEnvFilePrefersFlagThenEnvFileVar(t *testing.T) {
+	fromFlag := writeEnvFile(t, "ENV_FILE_TEST_SOURCE=flag\n")
+	fromVar := writeEnvFile(t, "ENV_FILE_TEST_SOURCE=var\n")
+	fromDefault := writeEnvFile(t, "ENV_FILE_TEST_SOURCE=default\n")
+
+	tests := []struct {
+		flag, envFile string
+		want          string
+	}{
+		{fromFlag, fromVar, "flag"},
+		{"", fromVar, "var"},
+		{"", "", "default"},
+	}
+	for _, tt := range tests {
+		t.Setenv(EnvFileVar, tt.envFile)
+		t.Setenv("ENV_FILE_TEST_SOURCE", "")
+		os.Unsetenv("ENV_FILE_TEST_SOURCE")
+
+		if err := LoadEnvFile(tt.flag, fromDefault); err != nil {
+			t.Fatal(err)
+		}
+		if got := os.Getenv("ENV_FILE_TEST_SOURCE"); got != tt.want {
+			t.Errorf("flag=%q ENV_FILE=%q: loaded %q, want %q", tt.flag, tt.envFile, got, tt.want)
+		}
+	}
+}
+
+func TestLoadEnvFileKeepsSetVariables(t *testing.T) {
+	t.Setenv(EnvFileVar, "")
+	t.Setenv("ENV_FILE_TEST_SOURCE", "system")
+
+	if err := LoadEnvFile(writeEnvFile(t, "ENV_FILE_TEST_SOURCE=file\n"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("ENV_FILE_TEST_SOURCE"); got != "system" {
+		t.Errorf("ENV_FILE_TEST_SOURCE = %q, want the system value", got)
+	}
+}
+
+func TestLoadEnvFileMissing(t *testing.T) {
+	t.Setenv(EnvFileVar, "")
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	if err := LoadEnvFile("", missing); err != nil {
+		t.Errorf("a missing default file should fall back to the system environment, got %v", err)
+	}
+	if err := LoadEnvFile(missing, ""); err == nil {
+		t.Error("a missing file given with -env should be an error")
+	}
+	t.Setenv(EnvFileVar, missing)
+	if err := LoadEnvFile("", ""); err == nil {
+		t.Errorf("a missing file given with %s should be an error", EnvFileVar)
+	}
+}
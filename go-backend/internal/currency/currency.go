@@ -0,0 +1,62 @@
+// Package currency normalizes the currency codes and symbols listings carry
+// to ISO 4217 codes
+package currency
+
+import "strings"
+
+// codes are the active ISO 4217 currency codes
+var codes = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND
+		BOB BRL BSD BTN BWP BYN BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF
+		DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD
+		HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW
+		KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR
+		MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN
+		PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN
+		SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES
+		VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL`) {
+		m[code] = true
+	}
+	return m
+}()
+
+// symbols maps the symbols and prefixed dollars sellers use to a code. Keys
+// are upper case; bare symbols shared by several currencies (kr, R) are left
+// out rather than guessed.
+var symbols = map[string]string{
+	"$":    "USD",
+	"US$":  "USD",
+	"USD$": "USD",
+	"£":    "GBP",
+	"€":    "EUR",
+	"¥":    "JPY",
+	"JP¥":  "JPY",
+	"C$":   "CAD",
+	"CA$":  "CAD",
+	"A$":   "AUD",
+	"AU$":  "AUD",
+	"NZ$":  "NZD",
+	"HK$":  "HKD",
+	"S$":   "SGD",
+	"MX$":  "MXN",
+	"R$":   "BRL",
+	"₩":    "KRW",
+	"₹":    "INR",
+	"₽":    "RUB",
+	"ZŁ":   "PLN",
+	"KČ":   "CZK",
+}
+
+// Normalize returns the ISO 4217 code for a code in any case or a known
+// symbol, e.g. "usd" and "US$" both become "USD". ok is false for anything
+// else, including an empty string.
+func Normalize(currency string) (code string, ok bool) {
+	key := strings.ToUpper(strings.TrimSpace(currency))
+	if codes[key] {
+		return key, true
+	}
+	code, ok = symbols[key]
+	return code, ok
+}
@@ -0,0 +1,32 @@
+package currency
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"USD":   "USD",
+		" usd ": "USD",
+		"$":     "USD",
+		"US$":   "USD",
+		"us$":   "USD",
+		"£":     "GBP",
+		"€":     "EUR",
+		"eur":   "EUR",
+		"CA$":   "CAD",
+		"zł":    "PLN",
+		"JPY":   "JPY",
+	}
+	for in, want := range tests {
+		if got, ok := Normalize(in); !ok || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestNormalizeRejectsUnknown(t *testing.T) {
+	for _, in := range []string{"", "dollars", "XXX", "kr", "US"} {
+		if got, ok := Normalize(in); ok {
+			t.Errorf("Normalize(%q) = %q, want not ok", in, got)
+		}
+	}
+}
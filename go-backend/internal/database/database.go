@@ -8,18 +8,28 @@ import (
 	"discogs-api/internal/models"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-// Initialize creates a new database connection
+// Initialize creates a new database connection with the configured driver
 func Initialize(cfg config.DatabaseConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode,
-	)
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case config.DriverPostgres, "":
+		dsn := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode,
+		)
+		dialector = postgres.Open(dsn)
+	case config.DriverSQLite:
+		dialector = sqlite.Open(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q, want %q or %q", cfg.Driver, config.DriverPostgres, config.DriverSQLite)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
@@ -33,8 +43,14 @@ func Initialize(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	}
 
 	// Configure connection pool
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	if isSQLite(db) {
+		// SQLite allows one writer at a time; a single connection makes
+		// concurrent requests queue rather than fail with "database is locked"
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
+	}
 
 	log.Println("Database connection established")
 	return db, nil
@@ -44,17 +60,94 @@ func Initialize(cfg config.DatabaseConfig) (*gorm.DB, error) {
 func AutoMigrate(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
-	// Note: We're not auto-migrating since we want to use existing Django tables
-	// Instead, we'll just verify the connection works
-	var count int64
-	if err := db.Raw("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public'").Scan(&count).Error; err != nil {
-		return fmt.Errorf("failed to verify database connection: %w", err)
+	if isSQLite(db) {
+		// There is no Django to create the shared tables in SQLite mode
+		if err := CreateTables(db); err != nil {
+			return err
+		}
+	} else {
+		// Note: We're not auto-migrating since we want to use existing Django tables
+		// Instead, we'll just verify the connection works
+		var count int64
+		if err := db.Raw("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public'").Scan(&count).Error; err != nil {
+			return fmt.Errorf("failed to verify database connection: %w", err)
+		}
+
+		log.Printf("Database verified - found %d tables", count)
+	}
+
+	// Create tables owned by the Go backend
+	if err := db.AutoMigrate(goManagedTables...); err != nil {
+		return fmt.Errorf("failed to migrate Go tables: %w", err)
+	}
+
+	// Add columns the Go backend introduced on top of the Django schema
+	for _, column := range goManagedColumns {
+		if db.Migrator().HasColumn(column.model, column.field) {
+			continue
+		}
+		log.Printf("Adding column %s", column.field)
+		if err := db.Migrator().AddColumn(column.model, column.field); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.field, err)
+		}
+	}
+
+	return backfillRecordOfTheDayBreakdowns(db)
+}
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == config.DriverSQLite
+}
+
+// backfillRecordOfTheDayBreakdowns copies the breakdown of picks made before
+// the breakdown column existed out of their individual columns. Only picks
+// with an empty breakdown are loaded, so once they are filled each startup
+// costs a query that finds nothing.
+func backfillRecordOfTheDayBreakdowns(db *gorm.DB) error {
+	var picks []models.RecordOfTheDay
+	if err := db.Where("breakdown IS NULL OR breakdown = ?", "{}").Find(&picks).Error; err != nil {
+		return fmt.Errorf("failed to load records of the day: %w", err)
 	}
 
-	log.Printf("Database verified - found %d tables", count)
+	for _, pick := range picks {
+		if err := db.Model(&pick).UpdateColumn("breakdown", pick.LegacyBreakdown()).Error; err != nil {
+			return fmt.Errorf("failed to backfill record of the day %d: %w", pick.ID, err)
+		}
+	}
+	if len(picks) > 0 {
+		log.Printf("Backfilled the breakdown of %d records of the day", len(picks))
+	}
 	return nil
 }
 
+// goManagedTables lists tables that only the Go backend uses
+var goManagedTables = []interface{}{
+	&models.ScrapeRun{},
+	&models.PriceHistory{},
+	&models.FailedListing{},
+	&models.ReviewSession{},
+	&models.SavedSearch{},
+}
+
+// goManagedColumns lists fields added by the Go backend to Django-owned tables
+var goManagedColumns = []struct {
+	model interface{}
+	field string
+}{
+	{&models.Listing{}, "KeeperProbability"},
+	{&models.Seller{}, "LastScrapedAt"},
+	{&models.Listing{}, "DeletedAt"},
+	{&models.Listing{}, "DiscogsListingID"},
+	{&models.Record{}, "FormatDescriptions"},
+	{&models.Seller{}, "Rating"},
+	{&models.Seller{}, "FeedbackCount"},
+	{&models.Record{}, "Metadata"},
+	{&models.RecordOfTheDay{}, "Breakdown"},
+	{&models.Listing{}, "ShipsFrom"},
+	{&models.Record{}, "Notes"},
+	{&models.Record{}, "Tags"},
+}
+
 // CreateTables creates all tables (for testing or fresh installs)
 func CreateTables(db *gorm.DB) error {
 	log.Println("Creating database tables...")
@@ -67,6 +160,11 @@ func CreateTables(db *gorm.DB) error {
 		&models.RecommendationMetrics{},
 		&models.RecordOfTheDay{},
 		&models.RecordOfTheDayFeedback{},
+		&models.ScrapeRun{},
+		&models.PriceHistory{},
+		&models.FailedListing{},
+		&models.ReviewSession{},
+		&models.SavedSearch{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"discogs-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrDatabaseNotEmpty is returned by Seed when the database holds data that
+// didn't come from seeding
+var ErrDatabaseNotEmpty = errors.New("database already holds sellers or records that weren't seeded")
+
+// seedPrefix marks seeded sellers' names and records' Discogs IDs, so seeding
+// never reuses a real seller or record; real Discogs IDs are numeric
+const seedPrefix = "seed-"
+
+// seedCurrency is the currency of seeded records' suggested prices
+const seedCurrency = "USD"
+
+// SeedResult counts the rows Seed inserted; rows already there aren't counted
+type SeedResult struct {
+	Sellers  int `json:"sellers"`
+	Records  int `json:"records"`
+	Listings int `json:"listings"`
+}
+
+type seedListing struct {
+	seller, record string
+	price          float64
+	condition      string
+	score          float64
+	evaluated      bool
+	kept           bool
+}
+
+var seedSellers = []models.Seller{
+	{Name: seedPrefix + "groove_merchant", Currency: "USD", Rating: seedFloat(99.8), FeedbackCount: 4210},
+	{Name: seedPrefix + "vinylvault_berlin", Currency: "EUR", Rating: seedFloat(100), FeedbackCount: 1875},
+	{Name: seedPrefix + "soho_second_spin", Currency: "GBP", Rating: seedFloat(97.2), FeedbackCount: 312},
+}
+
+var seedRecords = []models.Record{
+	{DiscogsID: seedPrefix + "1", Artist: "Miles Davis", Title: "Kind Of Blue", Format: "Vinyl", Label: "Columbia",
+		Wants: 5400, Haves: 21000, Genres: models.StringSlice{"Jazz"}, Styles: models.StringSlice{"Modal", "Cool Jazz"},
+		FormatDescriptions: models.StringSlice{"LP", "Album", "Reissue"}, Year: seedInt(1959), SuggestedPrice: "32.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "2", Artist: "John Coltrane", Title: "A Love Supreme", Format: "Vinyl", Label: "Impulse!",
+		Wants: 6100, Haves: 14000, Genres: models.StringSlice{"Jazz"}, Styles: models.StringSlice{"Hard Bop", "Modal"},
+		FormatDescriptions: models.StringSlice{"LP", "Album"}, Year: seedInt(1965), SuggestedPrice: "45.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "3", Artist: "Kraftwerk", Title: "Trans-Europa Express", Format: "Vinyl", Label: "Kling Klang",
+		Wants: 3900, Haves: 8800, Genres: models.StringSlice{"Electronic"}, Styles: models.StringSlice{"Synth-pop", "Electro"},
+		FormatDescriptions: models.StringSlice{"LP", "Album"}, Year: seedInt(1977), SuggestedPrice: "38.50 " + seedCurrency},
+	{DiscogsID: seedPrefix + "4", Artist: "Can", Title: "Tago Mago", Format: "Vinyl", Label: "United Artists Records",
+		Wants: 4200, Haves: 5200, Genres: models.StringSlice{"Rock", "Electronic"}, Styles: models.StringSlice{"Krautrock"},
+		FormatDescriptions: models.StringSlice{"2×LP", "Album", "Gatefold"}, Year: seedInt(1971), SuggestedPrice: "60.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "5", Artist: "Fela Kuti", Title: "Zombie", Format: "Vinyl", Label: "Coconut",
+		Wants: 2700, Haves: 2100, Genres: models.StringSlice{"Funk / Soul", "Jazz"}, Styles: models.StringSlice{"Afrobeat"},
+		FormatDescriptions: models.StringSlice{"LP", "Album"}, Year: seedInt(1976), SuggestedPrice: "55.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "6", Artist: "Joy Division", Title: "Unknown Pleasures", Format: "Vinyl", Label: "Factory",
+		Wants: 5100, Haves: 19000, Genres: models.StringSlice{"Rock"}, Styles: models.StringSlice{"Post-Punk"},
+		FormatDescriptions: models.StringSlice{"LP", "Album"}, Year: seedInt(1979), SuggestedPrice: "29.99 " + seedCurrency},
+	{DiscogsID: seedPrefix + "7", Artist: "Aphex Twin", Title: "Selected Ambient Works 85-92", Format: "Vinyl", Label: "Apollo",
+		Wants: 6600, Haves: 7300, Genres: models.StringSlice{"Electronic"}, Styles: models.StringSlice{"Ambient", "Techno"},
+		FormatDescriptions: models.StringSlice{"2×LP", "Compilation"}, Year: seedInt(1992), SuggestedPrice: "48.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "8", Artist: "Nina Simone", Title: "Wild Is The Wind", Format: "Vinyl", Label: "Philips",
+		Wants: 1900, Haves: 2600, Genres: models.StringSlice{"Jazz", "Blues"}, Styles: models.StringSlice{"Soul-Jazz"},
+		FormatDescriptions: models.StringSlice{"LP", "Album", "Mono"}, Year: seedInt(1966), SuggestedPrice: "40.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "9", Artist: "Boards Of Canada", Title: "Music Has The Right To Children", Format: "CD", Label: "Warp Records",
+		Wants: 2200, Haves: 16000, Genres: models.StringSlice{"Electronic"}, Styles: models.StringSlice{"IDM", "Downtempo"},
+		FormatDescriptions: models.StringSlice{"Album"}, Year: seedInt(1998), SuggestedPrice: "12.00 " + seedCurrency},
+	{DiscogsID: seedPrefix + "10", Artist: "Talk Talk", Title: "Spirit Of Eden", Format: "Cassette", Label: "Parlophone",
+		Wants: 800, Haves: 1400, Genres: models.StringSlice{"Rock"}, Styles: models.StringSlice{"Art Rock", "Post Rock"},
+		FormatDescriptions: models.StringSlice{"Album"}, Year: seedInt(1988), SuggestedPrice: "15.00 " + seedCurrency},
+}
+
+var seedListings = []seedListing{
+	{"groove_merchant", "1", 28.00, "Very Good Plus (VG+)", 7.4, true, true},
+	{"groove_merchant", "2", 52.00, "Near Mint (NM or M-)", 8.9, true, true},
+	{"groove_merchant", "6", 24.50, "Very Good (VG)", 5.1, true, false},
+	{"groove_merchant", "8", 35.00, "Very Good Plus (VG+)", 6.8, false, false},
+	{"vinylvault_berlin", "3", 41.00, "Near Mint (NM or M-)", 8.2, false, false},
+	{"vinylvault_berlin", "4", 75.00, "Very Good Plus (VG+)", 9.1, false, false},
+	{"vinylvault_berlin", "7", 44.00, "Mint (M)", 7.9, true, true},
+	{"vinylvault_berlin", "9", 9.00, "Good Plus (G+)", 3.2, true, false},
+	{"soho_second_spin", "5", 60.00, "Very Good Plus (VG+)", 8.0, false, false},
+	{"soho_second_spin", "6", 30.00, "Near Mint (NM or M-)", 6.5, false, false},
+	{"soho_second_spin", "10", 11.00, "Very Good (VG)", 4.4, false, false},
+	{"soho_second_spin", "1", 22.00, "Good (G)", 3.9, false, false},
+}
+
+// Seed inserts a small set of sellers, records and listings for local
+// development. Running it again inserts nothing new. Unless force is set it
+// refuses, with ErrDatabaseNotEmpty, a database holding sellers or records of
+// its own, so it can't be pointed at production by mistake. Seeded sellers
+// and records are named apart from real ones, so even forced it only adds
+// listings to its own.
+func Seed(db *gorm.DB, force bool) (*SeedResult, error) {
+	if !force {
+		empty, err := onlySeedData(db)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrDatabaseNotEmpty
+		}
+	}
+
+	result := &SeedResult{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		sellers := make(map[string]uint, len(seedSellers))
+		for _, seller := range seedSellers {
+			created, err := firstOrCreate(tx, &seller, "name = ?", seller.Name)
+			if err != nil {
+				return fmt.Errorf("failed to seed seller %s: %w", seller.Name, err)
+			}
+			if created {
+				result.Sellers++
+			}
+			sellers[strings.TrimPrefix(seller.Name, seedPrefix)] = seller.ID
+		}
+
+		records := make(map[string]uint, len(seedRecords))
+		now := time.Now()
+		for i, record := range seedRecords {
+			// Spread over the last few weeks so recent views have something to show
+			record.Added = now.AddDate(0, 0, -3*i)
+			created, err := firstOrCreate(tx, &record, "discogs_id = ?", record.DiscogsID)
+			if err != nil {
+				return fmt.Errorf("failed to seed record %s: %w", record.DiscogsID, err)
+			}
+			if created {
+				result.Records++
+			}
+			records[strings.TrimPrefix(record.DiscogsID, seedPrefix)] = record.ID
+		}
+
+		for _, l := range seedListings {
+			listing := models.Listing{
+				SellerID:       sellers[l.seller],
+				RecordID:       records[l.record],
+				RecordPrice:    l.price,
+				MediaCondition: l.condition,
+				Score:          l.score,
+				Evaluated:      l.evaluated,
+				Kept:           l.kept,
+			}
+			created, err := firstOrCreate(tx, &listing, "seller_id = ? AND record_id = ?", listing.SellerID, listing.RecordID)
+			if err != nil {
+				return fmt.Errorf("failed to seed listing of record %s by %s: %w", l.record, l.seller, err)
+			}
+			if created {
+				result.Listings++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// onlySeedData reports whether every seller and record came from Seed
+func onlySeedData(db *gorm.DB) (bool, error) {
+	var sellers, records int64
+	if err := db.Model(&models.Seller{}).Where("name NOT LIKE ?", seedPrefix+"%").Count(&sellers).Error; err != nil {
+		return false, fmt.Errorf("failed to count sellers: %w", err)
+	}
+	if err := db.Model(&models.Record{}).Where("discogs_id NOT LIKE ?", seedPrefix+"%").Count(&records).Error; err != nil {
+		return false, fmt.Errorf("failed to count records: %w", err)
+	}
+	return sellers == 0 && records == 0, nil
+}
+
+// firstOrCreate loads the row matching the condition into value, or inserts
+// value when there is none, and reports whether it inserted
+func firstOrCreate(tx *gorm.DB, value interface{}, query string, args ...interface{}) (bool, error) {
+	result := tx.Unscoped().Where(query, args...).Limit(1).Find(value)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return false, nil
+	}
+	return true, tx.Create(value).Error
+}
+
+func seedFloat(f float64) *float64 {
+	return &f
+}
+
+func seedInt(i int) *int {
+	return &i
+}
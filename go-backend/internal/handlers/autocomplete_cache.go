@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// autocompleteCacheMaxEntries bounds the cache; when full, expired entries
+// are dropped and, failing that, everything is
+const autocompleteCacheMaxEntries = 10000
+
+type autocompleteKey struct {
+	endpoint string
+	term     string
+}
+
+type autocompleteEntry struct {
+	suggestions []string
+	expires     time.Time
+}
+
+// autocompleteCache holds recent autocomplete suggestions so typing the same
+// prefix again within the TTL doesn't query the database. A nil cache or a
+// zero TTL caches nothing.
+type autocompleteCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[autocompleteKey]autocompleteEntry
+}
+
+func newAutocompleteCache(ttl time.Duration) *autocompleteCache {
+	return &autocompleteCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[autocompleteKey]autocompleteEntry),
+	}
+}
+
+func (ac *autocompleteCache) get(endpoint, term string) ([]string, bool) {
+	if ac == nil || ac.ttl <= 0 {
+		return nil, false
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	key := autocompleteKey{endpoint, term}
+	entry, ok := ac.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !ac.now().Before(entry.expires) {
+		delete(ac.entries, key)
+		return nil, false
+	}
+	return entry.suggestions, true
+}
+
+func (ac *autocompleteCache) set(endpoint, term string, suggestions []string) {
+	if ac == nil || ac.ttl <= 0 {
+		return
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	now := ac.now()
+	if len(ac.entries) >= autocompleteCacheMaxEntries {
+		for key, entry := range ac.entries {
+			if !now.Before(entry.expires) {
+				delete(ac.entries, key)
+			}
+		}
+		if len(ac.entries) >= autocompleteCacheMaxEntries {
+			ac.entries = make(map[autocompleteKey]autocompleteEntry)
+		}
+	}
+	ac.entries[autocompleteKey{endpoint, term}] = autocompleteEntry{
+		suggestions: suggestions,
+		expires:     now.Add(ac.ttl),
+	}
+}
+
+// clear drops every entry, e.g. after a scrape has added records
+func (ac *autocompleteCache) clear() {
+	if ac == nil {
+		return
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.entries = make(map[autocompleteKey]autocompleteEntry)
+}
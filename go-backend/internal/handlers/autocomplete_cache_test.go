@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAutocompleteCacheExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newAutocompleteCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.set("genre", "ja", []string{"Jazz"})
+	got, ok := cache.get("genre", "ja")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Jazz"}, got)
+
+	_, ok = cache.get("styles", "ja")
+	assert.False(t, ok, "entries are per endpoint")
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get("genre", "ja")
+	assert.False(t, ok, "entries expire after the TTL")
+}
+
+func TestAutocompleteCacheClear(t *testing.T) {
+	cache := newAutocompleteCache(time.Minute)
+	cache.set("genre", "ja", []string{"Jazz"})
+	cache.clear()
+
+	_, ok := cache.get("genre", "ja")
+	assert.False(t, ok)
+}
+
+func TestAutocompleteCacheDisabled(t *testing.T) {
+	var nilCache *autocompleteCache
+	nilCache.set("genre", "ja", []string{"Jazz"})
+	_, ok := nilCache.get("genre", "ja")
+	assert.False(t, ok)
+	nilCache.clear()
+
+	cache := newAutocompleteCache(0)
+	cache.set("genre", "ja", []string{"Jazz"})
+	_, ok = cache.get("genre", "ja")
+	assert.False(t, ok)
+}
+
+func TestAutocompleteCacheConcurrent(t *testing.T) {
+	cache := newAutocompleteCache(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.set("genre", string(rune('a'+i)), []string{"Jazz"})
+				cache.get("genre", string(rune('a'+j%8)))
+				if j%25 == 0 {
+					cache.clear()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestAutocompleteServedFromCache(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Record{}))
+	require.NoError(t, db.Create(&models.Record{DiscogsID: "1", Format: "Vinyl"}).Error)
+
+	h := &Handler{db: db, config: &config.Config{}, autocompleteCache: newAutocompleteCache(time.Minute)}
+	suggest := func(term string) []string {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/autocomplete/format/?term="+term, nil)
+		h.GetFormatAutocomplete(c)
+
+		var formats []string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &formats))
+		return formats
+	}
+
+	assert.Equal(t, []string{"Vinyl"}, suggest("vi"))
+
+	require.NoError(t, db.Model(&models.Record{}).Where("discogs_id = ?", "1").Update("format", "Vinyl LP").Error)
+	assert.Equal(t, []string{"Vinyl"}, suggest("vi"), "repeated term is served from the cache")
+	assert.Equal(t, []string{"Vinyl LP"}, suggest("vin"), "a new term queries the database")
+
+	h.autocompleteCache.clear()
+	assert.Equal(t, []string{"Vinyl LP"}, suggest("vi"))
+}
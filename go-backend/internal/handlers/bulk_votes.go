@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkVotes caps how many votes one bulk request may carry
+const maxBulkVotes = 100
+
+// BulkVote is one vote on a past record of the day. Ratings are pointers so
+// a missing one can be told apart from a zero.
+type BulkVote struct {
+	ID           uint     `json:"id"`
+	Desirability *float64 `json:"desirability"`
+	Novelty      *float64 `json:"novelty"`
+}
+
+// BulkVoteResult reports whether a vote was recorded and, if so, the record
+// of the day's averages after the whole batch
+type BulkVoteResult struct {
+	ID                  uint    `json:"id"`
+	Success             bool    `json:"success"`
+	Error               string  `json:"error,omitempty"`
+	AverageDesirability float64 `json:"average_desirability,omitempty"`
+	AverageNovelty      float64 `json:"average_novelty,omitempty"`
+}
+
+// VoteRecordsOfTheDay handles POST /vote-record-of-the-day/bulk/, taking a
+// JSON body {"votes": [{"id": 1, "desirability": 4, "novelty": 3}, ...]} so
+// past records of the day can be rated in one go. Each vote is validated
+// like VoteRecordOfTheDay's; invalid votes and unknown records are reported
+// and skipped, and the rest are added to the vote lists and averages in one
+// transaction. Results are in the order of the votes.
+func (h *Handler) VoteRecordsOfTheDay(c *gin.Context) {
+	var req struct {
+		Votes []BulkVote `json:"votes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(req.Votes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "votes is required"})
+		return
+	}
+	if len(req.Votes) > maxBulkVotes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d votes can be submitted at once", maxBulkVotes)})
+		return
+	}
+
+	results := make([]BulkVoteResult, len(req.Votes))
+	var ids []uint
+	for i, vote := range req.Votes {
+		results[i] = BulkVoteResult{ID: vote.ID}
+		switch {
+		case vote.Desirability == nil:
+			results[i].Error = "Invalid desirability rating"
+		case vote.Novelty == nil:
+			results[i].Error = "Invalid novelty rating"
+		default:
+			if msg := h.voteRangeError("Desirability", *vote.Desirability); msg != "" {
+				results[i].Error = msg
+			} else if msg := h.voteRangeError("Novelty", *vote.Novelty); msg != "" {
+				results[i].Error = msg
+			} else {
+				ids = append(ids, vote.ID)
+			}
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var records []models.RecordOfTheDay
+		if len(ids) > 0 {
+			if err := tx.Where("id IN ?", ids).Find(&records).Error; err != nil {
+				return err
+			}
+		}
+		byID := make(map[uint]*models.RecordOfTheDay, len(records))
+		for i := range records {
+			byID[records[i].ID] = &records[i]
+		}
+
+		// A record voted on more than once gets every vote
+		for i, vote := range req.Votes {
+			if results[i].Error != "" {
+				continue
+			}
+			record, ok := byID[vote.ID]
+			if !ok {
+				results[i].Error = "Record of the day not found"
+				continue
+			}
+			record.DesirabilityVotes = append(record.DesirabilityVotes, *vote.Desirability)
+			record.NoveltyVotes = append(record.NoveltyVotes, *vote.Novelty)
+			record.UpdateAverages()
+		}
+
+		for i := range records {
+			if err := tx.Save(&records[i]).Error; err != nil {
+				return err
+			}
+		}
+		for i := range results {
+			if results[i].Error == "" {
+				record := byID[results[i].ID]
+				results[i].Success = true
+				results[i].AverageDesirability = record.AverageDesirability
+				results[i].AverageNovelty = record.AverageNovelty
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error saving bulk votes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save votes"})
+		return
+	}
+
+	var accepted int
+	for _, result := range results {
+		if result.Success {
+			accepted++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"accepted": accepted,
+		"rejected": len(results) - accepted,
+		"results":  results,
+	})
+}
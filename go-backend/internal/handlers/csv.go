@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"discogs-api/internal/models"
+	"discogs-api/internal/services"
+)
+
+// listingCSVColumn is one column of a listing export
+type listingCSVColumn struct {
+	Header string
+	Value  func(listing models.Listing) string
+}
+
+// listingCSVColumns are the columns an export can include, by name
+var listingCSVColumns = map[string]listingCSVColumn{
+	"id":         {"Listing ID", func(l models.Listing) string { return strconv.Itoa(int(l.ID)) }},
+	"artist":     {"Record Artist", func(l models.Listing) string { return l.Record.Artist }},
+	"title":      {"Record Title", func(l models.Listing) string { return l.Record.Title }},
+	"label":      {"Record Label", func(l models.Listing) string { return l.Record.Label }},
+	"format":     {"Record Format", func(l models.Listing) string { return l.Record.Format }},
+	"year":       {"Record Year", listingYear},
+	"seller":     {"Seller", func(l models.Listing) string { return l.Seller.Name }},
+	"price":      {"Record Price", func(l models.Listing) string { return fmt.Sprintf("%.2f", l.RecordPrice) }},
+	"condition":  {"Media Condition", func(l models.Listing) string { return l.MediaCondition }},
+	"score":      {"Score", func(l models.Listing) string { return fmt.Sprintf("%.2f", l.Score) }},
+	"kept":       {"Kept", func(l models.Listing) string { return strconv.FormatBool(l.Kept) }},
+	"evaluated":  {"Evaluated", func(l models.Listing) string { return strconv.FormatBool(l.Evaluated) }},
+	"release":    {"Discogs Release ID", func(l models.Listing) string { return l.Record.DiscogsID }},
+	"currency":   {"Currency", func(l models.Listing) string { return l.Seller.Currency }},
+	"ships_from": {"Ships From", func(l models.Listing) string { return l.ShipsFrom }},
+}
+
+// defaultCSVColumns is the column set of the listings export. It carries
+// the release ID and currency so an export imports back as the same records
+// and sellers.
+var defaultCSVColumns = []string{
+	"id", "artist", "title", "label", "format", "year",
+	"seller", "price", "condition", "score", "kept", "evaluated",
+	"ships_from", "release", "currency",
+}
+
+func listingYear(l models.Listing) string {
+	if l.Record.Year == nil {
+		return ""
+	}
+	return strconv.Itoa(*l.Record.Year)
+}
+
+// lookupCSVColumns resolves column names, failing on the first unknown one
+func lookupCSVColumns(columns []string) ([]listingCSVColumn, error) {
+	resolved := make([]listingCSVColumn, len(columns))
+	for i, name := range columns {
+		column, ok := listingCSVColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		resolved[i] = column
+	}
+	return resolved, nil
+}
+
+// writeListingCSVHeader writes the header row for the given columns
+func writeListingCSVHeader(w *csv.Writer, columns []string) error {
+	resolved, err := lookupCSVColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	headers := make([]string, len(resolved))
+	for i, column := range resolved {
+		headers[i] = column.Header
+	}
+	return w.Write(headers)
+}
+
+// writeListingCSV writes one row per listing. Listings need Record and
+// Seller preloaded for those columns to be filled in.
+func writeListingCSV(w *csv.Writer, listings []models.Listing, columns []string) error {
+	resolved, err := lookupCSVColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, len(resolved))
+	for _, listing := range listings {
+		for i, column := range resolved {
+			row[i] = column.Value(listing)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importCSVRequired are the columns an imported CSV must have
+var importCSVRequired = []string{"artist", "title", "seller", "price", "condition"}
+
+// readListingCSV parses a CSV with the export's headers into listings to
+// import. Columns may come in any order and are matched by header or column
+// name, ignoring case; others, such as Listing ID, are ignored. Rows that
+// can't be parsed are returned as errors rather than failing the file.
+func readListingCSV(r io.Reader) ([]services.ImportListing, []services.ImportError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the CSV is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := map[string]string{}
+	for name, column := range listingCSVColumns {
+		names[strings.ToLower(column.Header)] = name
+		names[name] = name
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		if name, ok := names[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))]; ok {
+			index[name] = i
+		}
+	}
+	var missing []string
+	for _, name := range importCSVRequired {
+		if _, ok := index[name]; !ok {
+			missing = append(missing, listingCSVColumns[name].Header)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	var listings []services.ImportListing
+	var rowErrors []services.ImportError
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		listing, err := parseImportRow(row, index)
+		if err != nil {
+			rowErrors = append(rowErrors, services.ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		listing.Line = line
+		listings = append(listings, listing)
+	}
+	return listings, rowErrors, nil
+}
+
+// parseImportRow reads one CSV row using the column positions in index
+func parseImportRow(row []string, index map[string]int) (services.ImportListing, error) {
+	get := func(name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for _, name := range importCSVRequired {
+		if get(name) == "" {
+			return services.ImportListing{}, fmt.Errorf("%s is empty", listingCSVColumns[name].Header)
+		}
+	}
+
+	listing := services.ImportListing{
+		DiscogsID: get("release"),
+		Artist:    get("artist"),
+		Title:     get("title"),
+		Label:     get("label"),
+		Format:    get("format"),
+		Seller:    get("seller"),
+		Currency:  get("currency"),
+		Condition: get("condition"),
+		ShipsFrom: get("ships_from"),
+	}
+
+	var err error
+	if listing.Price, err = strconv.ParseFloat(get("price"), 64); err != nil || listing.Price < 0 {
+		return listing, fmt.Errorf("invalid Record Price %q", get("price"))
+	}
+	if year := get("year"); year != "" {
+		y, err := strconv.Atoi(year)
+		if err != nil {
+			return listing, fmt.Errorf("invalid Record Year %q", year)
+		}
+		listing.Year = &y
+	}
+	if score := get("score"); score != "" {
+		if listing.Score, err = strconv.ParseFloat(score, 64); err != nil {
+			return listing, fmt.Errorf("invalid Score %q", score)
+		}
+	}
+	if kept := get("kept"); kept != "" {
+		if listing.Kept, err = strconv.ParseBool(kept); err != nil {
+			return listing, fmt.Errorf("invalid Kept %q", kept)
+		}
+	}
+	if evaluated := get("evaluated"); evaluated != "" {
+		if listing.Evaluated, err = strconv.ParseBool(evaluated); err != nil {
+			return listing, fmt.Errorf("invalid Evaluated %q", evaluated)
+		}
+	}
+	return listing, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"discogs-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteListingCSV(t *testing.T) {
+	year := 1959
+	listings := []models.Listing{
+		{
+			ID:             42,
+			Record:         models.Record{DiscogsID: "1454", Artist: "Miles Davis", Title: "Kind of Blue", Label: "Columbia, CBS", Format: "LP", Year: &year},
+			Seller:         models.Seller{Name: "bluenote", Currency: "USD"},
+			RecordPrice:    34.5,
+			MediaCondition: "Very Good Plus (VG+)",
+			Score:          8.126,
+			Kept:           true,
+			ShipsFrom:      "United States",
+		},
+		{ID: 43},
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	require.NoError(t, writeListingCSVHeader(w, defaultCSVColumns))
+	require.NoError(t, writeListingCSV(w, listings, defaultCSVColumns))
+	w.Flush()
+	require.NoError(t, w.Error())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{
+		"Listing ID", "Record Artist", "Record Title", "Record Label",
+		"Record Format", "Record Year", "Seller", "Record Price",
+		"Media Condition", "Score", "Kept", "Evaluated",
+		"Ships From", "Discogs Release ID", "Currency",
+	}, rows[0])
+	assert.Equal(t, []string{
+		"42", "Miles Davis", "Kind of Blue", "Columbia, CBS",
+		"LP", "1959", "bluenote", "34.50",
+		"Very Good Plus (VG+)", "8.13", "true", "false",
+		"United States", "1454", "USD",
+	}, rows[1])
+	assert.Equal(t, "", rows[2][5], "missing year is left blank")
+}
+
+func TestWriteListingCSVColumns(t *testing.T) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	require.NoError(t, writeListingCSVHeader(w, []string{"artist", "price"}))
+	require.NoError(t, writeListingCSV(w, []models.Listing{{Record: models.Record{Artist: "Nina Simone"}, RecordPrice: 12}}, []string{"artist", "price"}))
+	w.Flush()
+	assert.Equal(t, "Record Artist,Record Price\nNina Simone,12.00\n", buf.String())
+
+	assert.Error(t, writeListingCSVHeader(w, []string{"artist", "colour"}))
+	assert.Error(t, writeListingCSV(w, nil, []string{"colour"}))
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// jsonArrayElements returns a subquery with one row per element of a JSON
+// array column, the element being in its name column. Production runs on
+// PostgreSQL; the SQLite form keeps the same queries working in tests.
+func jsonArrayElements(db *gorm.DB, table, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "SELECT json_each.value AS name FROM " + table + ", json_each(" + table + "." + column + ")"
+	}
+	return "SELECT jsonb_array_elements_text(" + table + "." + column + ") AS name FROM " + table
+}
+
+// jsonArrayContains returns a condition matching rows whose JSON array column
+// has an element equal to the condition's argument
+func jsonArrayContains(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "EXISTS (SELECT 1 FROM json_each(" + column + ") WHERE json_each.value = ?)"
+	}
+	return "EXISTS (SELECT 1 FROM jsonb_array_elements_text(" + column + ") AS element WHERE element = ?)"
+}
+
+// jsonArrayContainsAny returns a condition matching rows whose JSON array of
+// integers has an element among the condition's argument, a slice of them
+func jsonArrayContainsAny(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "EXISTS (SELECT 1 FROM json_each(" + column + ") WHERE json_each.value IN ?)"
+	}
+	return "EXISTS (SELECT 1 FROM jsonb_array_elements_text(" + column + ") AS element WHERE element::bigint IN ?)"
+}
+
+// containsInsensitive returns a condition matching column values containing
+// term in any case, and its argument. Postgres' ILIKE isn't available in
+// SQLite, so both compare lowercase values.
+func containsInsensitive(column, term string) (string, string) {
+	return "LOWER(" + column + ") LIKE ?", "%" + strings.ToLower(term) + "%"
+}
+
+// jsonContainsInsensitive is containsInsensitive for a JSON column, matching
+// its text, e.g. a genre in a list of genres
+func jsonContainsInsensitive(column, term string) (string, string) {
+	return containsInsensitive("CAST("+column+" AS TEXT)", term)
+}
+
+// medianSQL returns an aggregate for the median of expr, or "" when the
+// database has no percentile function and the caller must find the middle
+// rows itself
+func medianSQL(db *gorm.DB, expr string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return ""
+	}
+	return "percentile_cont(0.5) WITHIN GROUP (ORDER BY " + expr + ")"
+}
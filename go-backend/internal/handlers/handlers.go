@@ -1,33 +1,80 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha1"
 	"encoding/csv"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"discogs-api/internal/config"
+	"discogs-api/internal/middleware"
 	"discogs-api/internal/models"
+	"discogs-api/internal/schedule"
+	"discogs-api/internal/scoring"
+	"discogs-api/internal/scraper"
 	"discogs-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// wantsHavesRatioExpr mirrors models.Record.WantsToHaves in SQL so results can
+// be ordered by demand without loading every record
+const wantsHavesRatioExpr = "CAST(discogs_record.wants AS FLOAT) / " +
+	"CASE WHEN discogs_record.haves > 0 THEN discogs_record.haves ELSE 1 END"
+
 type Handler struct {
-	db              *gorm.DB
-	config          *config.Config
-	externalService *services.ExternalService
-	scraperService  *services.ScraperService
+	db                *gorm.DB
+	config            *config.Config
+	externalService   *services.ExternalService
+	scraperService    *services.ScraperService
+	scorer            *scoring.Scorer
+	autocompleteCache *autocompleteCache
+	idempotencyKeys   *idempotencyKeys
+	responseCache     *middleware.ResponseCache
+
+	predictionRefresher *predictionRefresher
+
+	// scraperUnavailable says why scraperService is nil
+	scraperUnavailable string
 }
 
 func New(db *gorm.DB, cfg *config.Config) *Handler {
-	scraperService, err := services.NewScraperService(db, cfg)
-	if err != nil {
-		log.Printf("Warning: Failed to initialize Go scraper service: %v", err)
+	// Without a token the scraper would wait for authorization on stdin
+	var scraperService *services.ScraperService
+	var scraperUnavailable string
+	if cfg.HasDiscogsToken() {
+		var err error
+		scraperService, err = services.NewScraperService(db, cfg)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize Go scraper service: %v", err)
+			scraperUnavailable = "it failed to start, see the server log"
+		}
+	}
+	if scraperService == nil {
+		if missing := cfg.MissingDiscogsCredentials(); len(missing) > 0 {
+			scraperUnavailable = fmt.Sprintf("Discogs credentials are not configured, set %s and restart the server",
+				strings.Join(missing, " and "))
+		}
+	}
+
+	if err := cfg.Recommendation.ValidateVoteRange(); err != nil {
+		log.Printf("Warning: %v, accepting votes from %d to %d", err, config.DefaultVoteMin, config.DefaultVoteMax)
+	}
+
+	// Share the scorer so weights changed through the API apply to scrapes
+	scorer := scoring.NewScorer(cfg.Recommendation.ScoreWeights)
+	if scraperService != nil {
+		scraperService.SetScorer(scorer)
 	}
 
 	return &Handler{
@@ -35,9 +82,43 @@ func New(db *gorm.DB, cfg *config.Config) *Handler {
 		config:          cfg,
 		externalService: services.NewExternalService(cfg),
 		scraperService:  scraperService,
+		scorer:          scorer,
+
+		autocompleteCache: newAutocompleteCache(cfg.Server.AutocompleteCacheTTL),
+		idempotencyKeys:   newIdempotencyKeys(cfg.Server.IdempotencyKeyTTL),
+		responseCache:     middleware.NewResponseCache(cfg.Server.ResponseCacheTTL),
+
+		predictionRefresher: &predictionRefresher{},
+
+		scraperUnavailable: scraperUnavailable,
 	}
 }
 
+// ResponseCache returns the cache of GET responses, whose middleware main
+// puts in front of the expensive read endpoints
+func (h *Handler) ResponseCache() *middleware.ResponseCache {
+	return h.responseCache
+}
+
+// clearCaches drops cached autocomplete suggestions and responses, e.g. after
+// a scrape or an import has added records
+func (h *Handler) clearCaches() {
+	h.autocompleteCache.clear()
+	h.responseCache.Clear()
+}
+
+// respondScraperUnavailable answers 503, saying why, when the Go scraper
+// service couldn't start. It reports whether it answered.
+func (h *Handler) respondScraperUnavailable(c *gin.Context) bool {
+	if h.scraperService != nil {
+		return false
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": "Go scraper service is not available: " + h.scraperUnavailable,
+	})
+	return true
+}
+
 // DashboardStats represents the dashboard statistics response
 type DashboardStats struct {
 	NumRecords       int64                  `json:"num_records"`
@@ -69,6 +150,22 @@ func (h *Handler) GetDashboard(c *gin.Context) {
 	today := time.Now().Format("2006-01-02")
 	forceRefresh := c.Query("force_refresh") == "1"
 
+	// If today's pick already exists the dashboard can only have changed through
+	// the counts, so let clients revalidate without rebuilding the response
+	if !forceRefresh {
+		var existing models.RecordOfTheDay
+		if err := h.db.Select("id", "average_desirability", "average_novelty").
+			Where("date = ?", today).First(&existing).Error; err == nil {
+			etag := dashboardETag(today, existing, numRecords, numListings, unevaluated, accuracy)
+			c.Header("ETag", etag)
+			c.Header("Cache-Control", "private, no-cache")
+			if middleware.ETagMatches(c.GetHeader("If-None-Match"), etag) {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
 	var recordOfTheDayObj models.RecordOfTheDay
 	var recordOfTheDay *models.Listing
 	breakdown := make(map[string]interface{})
@@ -81,67 +178,57 @@ func (h *Handler) GetDashboard(c *gin.Context) {
 		// If not found or force refresh, try to get from thermodynamic service
 		if forceRefresh {
 			h.db.Where("date = ?", today).Delete(&models.RecordOfTheDay{})
+			// A forced pick replaces today's, so cached dashboards are stale
+			// and this one must not be replayed
+			h.responseCache.Clear()
+			c.Header("Cache-Control", "no-store")
 		}
 
-		thermoResp, err := h.externalService.GetThermodynamicSelection(forceRefresh)
-		if err != nil {
+		if !h.config.Recommendation.ThermodynamicEnabled {
+			// No recommender in this deployment; don't wait on it
+			var candidates int64
+			if recordOfTheDay, candidates = h.fallbackRecordOfTheDay(); recordOfTheDay != nil {
+				breakdown["selection_method"] = fallbackSelectionMethod
+				breakdown["total_candidates"] = candidates
+			}
+		} else if thermoResp, err := h.externalService.GetThermodynamicSelection(forceRefresh, h.candidateIDs()); err != nil {
 			log.Printf("Error getting thermodynamic selection: %v", err)
-			// Fallback to highest score listing
-			var fallbackListing models.Listing
-			if err := h.db.Preload("Record").Preload("Seller").
-				Where("score > ?", 0).Order("score DESC").First(&fallbackListing).Error; err == nil {
-				recordOfTheDay = &fallbackListing
-				breakdown["selection_method"] = "fallback_highest_score"
+			var candidates int64
+			if recordOfTheDay, candidates = h.fallbackRecordOfTheDay(); recordOfTheDay != nil {
+				breakdown["selection_method"] = fallbackSelectionMethod
+				breakdown["total_candidates"] = candidates
 				breakdown["error"] = "external service unavailable"
 			}
 		} else if thermoResp != nil && thermoResp.Success {
 			// Get the listing from the database
-			var listing models.Listing
-			if err := h.db.Preload("Record").Preload("Seller").
-				First(&listing, thermoResp.ListingID).Error; err == nil {
-				recordOfTheDay = &listing
-				breakdown = thermoResp.Breakdown
-
-				// Save to database
-				recordOfTheDayObj = models.RecordOfTheDay{
-					Date:      time.Now(),
-					ListingID: uint(thermoResp.ListingID),
-					Listing:   listing,
-				}
-				// Extract breakdown values
-				if val, ok := thermoResp.Breakdown["model_score"].(float64); ok {
-					recordOfTheDayObj.ModelScore = val
-				}
-				if val, ok := thermoResp.Breakdown["entropy_measure"].(float64); ok {
-					recordOfTheDayObj.EntropyMeasure = val
-				}
-				if val, ok := thermoResp.Breakdown["system_temperature"].(float64); ok {
-					recordOfTheDayObj.SystemTemperature = val
-				}
-				if val, ok := thermoResp.Breakdown["selection_method"].(string); ok {
-					recordOfTheDayObj.SelectionMethod = val
-				}
-
+			listing, err := h.candidateListing(thermoResp.ListingID)
+			if err == nil {
+				recordOfTheDay = listing
+				recordOfTheDayObj = h.newRecordOfTheDay(*listing, thermoResp.Breakdown)
 				h.db.Create(&recordOfTheDayObj)
+				breakdown = copyBreakdown(recordOfTheDayObj.Breakdown)
+			} else if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Thermodynamic service picked listing %d, which isn't in the candidate pool", thermoResp.ListingID)
+				var candidates int64
+				if recordOfTheDay, candidates = h.fallbackRecordOfTheDay(); recordOfTheDay != nil {
+					breakdown["selection_method"] = fallbackSelectionMethod
+					breakdown["total_candidates"] = candidates
+					breakdown["error"] = "selection outside the candidate pool"
+				}
 			}
 		}
 	} else {
 		// Use existing record
 		recordOfTheDay = &recordOfTheDayObj.Listing
-		breakdown = map[string]interface{}{
-			"model_score":          recordOfTheDayObj.ModelScore,
-			"entropy_measure":      recordOfTheDayObj.EntropyMeasure,
-			"system_temperature":   recordOfTheDayObj.SystemTemperature,
-			"utility_term":         recordOfTheDayObj.UtilityTerm,
-			"entropy_term":         recordOfTheDayObj.EntropyTerm,
-			"free_energy":          recordOfTheDayObj.FreeEnergy,
-			"selection_probability": recordOfTheDayObj.SelectionProbability,
-			"total_candidates":     recordOfTheDayObj.TotalCandidates,
-			"cluster_count":        recordOfTheDayObj.ClusterCount,
-			"selection_method":     recordOfTheDayObj.SelectionMethod,
+		stored := recordOfTheDayObj.Breakdown
+		if len(stored) == 0 {
+			stored = recordOfTheDayObj.LegacyBreakdown()
 		}
+		breakdown = copyBreakdown(stored)
 	}
 
+	breakdown["thermodynamic_enabled"] = h.config.Recommendation.ThermodynamicEnabled
+
 	var recordOfTheDayObjPtr *models.RecordOfTheDay
 	if recordOfTheDay != nil {
 		recordOfTheDayObjPtr = &recordOfTheDayObj
@@ -161,6 +248,110 @@ func (h *Handler) GetDashboard(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// fallbackSelectionMethod marks a record of the day picked locally rather
+// than by the thermodynamic service
+const fallbackSelectionMethod = "fallback_highest_score"
+
+// fallbackRecordOfTheDay picks the highest scoring listing in the candidate
+// pool, or nil if none has been scored. It also returns how many scored
+// listings it chose from.
+func (h *Handler) fallbackRecordOfTheDay() (*models.Listing, int64) {
+	var candidates int64
+	h.candidatePool(h.db.Model(&models.Listing{})).Where("score > ?", 0).Count(&candidates)
+
+	var listing models.Listing
+	if err := h.candidatePool(h.db.Preload("Record").Preload("Seller")).
+		Where("score > ?", 0).Order("score DESC").First(&listing).Error; err != nil {
+		return nil, candidates
+	}
+	return &listing, candidates
+}
+
+// candidatePool restricts a listing query to the configured record of the
+// day candidates
+func (h *Handler) candidatePool(query *gorm.DB) *gorm.DB {
+	pool := h.config.Recommendation.CandidatePool
+	if pool.KeptOnly {
+		query = query.Where("kept = ?", true)
+	}
+	if pool.UnevaluatedOnly {
+		query = query.Where("evaluated = ?", false)
+	}
+	if pool.MinPrice > 0 {
+		query = query.Where("record_price >= ?", pool.MinPrice)
+	}
+	if pool.MaxPrice > 0 {
+		query = query.Where("record_price <= ?", pool.MaxPrice)
+	}
+	if pool.MinScore > 0 {
+		query = query.Where("score >= ?", pool.MinScore)
+	}
+	return query
+}
+
+// candidateIDs lists the listings in the candidate pool for the
+// thermodynamic service to pick from, or nil when no pool is configured
+func (h *Handler) candidateIDs() []uint {
+	if h.config.Recommendation.CandidatePool == (config.CandidatePool{}) {
+		return nil
+	}
+	ids := []uint{}
+	if err := h.candidatePool(h.db.Model(&models.Listing{})).Pluck("id", &ids).Error; err != nil {
+		log.Printf("Error listing record of the day candidates: %v", err)
+	}
+	return ids
+}
+
+// candidateListing loads the listing the thermodynamic service picked. The
+// service is asked to pick from the candidate pool but isn't trusted to, so
+// a listing outside it isn't found.
+func (h *Handler) candidateListing(id int) (*models.Listing, error) {
+	var listing models.Listing
+	if err := h.candidatePool(h.db.Preload("Record").Preload("Seller")).First(&listing, id).Error; err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// newRecordOfTheDay builds today's pick of listing with the thermodynamic
+// service's breakdown, filling in the configured pool's size when the
+// service didn't report one
+func (h *Handler) newRecordOfTheDay(listing models.Listing, breakdown map[string]interface{}) models.RecordOfTheDay {
+	stored := models.JSONMap(copyBreakdown(breakdown))
+	if _, ok := stored["total_candidates"].(float64); !ok {
+		var count int64
+		h.candidatePool(h.db.Model(&models.Listing{})).Count(&count)
+		stored["total_candidates"] = count
+	}
+	selectionMethod, _ := stored["selection_method"].(string)
+
+	return models.RecordOfTheDay{
+		Date:            time.Now(),
+		ListingID:       listing.ID,
+		Listing:         listing,
+		Breakdown:       stored,
+		SelectionMethod: selectionMethod,
+	}
+}
+
+// copyBreakdown copies a breakdown so the response can add to it without
+// changing the stored one
+func copyBreakdown(breakdown map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(breakdown)+1)
+	for key, value := range breakdown {
+		copied[key] = value
+	}
+	return copied
+}
+
+// dashboardETag identifies a dashboard state by today's pick, its votes and the counts
+func dashboardETag(date string, rotd models.RecordOfTheDay, numRecords, numListings, unevaluated int64, accuracy float64) string {
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s|%d|%.4f|%.4f|%d|%d|%d|%.4f",
+		date, rotd.ID, rotd.AverageDesirability, rotd.AverageNovelty,
+		numRecords, numListings, unevaluated, accuracy)))
+	return `W/"` + hex.EncodeToString(hash[:]) + `"`
+}
+
 // GetDashboardListings handles GET /api/dashboard/listings/
 func (h *Handler) GetDashboardListings(c *gin.Context) {
 	var listings []models.Listing
@@ -187,8 +378,17 @@ func (h *Handler) RefreshRecordOfTheDay(c *gin.Context) {
 	// Delete existing record for today
 	h.db.Where("date = ?", today).Delete(&models.RecordOfTheDay{})
 
+	// Without the thermodynamic service the dashboard picks locally each time
+	if !h.config.Recommendation.ThermodynamicEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Record of the Day refreshed successfully",
+			"selection_method": fallbackSelectionMethod,
+		})
+		return
+	}
+
 	// Get new selection from thermodynamic service
-	thermoResp, err := h.externalService.GetThermodynamicSelection(true)
+	thermoResp, err := h.externalService.GetThermodynamicSelection(true, h.candidateIDs())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get thermodynamic selection: " + err.Error(),
@@ -204,35 +404,24 @@ func (h *Handler) RefreshRecordOfTheDay(c *gin.Context) {
 	}
 
 	// Get the listing and save new record of the day
-	var listing models.Listing
-	if err := h.db.Preload("Record").Preload("Seller").
-		First(&listing, thermoResp.ListingID).Error; err != nil {
+	listing, err := h.candidateListing(thermoResp.ListingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Store no pick, so the dashboard falls back to a local one
+		log.Printf("Thermodynamic service picked listing %d, which isn't in the candidate pool", thermoResp.ListingID)
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Record of the Day refreshed successfully",
+			"selection_method": fallbackSelectionMethod,
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to find selected listing",
 		})
 		return
 	}
 
-	recordOfTheDayObj := models.RecordOfTheDay{
-		Date:      time.Now(),
-		ListingID: uint(thermoResp.ListingID),
-		Listing:   listing,
-	}
-
-	// Extract breakdown values
-	if val, ok := thermoResp.Breakdown["model_score"].(float64); ok {
-		recordOfTheDayObj.ModelScore = val
-	}
-	if val, ok := thermoResp.Breakdown["entropy_measure"].(float64); ok {
-		recordOfTheDayObj.EntropyMeasure = val
-	}
-	if val, ok := thermoResp.Breakdown["system_temperature"].(float64); ok {
-		recordOfTheDayObj.SystemTemperature = val
-	}
-	if val, ok := thermoResp.Breakdown["selection_method"].(string); ok {
-		recordOfTheDayObj.SelectionMethod = val
-	}
-
+	recordOfTheDayObj := h.newRecordOfTheDay(*listing, thermoResp.Breakdown)
 	if err := h.db.Create(&recordOfTheDayObj).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to save new record of the day",
@@ -247,207 +436,222 @@ func (h *Handler) RefreshRecordOfTheDay(c *gin.Context) {
 
 // SearchListings handles GET /search/results/
 func (h *Handler) SearchListings(c *gin.Context) {
-	query := h.db.Preload("Record").Preload("Seller")
-
-	// Text search
-	if q := c.Query("q"); q != "" {
-		query = query.Where(
-			"discogs_record.artist ILIKE ? OR discogs_record.title ILIKE ? OR discogs_record.label ILIKE ?",
-			"%"+q+"%", "%"+q+"%", "%"+q+"%",
-		).Joins("JOIN discogs_record ON discogs_listing.record_id = discogs_record.id")
-	}
-
-	// Genre/Style filter
-	if genreStyle := c.Query("genre_style"); genreStyle != "" {
-		query = query.Where(
-			"discogs_record.genres::text ILIKE ? OR discogs_record.styles::text ILIKE ?",
-			"%"+genreStyle+"%", "%"+genreStyle+"%",
-		).Joins("JOIN discogs_record ON discogs_listing.record_id = discogs_record.id")
-	}
-
-	// Year range filter
-	if minYear := c.Query("min_year"); minYear != "" {
-		if maxYear := c.Query("max_year"); maxYear != "" {
-			if minYearInt, err := strconv.Atoi(minYear); err == nil {
-				if maxYearInt, err := strconv.Atoi(maxYear); err == nil {
-					query = query.Where(
-						"discogs_record.year BETWEEN ? AND ?", minYearInt, maxYearInt,
-					).Joins("JOIN discogs_record ON discogs_listing.record_id = discogs_record.id")
-				}
-			}
-		}
-	}
+	q := h.searchListingQuery(c).sortBy(c.DefaultQuery("sort", "score_desc"))
+	query := q.DB().Preload("Record").Preload("Seller")
 
-	// Price range filter
-	if minPrice := c.Query("min_price"); minPrice != "" {
-		if maxPrice := c.Query("max_price"); maxPrice != "" {
-			if minPriceFloat, err := strconv.ParseFloat(minPrice, 64); err == nil {
-				if maxPriceFloat, err := strconv.ParseFloat(maxPrice, 64); err == nil {
-					query = query.Where("record_price BETWEEN ? AND ?", minPriceFloat, maxPriceFloat)
-				}
-			}
-		}
-	}
+	// Pagination
+	p := newPagination(c, 20)
 
-	// Condition filter
-	if condition := c.Query("condition"); condition != "" {
-		query = query.Where("media_condition ILIKE ?", condition)
-	}
+	var listings []models.Listing
 
-	// Seller filter
-	if seller := c.Query("seller"); seller != "" {
-		query = query.Where(
-			"discogs_seller.name ILIKE ?", "%"+seller+"%",
-		).Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id")
-	}
-
-	// Sorting
-	sort := c.DefaultQuery("sort", "score_desc")
-	switch sort {
-	case "price_asc":
-		query = query.Order("record_price ASC")
-	case "price_desc":
-		query = query.Order("record_price DESC")
-	case "year_asc":
-		query = query.Joins("JOIN discogs_record ON discogs_listing.record_id = discogs_record.id").
-			Order("discogs_record.year ASC")
-	case "year_desc":
-		query = query.Joins("JOIN discogs_record ON discogs_listing.record_id = discogs_record.id").
-			Order("discogs_record.year DESC")
-	default:
-		query = query.Order("score DESC")
-	}
+	query.Count(&p.total)
+	query.Limit(p.limit).Offset(p.offset()).Find(&listings)
 
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
+	p.setHeaders(c)
+	response := gin.H{
+		"count":    p.total,
+		"next":     p.nextPage(),
+		"previous": p.prevPage(),
+		"results":  listings,
+	}
+	if c.Query("min_price") != "" && c.Query("max_price") != "" {
+		// Null when the bounds were compared unconverted against mixed currencies
+		response["price_currency"] = q.priceCurrency
 	}
-	limit := 20
-	offset := (page - 1) * limit
 
-	var listings []models.Listing
-	var total int64
+	c.JSON(http.StatusOK, response)
+}
+
+// CountListings handles GET /listings/count/. It takes the same filters as
+// SearchListings but only counts the matches.
+func (h *Handler) CountListings(c *gin.Context) {
+	query := h.searchListingQuery(c).DB()
 
-	query.Count(&total)
-	query.Limit(limit).Offset(offset).Find(&listings)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Error counting listings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count listings"})
+		return
+	}
 
-	// Calculate pagination info
-	hasNext := int64(offset+limit) < total
-	hasPrev := page > 1
+	c.JSON(http.StatusOK, gin.H{"count": total})
+}
 
-	var nextPage, prevPage *int
-	if hasNext {
-		next := page + 1
-		nextPage = &next
+// basePriceExpr returns a SQL expression converting a listing's price into the
+// configured base currency. ok is false when exchange rates are unavailable.
+// Listings in currencies without a rate convert to NULL and never match.
+func (h *Handler) basePriceExpr() (expr string, args []interface{}, ok bool) {
+	base := h.config.External.BaseCurrency
+	if base == "" {
+		base = "USD"
 	}
-	if hasPrev {
-		prev := page - 1
-		prevPage = &prev
+	return h.convertedPriceExpr(base)
+}
+
+// convertedPriceExpr is basePriceExpr for any target currency
+func (h *Handler) convertedPriceExpr(target string) (expr string, args []interface{}, ok bool) {
+	rates, err := h.externalService.GetExchangeRates(target)
+	if err != nil {
+		log.Printf("Warning: comparing prices unconverted: %v", err)
+		return "", nil, false
 	}
 
-	response := gin.H{
-		"count":    total,
-		"next":     nextPage,
-		"previous": prevPage,
-		"results":  listings,
+	var currencies []string
+	h.db.Model(&models.Seller{}).Distinct().Pluck("currency", &currencies)
+
+	var b strings.Builder
+	b.WriteString("(discogs_listing.record_price / CASE (SELECT currency FROM discogs_seller WHERE discogs_seller.id = discogs_listing.seller_id)")
+	for _, cur := range currencies {
+		if rate, found := rates[strings.ToUpper(cur)]; found && rate > 0 {
+			b.WriteString(" WHEN ? THEN ?")
+			args = append(args, cur, rate)
+		}
+	}
+	if len(args) == 0 {
+		return "NULL", nil, true
 	}
+	b.WriteString(" ELSE NULL END)")
 
-	c.JSON(http.StatusOK, response)
+	return b.String(), args, true
 }
 
-// GetGenreAutocomplete handles GET /autocomplete/genre/
-func (h *Handler) GetGenreAutocomplete(c *gin.Context) {
+// autocomplete responds with suggest's suggestions for the term parameter,
+// serving repeated terms from the cache
+func (h *Handler) autocomplete(c *gin.Context, endpoint string, suggest func(term string) []string) {
 	term := strings.ToLower(c.Query("term"))
 	if term == "" {
 		c.JSON(http.StatusOK, []string{})
 		return
 	}
 
-	var records []models.Record
-	h.db.Select("genres, styles").Where(
-		"genres::text ILIKE ? OR styles::text ILIKE ?",
-		"%"+term+"%", "%"+term+"%",
-	).Limit(100).Find(&records)
-
-	genreSet := make(map[string]bool)
-	for _, record := range records {
-		for _, genre := range record.Genres {
-			if strings.Contains(strings.ToLower(genre), term) {
-				genreSet[genre] = true
-			}
-		}
-		for _, style := range record.Styles {
-			if strings.Contains(strings.ToLower(style), term) {
-				genreSet[style] = true
-			}
-		}
-	}
-
-	var suggestions []string
-	for genre := range genreSet {
-		suggestions = append(suggestions, genre)
-		if len(suggestions) >= 10 {
-			break
-		}
+	suggestions, ok := h.autocompleteCache.get(endpoint, term)
+	if !ok {
+		suggestions = suggest(term)
+		h.autocompleteCache.set(endpoint, term, suggestions)
 	}
 
 	c.JSON(http.StatusOK, suggestions)
 }
 
+// GetGenreAutocomplete handles GET /autocomplete/genre/
+func (h *Handler) GetGenreAutocomplete(c *gin.Context) {
+	h.autocomplete(c, "genre", h.genreSuggestions)
+}
+
+// genreSuggestions returns the genres and styles containing term, most
+// common first
+func (h *Handler) genreSuggestions(term string) []string {
+	return h.jsonArraySuggestions(term, "genres", "styles")
+}
+
 // GetConditionAutocomplete handles GET /autocomplete/condition/
 func (h *Handler) GetConditionAutocomplete(c *gin.Context) {
-	term := strings.ToLower(c.Query("term"))
-	if term == "" {
-		c.JSON(http.StatusOK, []string{})
-		return
-	}
+	h.autocomplete(c, "condition", h.conditionSuggestions)
+}
 
+// conditionSuggestions returns the media conditions containing term
+func (h *Handler) conditionSuggestions(term string) []string {
 	var conditions []string
 	h.db.Model(&models.Listing{}).
 		Select("DISTINCT media_condition").
-		Where("media_condition ILIKE ?", "%"+term+"%").
-		Limit(10).
+		Where(containsInsensitive("media_condition", term)).
+		Limit(h.autocompleteLimit()).
 		Pluck("media_condition", &conditions)
 
-	c.JSON(http.StatusOK, conditions)
+	return conditions
+}
+
+// GetFormatAutocomplete handles GET /autocomplete/format/
+func (h *Handler) GetFormatAutocomplete(c *gin.Context) {
+	h.autocomplete(c, "format", h.formatSuggestions)
+}
+
+// formatSuggestions returns the record formats containing term
+func (h *Handler) formatSuggestions(term string) []string {
+	var formats []string
+	h.db.Model(&models.Record{}).
+		Distinct("format").
+		Where("LOWER(format) LIKE ?", "%"+term+"%").
+		Order("format").
+		Limit(h.autocompleteLimit()).
+		Pluck("format", &formats)
+
+	return formats
+}
+
+// GetShipsFromAutocomplete handles GET /autocomplete/ships-from/
+func (h *Handler) GetShipsFromAutocomplete(c *gin.Context) {
+	h.autocomplete(c, "ships_from", h.shipsFromSuggestions)
+}
+
+// shipsFromSuggestions returns the countries listings ship from containing term
+func (h *Handler) shipsFromSuggestions(term string) []string {
+	var countries []string
+	h.db.Model(&models.Listing{}).
+		Distinct("ships_from").
+		Where(containsInsensitive("ships_from", term)).
+		Order("ships_from").
+		Limit(h.autocompleteLimit()).
+		Pluck("ships_from", &countries)
+
+	return countries
 }
 
 // GetStylesAutocomplete handles GET /autocomplete/styles/
 func (h *Handler) GetStylesAutocomplete(c *gin.Context) {
-	term := strings.ToLower(c.Query("term"))
-	if term == "" {
-		c.JSON(http.StatusOK, []string{})
-		return
-	}
+	h.autocomplete(c, "styles", h.styleSuggestions)
+}
 
-	var records []models.Record
-	h.db.Select("styles").Where("styles::text ILIKE ?", "%"+term+"%").Limit(100).Find(&records)
+// styleSuggestions returns the styles containing term, most common first
+func (h *Handler) styleSuggestions(term string) []string {
+	return h.jsonArraySuggestions(term, "styles")
+}
 
-	styleSet := make(map[string]bool)
-	for _, record := range records {
-		for _, style := range record.Styles {
-			if strings.Contains(strings.ToLower(style), term) {
-				styleSet[style] = true
-			}
-		}
+// jsonArraySuggestions counts every element of the given record JSON array
+// columns that contains term and returns the most common. Aggregating in the
+// database keeps common values from being missed however many records match.
+func (h *Handler) jsonArraySuggestions(term string, columns ...string) []string {
+	elements := make([]string, len(columns))
+	for i, column := range columns {
+		elements[i] = jsonArrayElements(h.db, "discogs_record", column)
 	}
 
 	var suggestions []string
-	for style := range styleSet {
-		suggestions = append(suggestions, style)
-		if len(suggestions) >= 10 {
-			break
-		}
+	err := h.db.Raw(
+		"SELECT name FROM ("+strings.Join(elements, " UNION ALL ")+") AS elements "+
+			"WHERE LOWER(name) LIKE ? GROUP BY name ORDER BY COUNT(*) DESC, name LIMIT ?",
+		"%"+term+"%", h.autocompleteLimit(),
+	).Scan(&suggestions).Error
+	if err != nil {
+		log.Printf("Error building %s suggestions: %v", strings.Join(columns, "/"), err)
 	}
 
-	c.JSON(http.StatusOK, suggestions)
+	return suggestions
+}
+
+// autocompleteLimit is the most suggestions an autocomplete returns
+func (h *Handler) autocompleteLimit() int {
+	if h.config.Server.AutocompleteLimit > 0 {
+		return h.config.Server.AutocompleteLimit
+	}
+	return 10
+}
+
+// SellerListings is the response of a seller search: the sellers whose name
+// matched and their listings, grouped by seller
+type SellerListings struct {
+	Sellers  []string         `json:"sellers"`
+	Listings []models.Listing `json:"listings"`
+	// Truncated is set when more listings matched than MAX_RESULT_ROWS
+	Truncated bool `json:"truncated"`
 }
 
-// SearchSellerListings handles POST /by-seller/search/
+// SearchSellerListings handles POST /by-seller/search/. The seller name
+// matches case-insensitively anywhere in a name unless exact is set.
 func (h *Handler) SearchSellerListings(c *gin.Context) {
 	var req struct {
 		Seller string `json:"seller"`
+		Exact  bool   `json:"exact"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -460,13 +664,23 @@ func (h *Handler) SearchSellerListings(c *gin.Context) {
 		return
 	}
 
-	var listings []models.Listing
-	h.db.Preload("Record").Preload("Seller").
-		Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id").
-		Where("discogs_seller.name = ?", req.Seller).
-		Find(&listings)
+	nameMatch, name := "LOWER(discogs_seller.name) LIKE ?", "%"+strings.ToLower(req.Seller)+"%"
+	if req.Exact {
+		nameMatch, name = "discogs_seller.name = ?", req.Seller
+	}
 
-	c.JSON(http.StatusOK, listings)
+	response := SellerListings{Sellers: []string{}, Listings: []models.Listing{}}
+	h.db.Model(&models.Seller{}).Where(nameMatch, name).Order("name").Pluck("name", &response.Sellers)
+
+	query := newListingQuery(h.db).join(joinSeller).where(nameMatch, name).DB().
+		Preload("Record").Preload("Seller").
+		Order("discogs_seller.name, discogs_listing.id")
+	h.limitResults(query).Find(&response.Listings)
+
+	keep, truncated := h.truncateResults(c, len(response.Listings))
+	response.Listings, response.Truncated = response.Listings[:keep], truncated
+
+	c.JSON(http.StatusOK, response)
 }
 
 // TriggerSellerScrape handles POST /data/:seller
@@ -508,12 +722,262 @@ func (h *Handler) GetRecordsBySeller(c *gin.Context) {
 	}
 
 	var records []models.Record
-	h.db.Joins("JOIN discogs_listing ON discogs_record.id = discogs_listing.record_id").
+	query := h.db.Joins("JOIN discogs_listing ON discogs_record.id = discogs_listing.record_id AND discogs_listing.deleted_at IS NULL").
 		Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id").
 		Where("discogs_seller.name = ?", sellerName).
-		Find(&records)
+		Order("discogs_record.id")
+	h.limitResults(query).Find(&records)
+
+	keep, _ := h.truncateResults(c, len(records))
+	c.JSON(http.StatusOK, records[:keep])
+}
 
-	c.JSON(http.StatusOK, records)
+// SellerStats summarises a seller's catalog and scrape freshness
+type SellerStats struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	// Rating is the seller's positive feedback percentage, null if unknown
+	Rating        *float64   `json:"rating"`
+	FeedbackCount int        `json:"feedback_count"`
+	ListingCount  int64      `json:"listing_count"`
+	LastScrapedAt *time.Time `json:"last_scraped_at"`
+}
+
+// sellerStatsQuery selects SellerStats for every seller, counting the
+// listings still available
+func (h *Handler) sellerStatsQuery() *gorm.DB {
+	return h.db.Table("discogs_seller").
+		Select("discogs_seller.id, discogs_seller.name, discogs_seller.currency, " +
+			"discogs_seller.rating, discogs_seller.feedback_count, " +
+			"discogs_seller.last_scraped_at, COUNT(discogs_listing.id) AS listing_count").
+		Joins("LEFT JOIN discogs_listing ON discogs_listing.seller_id = discogs_seller.id AND discogs_listing.deleted_at IS NULL").
+		Group("discogs_seller.id, discogs_seller.name, discogs_seller.currency, " +
+			"discogs_seller.rating, discogs_seller.feedback_count, discogs_seller.last_scraped_at")
+}
+
+// GetSellerStats handles GET /api/sellers/stats/
+func (h *Handler) GetSellerStats(c *gin.Context) {
+	query := h.sellerStatsQuery()
+
+	// Only sellers never scraped or not scraped in the last N days, stalest first
+	if staleDays := c.Query("stale_days"); staleDays != "" {
+		days, err := strconv.Atoi(staleDays)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stale_days must be a non-negative integer"})
+			return
+		}
+		cutoff := time.Now().AddDate(0, 0, -days)
+		query = query.Where("discogs_seller.last_scraped_at IS NULL OR discogs_seller.last_scraped_at < ?", cutoff).
+			Order("discogs_seller.last_scraped_at IS NOT NULL, discogs_seller.last_scraped_at ASC")
+	} else {
+		query = query.Order("discogs_seller.name ASC")
+	}
+
+	var stats []SellerStats
+	if err := h.limitResults(query).Scan(&stats).Error; err != nil {
+		log.Printf("Error fetching seller stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seller stats"})
+		return
+	}
+
+	keep, _ := h.truncateResults(c, len(stats))
+	c.JSON(http.StatusOK, stats[:keep])
+}
+
+// GetSeller handles GET /sellers/:seller, returning the stored seller with its
+// currency, rating, last scrape time and available listing count
+func (h *Handler) GetSeller(c *gin.Context) {
+	var stats []SellerStats
+	sellerName := c.Param("seller")
+	if err := h.sellerStatsQuery().Where("discogs_seller.name = ?", sellerName).
+		Limit(1).Scan(&stats).Error; err != nil {
+		log.Printf("Error fetching seller %s: %v", sellerName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seller"})
+		return
+	}
+	if len(stats) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Seller not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats[0])
+}
+
+// maxListingIDs caps how many listings GetListingsByIDs returns in one request
+const maxListingIDs = 100
+
+// GetListingsByIDs handles GET /listings/?ids=1,2,3, returning the listings
+// with their record and seller in the order asked for. IDs may also be given
+// as repeated ids parameters; unknown IDs and repeats are left out.
+func (h *Handler) GetListingsByIDs(c *gin.Context) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, param := range c.QueryArray("ids") {
+		for _, idStr := range strings.Split(param, ",") {
+			idStr = strings.TrimSpace(idStr)
+			if idStr == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(idStr, 10, 0)
+			if err != nil || id == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid listing ID %q", idStr)})
+				return
+			}
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				ids = append(ids, uint(id))
+			}
+		}
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+	if len(ids) > maxListingIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d listing IDs per request", maxListingIDs)})
+		return
+	}
+
+	var listings []models.Listing
+	if err := h.db.Preload("Record").Preload("Seller").Where("id IN ?", ids).Find(&listings).Error; err != nil {
+		log.Printf("Error fetching listings by ID: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+		return
+	}
+	listingsByID := make(map[uint]models.Listing, len(listings))
+	for _, listing := range listings {
+		listingsByID[listing.ID] = listing
+	}
+
+	ordered := make([]models.Listing, 0, len(listings))
+	for _, id := range ids {
+		if listing, ok := listingsByID[id]; ok {
+			ordered = append(ordered, listing)
+		}
+	}
+
+	c.JSON(http.StatusOK, ordered)
+}
+
+// GetListingPriceHistory handles GET /listings/:id/price-history/
+func (h *Handler) GetListingPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	// Removed listings keep their history
+	var listing models.Listing
+	if err := h.db.Unscoped().Preload("Seller").First(&listing, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		return
+	}
+
+	var history []models.PriceHistory
+	if err := h.db.Where("listing_id = ?", listing.ID).
+		Order("observed_at ASC").Find(&history).Error; err != nil {
+		log.Printf("Error fetching price history for listing %d: %v", listing.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"listing_id":    listing.ID,
+		"current_price": listing.RecordPrice,
+		"currency":      listing.Seller.Currency,
+		"history":       history,
+	})
+}
+
+// PriceDrop is a listing whose latest observed price is below an earlier one
+type PriceDrop struct {
+	Listing       models.Listing `json:"listing"`
+	PreviousPrice float64        `json:"previous_price"`
+	CurrentPrice  float64        `json:"current_price"`
+	DropAmount    float64        `json:"drop_amount"`
+	DropPercent   float64        `json:"drop_percent"`
+}
+
+// GetPriceDrops handles GET /api/price-drops/
+func (h *Handler) GetPriceDrops(c *gin.Context) {
+	minDropPercent, err := strconv.ParseFloat(c.DefaultQuery("min_drop_percent", "10"), 64)
+	if err != nil || minDropPercent < 0 || minDropPercent >= 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_drop_percent must be between 0 and 100"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	// Compare each listing's latest observation against its highest observed
+	// price. Observations made at the same time are told apart by ID.
+	query := h.db.Table("discogs_pricehistory AS latest").
+		Select("latest.listing_id, peak.max_price AS previous_price, latest.price AS current_price").
+		Where("NOT EXISTS (SELECT 1 FROM discogs_pricehistory newer WHERE newer.listing_id = latest.listing_id AND "+
+			"(newer.observed_at > latest.observed_at OR (newer.observed_at = latest.observed_at AND newer.id > latest.id)))").
+		Joins("JOIN (SELECT listing_id, MAX(price) AS max_price FROM discogs_pricehistory GROUP BY listing_id) peak "+
+			"ON peak.listing_id = latest.listing_id").
+		Joins("JOIN discogs_listing ON discogs_listing.id = latest.listing_id AND discogs_listing.deleted_at IS NULL").
+		Where("latest.price < peak.max_price * ?", 1-minDropPercent/100).
+		Order("(peak.max_price - latest.price) / peak.max_price DESC").
+		Limit(limit)
+
+	if kept := c.Query("kept"); kept != "" {
+		if keptBool, err := strconv.ParseBool(kept); err == nil {
+			query = query.Where("discogs_listing.kept = ?", keptBool)
+		}
+	}
+	if predicted := c.Query("predicted_keeper"); predicted != "" {
+		if predictedBool, err := strconv.ParseBool(predicted); err == nil {
+			query = query.Where("discogs_listing.predicted_keeper = ?", predictedBool)
+		}
+	}
+
+	var rows []struct {
+		ListingID     uint
+		PreviousPrice float64
+		CurrentPrice  float64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		log.Printf("Error finding price drops: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find price drops"})
+		return
+	}
+
+	listingIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		listingIDs[i] = row.ListingID
+	}
+
+	var listings []models.Listing
+	if len(listingIDs) > 0 {
+		h.db.Preload("Record").Preload("Seller").Where("id IN ?", listingIDs).Find(&listings)
+	}
+	listingsByID := make(map[uint]models.Listing, len(listings))
+	for _, listing := range listings {
+		listingsByID[listing.ID] = listing
+	}
+
+	drops := make([]PriceDrop, 0, len(rows))
+	for _, row := range rows {
+		listing, ok := listingsByID[row.ListingID]
+		if !ok {
+			continue
+		}
+		dropAmount := row.PreviousPrice - row.CurrentPrice
+		drops = append(drops, PriceDrop{
+			Listing:       listing,
+			PreviousPrice: row.PreviousPrice,
+			CurrentPrice:  row.CurrentPrice,
+			DropAmount:    dropAmount,
+			DropPercent:   dropAmount / row.PreviousPrice * 100,
+		})
+	}
+
+	c.JSON(http.StatusOK, drops)
 }
 
 // GetRecommendationPredictions handles GET /recommendation-predictions/
@@ -531,34 +995,112 @@ func (h *Handler) GetRecommendationPredictions(c *gin.Context) {
 		}
 	}
 
+	if h.excludeEvaluated(c) {
+		var err error
+		if listingIDs, err = h.unevaluatedIDs(listingIDs); err != nil {
+			log.Printf("Error filtering evaluated listings: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+			return
+		}
+		if len(listingIDs) == 0 {
+			c.JSON(http.StatusOK, []gin.H{})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.predictListings(listingIDs))
+}
+
+// listingPrediction is the recommender's verdict on one listing
+type listingPrediction struct {
+	ID          int     `json:"id"`
+	Prediction  bool    `json:"prediction"`
+	Probability float64 `json:"probability"`
+	// fallback is set on the default given when the recommender failed
+	fallback bool
+}
+
+// defaultPrediction stands in for the recommender when it can't be reached
+func defaultPrediction(id int) listingPrediction {
+	return listingPrediction{ID: id, Prediction: true, Probability: 0.5, fallback: true}
+}
+
+// predictListings asks the recommender about the listings and stores the
+// probabilities it returns. If the recommender fails every listing gets the
+// default prediction, which isn't stored.
+func (h *Handler) predictListings(listingIDs []int) []listingPrediction {
 	// Call the recommendation microservice
 	resp, err := h.externalService.GetRecommendations(listingIDs)
 	if err != nil {
 		log.Printf("Error calling recommendation service: %v", err)
 		// Return default predictions on error
-		var predictions []gin.H
+		var predictions []listingPrediction
 		for _, id := range listingIDs {
-			predictions = append(predictions, gin.H{
-				"id":          id,
-				"prediction":  true,
-				"probability": 0.5,
-			})
+			predictions = append(predictions, defaultPrediction(id))
 		}
-		c.JSON(http.StatusOK, predictions)
-		return
+		return predictions
 	}
 
-	// Convert to expected format
-	var predictions []gin.H
+	// Convert to expected format, deriving the keeper flag from our own threshold
+	var predictions []listingPrediction
 	for _, pred := range resp.Predictions {
-		predictions = append(predictions, gin.H{
-			"id":          pred.ID,
-			"prediction":  pred.Prediction,
-			"probability": pred.Probability,
+		h.storePrediction(pred.ID, pred.Probability)
+
+		predictions = append(predictions, listingPrediction{
+			ID:          pred.ID,
+			Prediction:  h.isPredictedKeeper(pred.Probability),
+			Probability: pred.Probability,
 		})
 	}
+	return predictions
+}
+
+// excludeEvaluated reports whether a request leaves evaluated listings out:
+// the exclude_evaluated parameter if given, else the configured default
+func (h *Handler) excludeEvaluated(c *gin.Context) bool {
+	if exclude, err := strconv.ParseBool(c.Query("exclude_evaluated")); err == nil {
+		return exclude
+	}
+	return h.config.Recommendation.ExcludeEvaluated
+}
+
+// unevaluatedIDs keeps the IDs of listings not yet evaluated, in order
+func (h *Handler) unevaluatedIDs(ids []int) ([]int, error) {
+	var unevaluated []int
+	if err := h.db.Model(&models.Listing{}).Where("id IN ? AND evaluated = ?", ids, false).
+		Pluck("id", &unevaluated).Error; err != nil {
+		return nil, err
+	}
+	keep := make(map[int]bool, len(unevaluated))
+	for _, id := range unevaluated {
+		keep[id] = true
+	}
+
+	var kept []int
+	for _, id := range ids {
+		if keep[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+// storePrediction saves a listing's model probability and the keeper flag
+// derived from it, reporting whether it was saved
+func (h *Handler) storePrediction(listingID int, probability float64) bool {
+	if err := h.db.Model(&models.Listing{}).Where("id = ?", listingID).Updates(map[string]interface{}{
+		"predicted_keeper":   h.isPredictedKeeper(probability),
+		"keeper_probability": &probability,
+	}).Error; err != nil {
+		log.Printf("Failed to store prediction for listing %d: %v", listingID, err)
+		return false
+	}
+	return true
+}
 
-	c.JSON(http.StatusOK, predictions)
+// isPredictedKeeper reports whether a model probability meets the configured keeper threshold
+func (h *Handler) isPredictedKeeper(probability float64) bool {
+	return probability >= h.config.Recommendation.KeeperThreshold
 }
 
 // SubmitRecommendations handles POST /submit-scoring-selections/
@@ -596,13 +1138,41 @@ func (h *Handler) SubmitRecommendations(c *gin.Context) {
 	}
 
 	// Call the recommendation microservice to train the model
-	_, err := h.externalService.TrainModel(listingIDs, keeperIDs)
+	trained, err := h.externalService.TrainModel(listingIDs, keeperIDs)
 	if err != nil {
 		log.Printf("Error training model: %v", err)
 		// Continue even if training fails
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	// Record the batch for the session history, and so it can be undone
+	// with POST /recommendations/undo/
+	response := gin.H{"success": true}
+	if len(listingIDs) > 0 {
+		session := models.ReviewSession{ListingIDs: listingIDs, KeeperIDs: keeperIDs}
+		if err == nil && trained != nil && trained.Success {
+			session.Accuracy = &trained.Accuracy
+		}
+		if err := h.db.Create(&session).Error; err != nil {
+			log.Printf("Warning: failed to record review session: %v", err)
+		} else {
+			response["session_id"] = session.ID
+		}
+	}
+
+	// Optionally bring the stored predictions in line with the new model so
+	// the next review session reflects it; progress is at /api/predictions/refresh
+	if refresh, _ := strconv.ParseBool(c.PostForm("refresh_predictions")); refresh {
+		switch {
+		case err != nil || trained == nil || !trained.Success:
+			response["prediction_refresh"] = "skipped: training failed"
+		case h.startPredictionRefresh():
+			response["prediction_refresh"] = "started"
+		default:
+			response["prediction_refresh"] = "already running"
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetModelPerformanceStats handles GET /model-performance-stats/
@@ -627,59 +1197,119 @@ func (h *Handler) GetModelPerformanceStats(c *gin.Context) {
 	}
 
 	response := gin.H{
-		"accuracy":      accuracy,
-		"total_sessions": len(sessions),
-		"sessions":      sessions,
+		"accuracy":         accuracy,
+		"total_sessions":   len(sessions),
+		"sessions":         sessions,
+		"keeper_threshold": h.config.Recommendation.KeeperThreshold,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// ExportListingsCsv handles GET /export-listings
-func (h *Handler) ExportListingsCsv(c *gin.Context) {
-	var listings []models.Listing
-	h.db.Preload("Record").Preload("Seller").
-		Order("id DESC").Limit(5000).Find(&listings)
+// exportBatchSize is the number of listings loaded and flushed per CSV chunk
+const exportBatchSize = 500
 
+// exportMaxRows caps how many listings one export holds
+const exportMaxRows = 5000
+
+// ExportListingsCsv handles GET /export-listings. It accepts the search
+// filters, so an export can match what the search page shows. Listings come
+// newest first, at most exportMaxRows of them.
+func (h *Handler) ExportListingsCsv(c *gin.Context) {
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename=listings_export.csv")
+	// No Content-Length is set, so flushing each batch sends the export chunked
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
 
 	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
 
 	// Write headers
-	headers := []string{
-		"Listing ID", "Record Artist", "Record Title", "Record Label",
-		"Record Format", "Record Year", "Seller", "Record Price",
-		"Media Condition", "Score", "Kept", "Evaluated",
-	}
-	writer.Write(headers)
+	writeListingCSVHeader(writer, defaultCSVColumns)
+	writer.Flush()
+	c.Writer.Flush()
+
+	// Write data one batch at a time so the client starts receiving rows
+	// immediately. Each batch continues below the last ID written.
+	query := h.searchListingQuery(c).DB().Session(&gorm.Session{})
+	var lastID uint
+	for written := 0; written < exportMaxRows; {
+		limit := exportBatchSize
+		if remaining := exportMaxRows - written; remaining < limit {
+			limit = remaining
+		}
 
-	// Write data
-	for _, listing := range listings {
-		year := ""
-		if listing.Record.Year != nil {
-			year = strconv.Itoa(*listing.Record.Year)
+		batchQuery := query
+		if lastID > 0 {
+			batchQuery = batchQuery.Where("discogs_listing.id < ?", lastID)
+		}
+		var batch []models.Listing
+		if err := batchQuery.Preload("Record").Preload("Seller").
+			Order("discogs_listing.id DESC").Limit(limit).Find(&batch).Error; err != nil {
+			// Headers are already sent, so all we can do is log and truncate
+			log.Printf("CSV export aborted: %v", err)
+			return
 		}
 
-		row := []string{
-			strconv.Itoa(int(listing.ID)),
-			listing.Record.Artist,
-			listing.Record.Title,
-			listing.Record.Label,
-			listing.Record.Format,
-			year,
-			listing.Seller.Name,
-			fmt.Sprintf("%.2f", listing.RecordPrice),
-			listing.MediaCondition,
-			fmt.Sprintf("%.2f", listing.Score),
-			strconv.FormatBool(listing.Kept),
-			strconv.FormatBool(listing.Evaluated),
+		writeListingCSV(writer, batch, defaultCSVColumns)
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			// The client most likely went away; stop querying
+			log.Printf("CSV export aborted: %v", err)
+			return
 		}
-		writer.Write(row)
+		c.Writer.Flush()
+
+		if len(batch) < limit {
+			return
+		}
+		written += len(batch)
+		lastID = batch[len(batch)-1].ID
 	}
 }
 
+// ImportListingsCsv handles POST /api/import/csv. The file field holds a CSV
+// in the export format; its rows are stored in one transaction.
+func (h *Handler) ImportListingsCsv(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required in the file field"})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer f.Close()
+
+	listings, rowErrors, err := readListingCSV(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV: " + err.Error()})
+		return
+	}
+
+	result, err := services.ImportListings(h.db, listings)
+	if err != nil {
+		log.Printf("Error importing listings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import listings"})
+		return
+	}
+
+	// Rows that didn't parse count as errored alongside those that didn't save
+	result.Errored += len(rowErrors)
+	result.Errors = append(rowErrors, result.Errors...)
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+
+	if result.Imported > 0 {
+		// Imported records may bring new formats
+		h.clearCaches()
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // AddToWantlist handles POST /add-to-wantlist/
 func (h *Handler) AddToWantlist(c *gin.Context) {
 	recordID := c.PostForm("record_id")
@@ -702,19 +1332,24 @@ func (h *Handler) VoteRecordOfTheDay(c *gin.Context) {
 		return
 	}
 
-	desirabilityStr := c.PostForm("desirability")
-	noveltyStr := c.PostForm("novelty")
-
-	desirability, err := strconv.ParseFloat(desirabilityStr, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid desirability rating"})
-		return
-	}
-
-	novelty, err := strconv.ParseFloat(noveltyStr, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid novelty rating"})
-		return
+	var desirability, novelty float64
+	for _, field := range []struct {
+		name, label string
+		vote        *float64
+	}{
+		{"desirability", "Desirability", &desirability},
+		{"novelty", "Novelty", &novelty},
+	} {
+		vote, err := strconv.ParseFloat(c.PostForm(field.name), 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s rating", field.name)})
+			return
+		}
+		if msg := h.voteRangeError(field.label, vote); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		*field.vote = vote
 	}
 
 	// Get the record of the day
@@ -728,17 +1363,7 @@ func (h *Handler) VoteRecordOfTheDay(c *gin.Context) {
 	record.DesirabilityVotes = append(record.DesirabilityVotes, desirability)
 	record.NoveltyVotes = append(record.NoveltyVotes, novelty)
 
-	// Calculate averages
-	var desirabilitySum, noveltySum float64
-	for _, vote := range record.DesirabilityVotes {
-		desirabilitySum += vote
-	}
-	for _, vote := range record.NoveltyVotes {
-		noveltySum += vote
-	}
-
-	record.AverageDesirability = desirabilitySum / float64(len(record.DesirabilityVotes))
-	record.AverageNovelty = noveltySum / float64(len(record.NoveltyVotes))
+	record.UpdateAverages()
 
 	// Save to database
 	if err := h.db.Save(&record).Error; err != nil {
@@ -749,9 +1374,80 @@ func (h *Handler) VoteRecordOfTheDay(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Vote submitted! Thanks for your feedback."})
 }
 
+// voteRangeError explains why vote isn't an accepted rating, or returns ""
+func (h *Handler) voteRangeError(label string, vote float64) string {
+	minVote, maxVote := h.config.Recommendation.VoteRange()
+	// Written so NaN fails too
+	if !(vote >= minVote && vote <= maxVote) {
+		return fmt.Sprintf("%s rating must be between %g and %g", label, minVote, maxVote)
+	}
+	return ""
+}
+
+// RecomputeRecordOfTheDay handles POST /record-of-the-day/:id/recompute. It
+// recalculates the stored averages, from the feedback rows when there are
+// any and from the vote lists otherwise, for when votes were edited or
+// cleaned up by hand.
+func (h *Handler) RecomputeRecordOfTheDay(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	var record models.RecordOfTheDay
+	if err := h.db.First(&record, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record of the day not found"})
+		return
+	}
+
+	var feedback []models.RecordOfTheDayFeedback
+	if err := h.db.Where("record_of_the_day_id = ?", record.ID).Order("id").Find(&feedback).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feedback"})
+		return
+	}
+
+	source := "votes"
+	desirabilityVotes, noveltyVotes := record.DesirabilityVotes, record.NoveltyVotes
+	if len(feedback) > 0 {
+		source = "feedback"
+		desirabilityVotes = make(models.FloatSlice, len(feedback))
+		noveltyVotes = make(models.FloatSlice, len(feedback))
+		for i, f := range feedback {
+			desirabilityVotes[i] = float64(f.DesirabilityRating)
+			noveltyVotes[i] = float64(f.NoveltyRating)
+		}
+	}
+
+	previousDesirability, previousNovelty := record.AverageDesirability, record.AverageNovelty
+	// Only the averages are rewritten; the vote lists are left as they are
+	averages := models.RecordOfTheDay{DesirabilityVotes: desirabilityVotes, NoveltyVotes: noveltyVotes}
+	averages.UpdateAverages()
+	if err := h.db.Model(&record).Updates(map[string]interface{}{
+		"average_desirability": averages.AverageDesirability,
+		"average_novelty":      averages.AverageNovelty,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save averages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                    record.ID,
+		"source":                source,
+		"desirability_votes":    len(desirabilityVotes),
+		"novelty_votes":         len(noveltyVotes),
+		"previous_desirability": previousDesirability,
+		"previous_novelty":      previousNovelty,
+		"average_desirability":  averages.AverageDesirability,
+		"average_novelty":       averages.AverageNovelty,
+	})
+}
+
 // Go Scraper Endpoints
 
-// TriggerGoScraper handles POST /api/scraper/go/:seller
+// TriggerGoScraper handles POST /api/scraper/go/:seller. A request with an
+// Idempotency-Key header that was already used for the same scrape gets the
+// first request's response back rather than starting another scrape.
 func (h *Handler) TriggerGoScraper(c *gin.Context) {
 	sellerName := c.Param("seller")
 	if sellerName == "" {
@@ -759,45 +1455,262 @@ func (h *Handler) TriggerGoScraper(c *gin.Context) {
 		return
 	}
 
-	if h.scraperService == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Go scraper service is not available",
-		})
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	fullScan, _ := strconv.ParseBool(c.Query("full_scan"))
+
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		status, body := h.runGoScrape(sellerName, fullScan)
+		respondScrape(c, status, body)
 		return
 	}
 
-	// Scrape the user's inventory
-	result, err := h.scraperService.ScrapeUserInventory(sellerName)
+	request := fmt.Sprintf("%s full_scan=%t", sellerName, fullScan)
+	existing, claimed := h.idempotencyKeys.begin(key, request)
+	switch {
+	case claimed:
+		status, body := h.runGoScrape(sellerName, fullScan)
+		h.idempotencyKeys.complete(key, status, body)
+		respondScrape(c, status, body)
+	case existing.request != request:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key was already used for a different scrape",
+		})
+	case !existing.done:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A scrape with this Idempotency-Key is still in progress",
+		})
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(existing.status, existing.body)
+	}
+}
+
+// runGoScrape scrapes a seller's inventory, returning the status and body to
+// respond with; a full scan also removes listings that are gone
+func (h *Handler) runGoScrape(sellerName string, fullScan bool) (int, gin.H) {
+	result, err := h.scraperService.ScrapeUserInventory(sellerName, scraper.ScrapeOptions{FullScan: fullScan})
 	if err != nil {
 		log.Printf("Error scraping inventory with Go scraper: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to scrape inventory: " + err.Error(),
-		})
-		return
+		return scrapeError(sellerName, err)
 	}
 
 	if !result.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		return http.StatusInternalServerError, gin.H{
 			"error": result.Error,
-		})
+			"code":  scrapeErrorFailed,
+		}
+	}
+	if result.InventoryNotFound {
+		// The scrape succeeds so batch scrapes carry on past the seller, but
+		// whoever asked for this one should hear it isn't there
+		return scrapeError(sellerName, scraper.ErrSellerNotFound)
+	}
+
+	// The scrape may have added genres, styles and formats
+	h.clearCaches()
+
+	response := gin.H{
+		"success":             true,
+		"message":             fmt.Sprintf("Successfully scraped %d listings for %s", result.TotalRecords, sellerName),
+		"username":            result.Username,
+		"total_records":       result.TotalRecords,
+		"new_records":         result.NewRecords,
+		"new_keepers":         result.NewKeepers,
+		"full_scan":           result.FullScan,
+		"marked_unavailable":  result.MarkedUnavailable,
+		"listing_cap_reached": result.ListingCapReached,
+	}
+	if result.Note != "" {
+		response["note"] = result.Note
+	}
+	if result.BlockedAtPage > 0 {
+		response["blocked_at_page"] = result.BlockedAtPage
+	}
+	return http.StatusOK, response
+}
+
+// Codes in the body of a failed scrape's response, for clients to switch on
+const (
+	scrapeErrorFailed         = "scrape_failed"
+	scrapeErrorInProgress     = "scrape_in_progress"
+	scrapeErrorRateLimited    = "rate_limited"
+	scrapeErrorUnauthorized   = "unauthorized"
+	scrapeErrorSellerNotFound = "seller_not_found"
+	scrapeErrorBadResponse    = "bad_discogs_response"
+)
+
+// scrapeError maps a scrape error to the status and body to respond with.
+// Rate limited scrapes carry retry_after, in seconds.
+func scrapeError(sellerName string, err error) (int, gin.H) {
+	status, code := http.StatusInternalServerError, scrapeErrorFailed
+	message := "Failed to scrape inventory: " + err.Error()
+	switch {
+	case errors.Is(err, services.ErrScrapeInProgress):
+		status, code = http.StatusConflict, scrapeErrorInProgress
+		message = fmt.Sprintf("A scrape of %s is already running", sellerName)
+	case errors.Is(err, scraper.ErrRateLimited):
+		status, code = http.StatusTooManyRequests, scrapeErrorRateLimited
+	case errors.Is(err, scraper.ErrUnauthorized):
+		status, code = http.StatusUnauthorized, scrapeErrorUnauthorized
+	case errors.Is(err, scraper.ErrSellerNotFound):
+		status, code = http.StatusNotFound, scrapeErrorSellerNotFound
+		message = fmt.Sprintf("Discogs has no public inventory for %s", sellerName)
+	case errors.Is(err, scraper.ErrParse):
+		status, code = http.StatusBadGateway, scrapeErrorBadResponse
+	}
+
+	body := gin.H{"error": message, "code": code}
+	if wait, ok := scraper.RetryAfter(err); ok {
+		body["retry_after"] = int(wait.Round(time.Second).Seconds())
+	}
+	return status, body
+}
+
+// respondScrape writes a scrape response, repeating retry_after as a
+// Retry-After header
+func respondScrape(c *gin.Context, status int, body gin.H) {
+	if retryAfter, ok := body["retry_after"].(int); ok {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	c.JSON(status, body)
+}
+
+// StreamGoScraper handles GET /api/scraper/go/:seller/stream. It runs the
+// scrape and sends a "progress" event after each page, then a "summary" or
+// "error" event. Disconnecting cancels the scrape.
+func (h *Handler) StreamGoScraper(c *gin.Context) {
+	sellerName := c.Param("seller")
+
+	if h.respondScraperUnavailable(c) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"message":       fmt.Sprintf("Successfully scraped %d listings for %s", result.TotalRecords, sellerName),
-		"username":      result.Username,
-		"total_records": result.TotalRecords,
-		"new_records":   result.NewRecords,
+	type outcome struct {
+		result *scraper.ScraperResult
+		err    error
+	}
+
+	ctx := c.Request.Context()
+	fullScan, _ := strconv.ParseBool(c.Query("full_scan"))
+	progress := make(chan scraper.PageProgress)
+	done := make(chan outcome, 1)
+
+	go func() {
+		result, err := h.scraperService.ScrapeUserInventoryContext(ctx, sellerName,
+			scraper.ScrapeOptions{FullScan: fullScan, Progress: progress})
+		done <- outcome{result, err}
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case p := <-progress:
+			c.SSEvent("progress", p)
+			return true
+		case o := <-done:
+			switch {
+			case o.err != nil:
+				_, body := scrapeError(sellerName, o.err)
+				c.SSEvent("error", body)
+			case !o.result.Success:
+				c.SSEvent("error", gin.H{"error": o.result.Error, "code": scrapeErrorFailed})
+			case o.result.InventoryNotFound:
+				_, body := scrapeError(sellerName, scraper.ErrSellerNotFound)
+				c.SSEvent("error", body)
+			default:
+				h.clearCaches()
+				summary := gin.H{
+					"username":           o.result.Username,
+					"total_records":      o.result.TotalRecords,
+					"new_records":        o.result.NewRecords,
+					"new_keepers":        o.result.NewKeepers,
+					"pages_scanned":      o.result.PagesScanned,
+					"failed_pages":       o.result.FailedPages,
+					"timed_out_pages":    o.result.TimedOutPages,
+					"full_scan":          o.result.FullScan,
+					"marked_unavailable": o.result.MarkedUnavailable,
+				}
+				if o.result.Note != "" {
+					summary["note"] = o.result.Note
+				}
+				c.SSEvent("summary", summary)
+			}
+			return false
+		case <-ctx.Done():
+			log.Printf("Client disconnected, cancelling scrape for %s", sellerName)
+			return false
+		}
+	})
+}
+
+// StartRefreshAll handles POST /api/scraper/refresh-all. It queues an
+// incremental scrape of every known seller, stalest first, and responds with
+// the queued job.
+func (h *Handler) StartRefreshAll(c *gin.Context) {
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	job, err := h.scraperService.StartRefreshAll(func(string, *scraper.ScraperResult) {
+		// Each scrape may have added genres, styles and formats
+		h.clearCaches()
 	})
+	if errors.Is(err, services.ErrRefreshInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A refresh of all sellers is already running"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error starting refresh of all sellers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start refresh"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// StartScrapeScheduler scrapes stale sellers in the background on the
+// SCRAPE_SCHEDULE cron schedule until ctx is done. It does nothing when no
+// schedule is configured, and logs and disables the scheduler if the
+// schedule is invalid.
+func (h *Handler) StartScrapeScheduler(ctx context.Context) {
+	spec := h.config.Scraper.Schedule
+	if spec == "" {
+		return
+	}
+	if h.scraperService == nil {
+		log.Printf("Warning: SCRAPE_SCHEDULE is set but the Go scraper service is not available")
+		return
+	}
+
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		log.Printf("Warning: invalid SCRAPE_SCHEDULE, scheduled scraping disabled: %v", err)
+		return
+	}
+
+	log.Printf("Scraping sellers not scraped in %s on schedule %q", h.config.Scraper.StaleAfter, spec)
+	go h.scraperService.RunSchedule(ctx, sched, h.config.Scraper.StaleAfter, func(string, *scraper.ScraperResult) {
+		h.clearCaches()
+	})
+}
+
+// GetRefreshAll handles GET /api/scraper/refresh-all
+func (h *Handler) GetRefreshAll(c *gin.Context) {
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.scraperService.RefreshAllStatus())
 }
 
 // GetScraperStats handles GET /api/scraper/stats
 func (h *Handler) GetScraperStats(c *gin.Context) {
-	if h.scraperService == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Go scraper service is not available",
-		})
+	if h.respondScraperUnavailable(c) {
 		return
 	}
 
@@ -813,12 +1726,127 @@ func (h *Handler) GetScraperStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetScraperConfig handles GET /api/scraper/config
+func (h *Handler) GetScraperConfig(c *gin.Context) {
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.scraperService.GetScraperConfig())
+}
+
+// GetScraperRateLimit handles GET /api/scraper/ratelimit
+func (h *Handler) GetScraperRateLimit(c *gin.Context) {
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.scraperService.GetRateLimitState())
+}
+
+// ClearScraperInventory handles DELETE /api/scraper/inventory/:seller. It
+// drops the record IDs tracked for the seller so the next scrape processes
+// every listing rather than stopping at the previous scrape's.
+func (h *Handler) ClearScraperInventory(c *gin.Context) {
+	seller := c.Param("seller")
+
+	cleared, err := scraper.ClearUserInventory(seller)
+	if err != nil {
+		log.Printf("Error clearing inventory tracking for %s: %v", seller, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear inventory tracking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"seller":  seller,
+		"cleared": cleared,
+	})
+}
+
+// GetScrapeRuns handles GET /api/scraper/runs/
+func (h *Handler) GetScrapeRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	query := h.db.Model(&models.ScrapeRun{})
+	if seller := c.Query("seller"); seller != "" {
+		query = query.Where("seller = ?", seller)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var runs []models.ScrapeRun
+	if err := query.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		log.Printf("Error fetching scrape runs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scrape runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetScraperFailures handles GET /api/scraper/failures, listing the scraped
+// listings that couldn't be saved, most recently failed first
+func (h *Handler) GetScraperFailures(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	query := h.db.Model(&models.FailedListing{})
+	if seller := c.Query("seller"); seller != "" {
+		query = query.Where("seller = ?", seller)
+	}
+
+	var failures []models.FailedListing
+	if err := query.Order("last_failed_at DESC").Limit(limit).Find(&failures).Error; err != nil {
+		log.Printf("Error fetching failed listings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch failed listings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, failures)
+}
+
+// RetryScraperFailure handles POST /api/scraper/failures/:id/retry. The
+// listing is saved again and dropped from the failures if that works.
+func (h *Handler) RetryScraperFailure(c *gin.Context) {
+	if h.respondScraperUnavailable(c) {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid failure ID"})
+		return
+	}
+
+	err = h.scraperService.RetryFailedListing(uint(id))
+	if errors.Is(err, services.ErrFailedListingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed listing not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Retrying failed listing %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Retry failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "saved": true})
+}
+
 // TestScraperConnection handles GET /api/scraper/test
 func (h *Handler) TestScraperConnection(c *gin.Context) {
-	if h.scraperService == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Go scraper service is not available",
-		})
+	if h.respondScraperUnavailable(c) {
 		return
 	}
 
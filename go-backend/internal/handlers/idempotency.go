@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyEntry is what a request made with an Idempotency-Key produced.
+// request identifies what the key was first used for; status and body are
+// set once the request has finished.
+type idempotencyEntry struct {
+	request string
+	done    bool
+	status  int
+	body    gin.H
+	expires time.Time
+}
+
+// idempotencyKeys remembers Idempotency-Key headers for the TTL so a retried
+// request gets the first request's response instead of repeating its work.
+// A nil store or a zero TTL remembers nothing.
+type idempotencyKeys struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*idempotencyEntry
+}
+
+func newIdempotencyKeys(ttl time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// begin claims key for request. When the key is already in use claimed is
+// false and existing is a copy of its entry.
+func (k *idempotencyKeys) begin(key, request string) (existing idempotencyEntry, claimed bool) {
+	if k == nil || k.ttl <= 0 {
+		return idempotencyEntry{}, true
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	for key, entry := range k.entries {
+		if entry.done && !now.Before(entry.expires) {
+			delete(k.entries, key)
+		}
+	}
+
+	if entry, ok := k.entries[key]; ok {
+		return *entry, false
+	}
+	k.entries[key] = &idempotencyEntry{request: request}
+	return idempotencyEntry{}, true
+}
+
+// complete stores the response for a key claimed with begin. Only successes
+// are kept; any other status releases the key, so retrying a request that
+// failed or was turned away runs it again.
+func (k *idempotencyKeys) complete(key string, status int, body gin.H) {
+	if k == nil || k.ttl <= 0 {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entry, ok := k.entries[key]
+	if !ok {
+		return
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		delete(k.entries, key)
+		return
+	}
+	entry.done = true
+	entry.status = status
+	entry.body = body
+	entry.expires = k.now().Add(k.ttl)
+}
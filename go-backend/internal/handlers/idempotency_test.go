@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	keys := newIdempotencyKeys(time.Hour)
+	keys.now = func() time.Time { return now }
+
+	_, claimed := keys.begin("k", "crate full_scan=false")
+	assert.True(t, claimed)
+
+	existing, claimed := keys.begin("k", "crate full_scan=false")
+	assert.False(t, claimed)
+	assert.False(t, existing.done, "still in progress")
+
+	keys.complete("k", http.StatusOK, gin.H{"success": true})
+	existing, claimed = keys.begin("k", "crate full_scan=false")
+	assert.False(t, claimed)
+	assert.True(t, existing.done)
+	assert.Equal(t, http.StatusOK, existing.status)
+	assert.Equal(t, gin.H{"success": true}, existing.body)
+
+	now = now.Add(time.Hour)
+	_, claimed = keys.begin("k", "crate full_scan=false")
+	assert.True(t, claimed, "keys expire after the TTL")
+}
+
+func TestIdempotencyKeysReleasedOnFailure(t *testing.T) {
+	keys := newIdempotencyKeys(time.Hour)
+	_, claimed := keys.begin("k", "crate full_scan=false")
+	assert.True(t, claimed)
+
+	keys.complete("k", http.StatusInternalServerError, gin.H{"error": "boom"})
+	_, claimed = keys.begin("k", "crate full_scan=false")
+	assert.True(t, claimed, "a failed request can be retried")
+
+	keys.complete("k", http.StatusTooManyRequests, gin.H{"error": "slow down"})
+	_, claimed = keys.begin("k", "crate full_scan=false")
+	assert.True(t, claimed, "a refused request can be retried")
+}
+
+func TestIdempotencyKeysDisabled(t *testing.T) {
+	var nilKeys *idempotencyKeys
+	_, claimed := nilKeys.begin("k", "r")
+	assert.True(t, claimed)
+	nilKeys.complete("k", http.StatusOK, nil)
+
+	keys := newIdempotencyKeys(0)
+	keys.begin("k", "r")
+	keys.complete("k", http.StatusOK, nil)
+	_, claimed = keys.begin("k", "r")
+	assert.True(t, claimed)
+}
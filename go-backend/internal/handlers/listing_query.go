@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"discogs-api/internal/condition"
+	"discogs-api/internal/currency"
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	joinRecord = "JOIN discogs_record ON discogs_listing.record_id = discogs_record.id"
+	joinSeller = "JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id"
+)
+
+// listingQuery builds a query over listings. Filters ask for the tables they
+// need with join, and each join is added at most once however many filters
+// use it.
+type listingQuery struct {
+	db    *gorm.DB
+	joins map[string]bool
+
+	// priceCurrency is the currency price bounds were compared in, or nil
+	priceCurrency interface{}
+}
+
+func newListingQuery(db *gorm.DB) *listingQuery {
+	return &listingQuery{db: db.Model(&models.Listing{}), joins: make(map[string]bool)}
+}
+
+// join adds a JOIN clause unless the query already has it
+func (q *listingQuery) join(clause string) *listingQuery {
+	if !q.joins[clause] {
+		q.joins[clause] = true
+		q.db = q.db.Joins(clause)
+	}
+	return q
+}
+
+func (q *listingQuery) where(query string, args ...interface{}) *listingQuery {
+	q.db = q.db.Where(query, args...)
+	return q
+}
+
+// sortBy orders the listings, joining records for the record-based sorts.
+// Unknown sorts fall back to score_desc.
+func (q *listingQuery) sortBy(sort string) *listingQuery {
+	switch sort {
+	case "price_asc":
+		q.db = q.db.Order("discogs_listing.record_price ASC")
+	case "price_desc":
+		q.db = q.db.Order("discogs_listing.record_price DESC")
+	case "year_asc":
+		q.join(joinRecord)
+		q.db = q.db.Order("discogs_record.year ASC")
+	case "year_desc":
+		q.join(joinRecord)
+		q.db = q.db.Order("discogs_record.year DESC")
+	case "condition_desc":
+		q.db = q.db.Order(condition.RankSQL("discogs_listing.media_condition") + " DESC")
+	case "condition_asc":
+		q.db = q.db.Order(condition.RankSQL("discogs_listing.media_condition") + " ASC")
+	case "hotness_desc":
+		q.join(joinRecord)
+		q.db = q.db.Order(wantsHavesRatioExpr + " DESC")
+	case "recent_desc":
+		q.db = q.db.Order("discogs_listing.created_at DESC, discogs_listing.id DESC")
+	default:
+		q.db = q.db.Order("discogs_listing.score DESC")
+	}
+	return q
+}
+
+// DB returns the built query
+func (q *listingQuery) DB() *gorm.DB {
+	return q.db
+}
+
+// searchFilter applies one request filter to a listing query
+type searchFilter func(h *Handler, c *gin.Context, q *listingQuery)
+
+// searchFilters are applied in order by search, count and export
+var searchFilters = []searchFilter{
+	filterIncludeDeleted,
+	filterText,
+	filterGenreStyle,
+	filterYearRange,
+	filterFormat,
+	filterCurrency,
+	filterPriceRange,
+	filterWantsHaves,
+	filterCondition,
+	filterMinCondition,
+	filterSeller,
+	filterShipsFrom,
+	filterAddedRange,
+	filterTag,
+}
+
+// searchListingQuery returns a listing query with every search filter in the
+// request applied
+func (h *Handler) searchListingQuery(c *gin.Context) *listingQuery {
+	q := newListingQuery(h.db)
+	for _, filter := range searchFilters {
+		filter(h, c, q)
+	}
+	return q
+}
+
+// Include listings no longer in their seller's inventory
+func filterIncludeDeleted(h *Handler, c *gin.Context, q *listingQuery) {
+	if includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted")); includeDeleted {
+		q.db = q.db.Unscoped()
+	}
+}
+
+// Text search
+func filterText(h *Handler, c *gin.Context, q *listingQuery) {
+	if text := c.Query("q"); text != "" {
+		artist, arg := containsInsensitive("discogs_record.artist", text)
+		title, _ := containsInsensitive("discogs_record.title", text)
+		label, _ := containsInsensitive("discogs_record.label", text)
+		q.join(joinRecord).where(artist+" OR "+title+" OR "+label, arg, arg, arg)
+	}
+}
+
+// Genre/Style filter
+func filterGenreStyle(h *Handler, c *gin.Context, q *listingQuery) {
+	if genreStyle := c.Query("genre_style"); genreStyle != "" {
+		genres, arg := jsonContainsInsensitive("discogs_record.genres", genreStyle)
+		styles, _ := jsonContainsInsensitive("discogs_record.styles", genreStyle)
+		q.join(joinRecord).where(genres+" OR "+styles, arg, arg)
+	}
+}
+
+// Year range filter
+func filterYearRange(h *Handler, c *gin.Context, q *listingQuery) {
+	minYear, err := strconv.Atoi(c.Query("min_year"))
+	if err != nil {
+		return
+	}
+	maxYear, err := strconv.Atoi(c.Query("max_year"))
+	if err != nil {
+		return
+	}
+	q.join(joinRecord).where("discogs_record.year BETWEEN ? AND ?", minYear, maxYear)
+}
+
+// Format filter. Repeat format to match any of several formats.
+func filterFormat(h *Handler, c *gin.Context, q *listingQuery) {
+	var formats []string
+	for _, format := range c.QueryArray("format") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) > 0 {
+		q.join(joinRecord).where("LOWER(discogs_record.format) IN ?", formats)
+	}
+}
+
+// Seller currency filter
+func filterCurrency(h *Handler, c *gin.Context, q *listingQuery) {
+	if code := requestCurrency(c); code != "" {
+		q.where("discogs_listing.seller_id IN (SELECT id FROM discogs_seller WHERE currency = ?)", code)
+	}
+}
+
+// requestCurrency is the currency parameter as an ISO code; symbols such as
+// "$" are accepted like they are from listings
+func requestCurrency(c *gin.Context) string {
+	raw := c.Query("currency")
+	if code, ok := currency.Normalize(raw); ok {
+		return code
+	}
+	return strings.ToUpper(strings.TrimSpace(raw))
+}
+
+// Price range filter. Bounds are in the requested currency, or converted
+// to the base currency when results span several currencies.
+func filterPriceRange(h *Handler, c *gin.Context, q *listingQuery) {
+	minPrice, err := strconv.ParseFloat(c.Query("min_price"), 64)
+	if err != nil {
+		return
+	}
+	maxPrice, err := strconv.ParseFloat(c.Query("max_price"), 64)
+	if err != nil {
+		return
+	}
+
+	if code := requestCurrency(c); code != "" {
+		q.where("discogs_listing.record_price BETWEEN ? AND ?", minPrice, maxPrice)
+		q.priceCurrency = code
+	} else if expr, args, ok := h.basePriceExpr(); ok {
+		args = append(args, minPrice, maxPrice)
+		q.where(expr+" BETWEEN ? AND ?", args...)
+		q.priceCurrency = strings.ToUpper(h.config.External.BaseCurrency)
+	} else {
+		q.where("discogs_listing.record_price BETWEEN ? AND ?", minPrice, maxPrice)
+	}
+}
+
+// Wants/haves filters
+func filterWantsHaves(h *Handler, c *gin.Context, q *listingQuery) {
+	if minWants, err := strconv.Atoi(c.Query("min_wants")); err == nil {
+		q.where("discogs_listing.record_id IN (SELECT id FROM discogs_record WHERE wants >= ?)", minWants)
+	}
+	if maxHaves, err := strconv.Atoi(c.Query("max_haves")); err == nil {
+		q.where("discogs_listing.record_id IN (SELECT id FROM discogs_record WHERE haves <= ?)", maxHaves)
+	}
+}
+
+// Condition filter
+func filterCondition(h *Handler, c *gin.Context, q *listingQuery) {
+	if mediaCondition := c.Query("condition"); mediaCondition != "" {
+		q.where("LOWER(discogs_listing.media_condition) = ?", strings.ToLower(mediaCondition))
+	}
+}
+
+// Minimum condition filter, e.g. min_condition=VG+ for VG+ or better
+func filterMinCondition(h *Handler, c *gin.Context, q *listingQuery) {
+	if minCondition := c.Query("min_condition"); minCondition != "" {
+		if conditions := condition.AtLeast(minCondition); conditions != nil {
+			q.where("discogs_listing.media_condition IN ?", conditions)
+		}
+	}
+}
+
+// Ships from filter, case-insensitive; repeat for several countries
+func filterShipsFrom(h *Handler, c *gin.Context, q *listingQuery) {
+	var countries []string
+	for _, country := range c.QueryArray("ships_from") {
+		if country = strings.ToLower(strings.TrimSpace(country)); country != "" {
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) > 0 {
+		q.where("LOWER(discogs_listing.ships_from) IN ?", countries)
+	}
+}
+
+// Added date filters on when a listing was first stored. A date such as
+// 2024-05-01 covers that whole day, so added_after=2024-05-01 and
+// added_before=2024-05-07 include both days; RFC 3339 times are exact.
+// Values that don't parse are ignored.
+func filterAddedRange(h *Handler, c *gin.Context, q *listingQuery) {
+	if after, _, ok := parseAddedTime(c.Query("added_after")); ok {
+		q.where("discogs_listing.created_at >= ?", after)
+	}
+	if before, dateOnly, ok := parseAddedTime(c.Query("added_before")); ok {
+		if dateOnly {
+			before = before.AddDate(0, 0, 1)
+		}
+		q.where("discogs_listing.created_at < ?", before)
+	}
+}
+
+// parseAddedTime reads a date or an RFC 3339 time; dateOnly is set for a
+// date, which is taken as midnight UTC
+func parseAddedTime(value string) (t time.Time, dateOnly bool, ok bool) {
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, true
+	}
+	return time.Time{}, false, false
+}
+
+// Record tag filter. Repeat tag to match records with any of several tags,
+// or with all of them when tag_match=all.
+func filterTag(h *Handler, c *gin.Context, q *listingQuery) {
+	var conditions []string
+	var args []interface{}
+	for _, tag := range c.QueryArray("tag") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			match, arg := recordTagCondition(h.db, tag)
+			conditions = append(conditions, match)
+			args = append(args, arg)
+		}
+	}
+	if len(conditions) == 0 {
+		return
+	}
+
+	q.join(joinRecord)
+	if c.Query("tag_match") == "all" {
+		for i, match := range conditions {
+			q.where(match, args[i])
+		}
+		return
+	}
+	q.where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// Seller filter
+func filterSeller(h *Handler, c *gin.Context, q *listingQuery) {
+	if seller := c.Query("seller"); seller != "" {
+		q.join(joinSeller).where(containsInsensitive("discogs_seller.name", seller))
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// searchSQL returns the SQL a search request would run, without running it
+func searchSQL(t *testing.T, rawQuery string) string {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/search/results/?"+rawQuery, nil)
+
+	h := &Handler{db: db, config: &config.Config{}}
+	var listings []models.Listing
+	q := h.searchListingQuery(c).sortBy(c.DefaultQuery("sort", "score_desc"))
+	stmt := q.DB().Find(&listings).Statement
+	return stmt.SQL.String()
+}
+
+func TestSearchListingQueryJoinsOnce(t *testing.T) {
+	sql := searchSQL(t, "q=miles&genre_style=jazz&min_year=1955&max_year=1965&seller=blue&min_wants=10")
+
+	assert.Equal(t, 1, strings.Count(sql, joinRecord), sql)
+	assert.Equal(t, 1, strings.Count(sql, joinSeller), sql)
+	assert.Contains(t, sql, "LOWER(discogs_record.artist) LIKE")
+	assert.Contains(t, sql, "LOWER(CAST(discogs_record.genres AS TEXT)) LIKE")
+	assert.NotContains(t, sql, "ILIKE", "ILIKE is Postgres only")
+	assert.NotContains(t, sql, "::", "casts are written portably")
+	assert.Contains(t, sql, "discogs_record.year BETWEEN")
+}
+
+func TestSearchListingQueryJoinsOnlyWhenNeeded(t *testing.T) {
+	sql := searchSQL(t, "min_wants=10&min_condition=VG%2B")
+
+	assert.NotContains(t, sql, joinRecord)
+	assert.NotContains(t, sql, joinSeller)
+}
+
+func TestSearchListingQuerySortJoinsOnce(t *testing.T) {
+	for _, sort := range []string{"year_asc", "year_desc", "hotness_desc"} {
+		sql := searchSQL(t, "q=miles&genre_style=jazz&sort="+sort)
+
+		assert.Equal(t, 1, strings.Count(sql, joinRecord), sql)
+		assert.Contains(t, sql, "ORDER BY")
+	}
+
+	sql := searchSQL(t, "sort=year_desc")
+	assert.Equal(t, 1, strings.Count(sql, joinRecord), sql)
+	assert.Contains(t, sql, "discogs_record.year DESC")
+}
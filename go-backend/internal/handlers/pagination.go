@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pagination describes one page of a paginated list endpoint
+type pagination struct {
+	page  int
+	limit int
+	total int64
+}
+
+// newPagination reads the page parameter, defaulting to the first page
+func newPagination(c *gin.Context, limit int) *pagination {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	return &pagination{page: page, limit: limit}
+}
+
+func (p *pagination) offset() int {
+	return (p.page - 1) * p.limit
+}
+
+// nextPage is the following page number, or nil on the last page
+func (p *pagination) nextPage() *int {
+	if int64(p.offset()+p.limit) >= p.total {
+		return nil
+	}
+	next := p.page + 1
+	return &next
+}
+
+// prevPage is the preceding page number, or nil on the first page
+func (p *pagination) prevPage() *int {
+	if p.page <= 1 {
+		return nil
+	}
+	prev := p.page - 1
+	return &prev
+}
+
+// setHeaders repeats the body's pagination info as X-Total-Count, X-Page
+// and a Link header with rel="next" and rel="prev" URLs for generic clients.
+// Call it once total is known and before writing the body.
+func (p *pagination) setHeaders(c *gin.Context) {
+	c.Header("X-Total-Count", strconv.FormatInt(p.total, 10))
+	c.Header("X-Page", strconv.Itoa(p.page))
+
+	var links []string
+	if next := p.nextPage(); next != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(c, *next)))
+	}
+	if prev := p.prevPage(); prev != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(c, *prev)))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageURL is the request's path and query with page replaced
+func pageURL(c *gin.Context, page int) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// predictionBatchSize is how many listings are sent to the recommender per
+// request when refreshing predictions
+const predictionBatchSize = 100
+
+// PredictionRefresh is the progress of re-predicting the unevaluated listings
+// after the model was retrained
+type PredictionRefresh struct {
+	Running bool `json:"running"`
+	// Total is how many unevaluated listings the refresh covers
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	// Updated counts the listings given a new prediction; Failed those in
+	// batches the recommender couldn't predict
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	// Error is the last recommender error, if any batch failed
+	Error string `json:"error,omitempty"`
+}
+
+// predictionRefresher runs one prediction refresh at a time in the background
+type predictionRefresher struct {
+	mu     sync.Mutex
+	status PredictionRefresh
+}
+
+// snapshot returns the progress of the current or last refresh
+func (r *predictionRefresher) snapshot() PredictionRefresh {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// update applies fn to the progress under the lock
+func (r *predictionRefresher) update(fn func(status *PredictionRefresh)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.status)
+}
+
+// startPredictionRefresh re-predicts every unevaluated listing in the
+// background. It reports false when a refresh is already running.
+func (h *Handler) startPredictionRefresh() bool {
+	r := h.predictionRefresher
+	r.mu.Lock()
+	if r.status.Running {
+		r.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	r.status = PredictionRefresh{Running: true, StartedAt: &now}
+	r.mu.Unlock()
+
+	go h.refreshPredictions()
+	return true
+}
+
+func (h *Handler) refreshPredictions() {
+	r := h.predictionRefresher
+	// Cached responses may count the old predictions
+	defer h.responseCache.Clear()
+	defer r.update(func(status *PredictionRefresh) {
+		now := time.Now()
+		status.Running = false
+		status.FinishedAt = &now
+	})
+
+	var ids []int
+	if err := h.db.Model(&models.Listing{}).Where("evaluated = ?", false).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		log.Printf("Error loading unevaluated listings to re-predict: %v", err)
+		r.update(func(status *PredictionRefresh) { status.Error = "failed to load unevaluated listings" })
+		return
+	}
+	r.update(func(status *PredictionRefresh) { status.Total = len(ids) })
+
+	for start := 0; start < len(ids); start += predictionBatchSize {
+		end := start + predictionBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		resp, err := h.externalService.GetRecommendations(batch)
+		if err != nil {
+			log.Printf("Error re-predicting listings %d to %d: %v", batch[0], batch[len(batch)-1], err)
+			r.update(func(status *PredictionRefresh) {
+				status.Processed += len(batch)
+				status.Failed += len(batch)
+				status.Error = err.Error()
+			})
+			continue
+		}
+
+		updated := 0
+		for _, pred := range resp.Predictions {
+			if h.storePrediction(pred.ID, pred.Probability) {
+				updated++
+			}
+		}
+		r.update(func(status *PredictionRefresh) {
+			status.Processed += len(batch)
+			status.Updated += updated
+		})
+	}
+
+	log.Printf("Refreshed predictions for %d unevaluated listings", len(ids))
+}
+
+// GetPredictionRefresh handles GET /api/predictions/refresh
+func (h *Handler) GetPredictionRefresh(c *gin.Context) {
+	c.JSON(http.StatusOK, h.predictionRefresher.snapshot())
+}
+
+// StartPredictionRefresh handles POST /api/predictions/refresh
+func (h *Handler) StartPredictionRefresh(c *gin.Context) {
+	if !h.startPredictionRefresh() {
+		c.JSON(http.StatusConflict, gin.H{"error": "A prediction refresh is already running"})
+		return
+	}
+	c.JSON(http.StatusAccepted, h.predictionRefresher.snapshot())
+}
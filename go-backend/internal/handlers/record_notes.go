@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits on a record's annotations
+const (
+	maxRecordTags = 20
+	maxTagLength  = 50
+)
+
+// UpdateRecordNotes handles PATCH /records/:id/notes, setting a record's
+// notes and tags from a JSON body {"notes": "...", "tags": ["..."]}. Omitted
+// fields are left as they are; an empty list clears the tags. Tags are
+// trimmed, lowercased and deduplicated. It returns the updated record.
+func (h *Handler) UpdateRecordNotes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+
+	var req struct {
+		Notes *string   `json:"notes"`
+		Tags  *[]string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Notes == nil && req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notes or tags is required"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Notes != nil {
+		updates["notes"] = strings.TrimSpace(*req.Notes)
+	}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["tags"] = tags
+	}
+
+	var record models.Record
+	if err := h.db.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			return
+		}
+		log.Printf("Error loading record %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load record"})
+		return
+	}
+	if err := h.db.Model(&record).Updates(updates).Error; err != nil {
+		log.Printf("Error saving notes of record %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notes"})
+		return
+	}
+	if req.Tags != nil {
+		// Tag suggestions may have changed
+		h.autocompleteCache.clear()
+	}
+	if err := h.db.First(&record, id).Error; err != nil {
+		log.Printf("Error reloading record %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// normalizeTags trims, lowercases and deduplicates tags, dropping empty
+// ones. Tags are matched by the lowercase form.
+func normalizeTags(tags []string) (models.StringSlice, error) {
+	normalized := models.StringSlice{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxRecordTags {
+		return nil, fmt.Errorf("a record can have at most %d tags", maxRecordTags)
+	}
+	return normalized, nil
+}
+
+// recordTagCondition returns a condition matching records tagged tag, and
+// its argument. Tags are stored lowercase, so tag is matched in any case
+// against whole elements of the JSON array.
+func recordTagCondition(db *gorm.DB, tag string) (string, string) {
+	return jsonArrayContains(db, "discogs_record.tags"), strings.ToLower(strings.TrimSpace(tag))
+}
+
+// GetTagAutocomplete handles GET /autocomplete/tag/
+func (h *Handler) GetTagAutocomplete(c *gin.Context) {
+	h.autocomplete(c, "tag", h.tagSuggestions)
+}
+
+// tagSuggestions returns the record tags containing term, most used first
+func (h *Handler) tagSuggestions(term string) []string {
+	return h.jsonArraySuggestions(term, "tags")
+}
+
+// GetRecords handles GET /records/?tag=to-buy, listing records, newest
+// first. tag keeps the records with that tag; limit defaults to 50, at most
+// 200.
+func (h *Handler) GetRecords(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	query := h.db.Model(&models.Record{})
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where(recordTagCondition(h.db, tag))
+	}
+
+	var records []models.Record
+	if err := query.Order("discogs_record.id DESC").Limit(limit).Find(&records).Error; err != nil {
+		log.Printf("Error fetching records: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// GetTagStats handles GET /api/stats/tags/, counting how many records carry
+// each tag, most used first. It takes the same seller, kept and limit
+// parameters as the genre stats.
+func (h *Handler) GetTagStats(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	tagCounts := make(map[string]int)
+	var batch []models.Record
+	result := h.statsRecords(c).Select("id, tags").
+		FindInBatches(&batch, statsBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, record := range batch {
+				for _, tag := range record.Tags {
+					tagCounts[tag]++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		log.Printf("Error computing tag stats: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute tag stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, topCounts(tagCounts, limit))
+}
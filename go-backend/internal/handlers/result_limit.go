@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// limitResults caps an unpaginated list query at MAX_RESULT_ROWS, fetching
+// one extra row so truncateResults can tell whether anything was cut off
+func (h *Handler) limitResults(query *gorm.DB) *gorm.DB {
+	if max := h.config.Server.MaxResultRows; max > 0 {
+		return query.Limit(max + 1)
+	}
+	return query
+}
+
+// truncateResults returns how many of the n rows a limitResults query found
+// to keep. When rows were cut off it sets X-Result-Truncated and
+// X-Result-Limit so clients know to narrow the query or paginate.
+func (h *Handler) truncateResults(c *gin.Context, n int) (keep int, truncated bool) {
+	max := h.config.Server.MaxResultRows
+	if max <= 0 || n <= max {
+		return n, false
+	}
+
+	log.Printf("Warning: %s returned more than %d rows, truncating", c.FullPath(), max)
+	c.Header("X-Result-Truncated", "true")
+	c.Header("X-Result-Limit", strconv.Itoa(max))
+	return max, true
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Review queue batch sizes; a batch is at most what GetListingsByIDs serves
+const (
+	defaultReviewQueueSize = 20
+	maxReviewQueueSize     = maxListingIDs
+)
+
+// ReviewItem is a listing waiting to be reviewed, with the recommender's
+// prediction for it
+type ReviewItem struct {
+	models.Listing
+	Prediction  bool    `json:"prediction"`
+	Probability float64 `json:"probability"`
+}
+
+// GetReviewQueue handles GET /recommendations/queue/?size=20, returning the
+// next batch of unevaluated listings with their record, seller and prediction
+// merged in, so the review screen needs a single request. The order parameter
+// picks which listings come first; see reviewOrder. Listings the recommender
+// couldn't predict get the default prediction.
+func (h *Handler) GetReviewQueue(c *gin.Context) {
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultReviewQueueSize)))
+	if err != nil || size < 1 {
+		size = defaultReviewQueueSize
+	} else if size > maxReviewQueueSize {
+		size = maxReviewQueueSize
+	}
+
+	q := newListingQuery(h.db).where("discogs_listing.evaluated = ?", false)
+	reviewOrder(q, c.Query("order"))
+
+	var listings []models.Listing
+	if err := q.DB().Preload("Record").Preload("Seller").
+		Limit(size).
+		Find(&listings).Error; err != nil {
+		log.Printf("Error fetching review queue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review queue"})
+		return
+	}
+
+	items := make([]ReviewItem, 0, len(listings))
+	if len(listings) == 0 {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+
+	ids := make([]int, len(listings))
+	for i, listing := range listings {
+		ids[i] = int(listing.ID)
+	}
+	predictions := make(map[int]listingPrediction, len(ids))
+	for _, prediction := range h.predictListings(ids) {
+		predictions[prediction.ID] = prediction
+	}
+
+	for _, listing := range listings {
+		prediction, ok := predictions[int(listing.ID)]
+		if !ok {
+			prediction = defaultPrediction(int(listing.ID))
+		}
+		if !prediction.fallback {
+			// The prediction was just stored on the listing
+			probability := prediction.Probability
+			listing.KeeperProbability = &probability
+			listing.PredictedKeeper = prediction.Prediction
+		}
+		items = append(items, ReviewItem{
+			Listing:     listing,
+			Prediction:  prediction.Prediction,
+			Probability: prediction.Probability,
+		})
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// reviewOrder orders the review queue:
+//   - probability: listings already predicted first, most likely keepers
+//     first, then the rest by score
+//   - score: highest heuristic score first
+//   - demand: highest wants/haves ratio first
+//   - random: a different batch every time
+//
+// Unknown orders fall back to probability.
+func reviewOrder(q *listingQuery, order string) *listingQuery {
+	switch order {
+	case "score":
+		q.db = q.db.Order("discogs_listing.score DESC, discogs_listing.id")
+	case "demand":
+		q.join(joinRecord)
+		q.db = q.db.Order(wantsHavesRatioExpr + " DESC, discogs_listing.id")
+	case "random":
+		q.db = q.db.Order("RANDOM()")
+	default:
+		q.db = q.db.Order("discogs_listing.keeper_probability IS NULL, discogs_listing.keeper_probability DESC, " +
+			"discogs_listing.score DESC, discogs_listing.id")
+	}
+	return q
+}
+
+// SellerUnevaluated is how much review work a seller's inventory holds
+type SellerUnevaluated struct {
+	Seller      string `json:"seller"`
+	Unevaluated int    `json:"unevaluated"`
+	// PredictedKeepers counts the unevaluated listings last predicted to be
+	// keepers
+	PredictedKeepers int `json:"predicted_keepers"`
+}
+
+// GetUnevaluatedBySeller handles GET /api/unevaluated/by-seller/, listing the
+// sellers with unevaluated listings, most first, to pick whose inventory to
+// review next. min_count=N leaves out sellers with fewer than N.
+func (h *Handler) GetUnevaluatedBySeller(c *gin.Context) {
+	minCount := 1
+	if value := c.Query("min_count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_count must be a non-negative integer"})
+			return
+		}
+		if n > minCount {
+			minCount = n
+		}
+	}
+
+	var counts []SellerUnevaluated
+	query := h.db.Model(&models.Listing{}).
+		Select("discogs_seller.name AS seller, COUNT(*) AS unevaluated, "+
+			"SUM(CASE WHEN discogs_listing.predicted_keeper THEN 1 ELSE 0 END) AS predicted_keepers").
+		Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id").
+		Where("discogs_listing.evaluated = ?", false).
+		Group("discogs_seller.name").
+		Having("COUNT(*) >= ?", minCount).
+		Order("unevaluated DESC, discogs_seller.name")
+	if err := h.limitResults(query).Scan(&counts).Error; err != nil {
+		log.Printf("Error counting unevaluated listings by seller: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unevaluated listings"})
+		return
+	}
+
+	keep, _ := h.truncateResults(c, len(counts))
+	c.JSON(http.StatusOK, counts[:keep])
+}
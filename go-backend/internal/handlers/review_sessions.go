@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UndoRecommendations handles POST /recommendations/undo/, putting listings
+// back in the review queue as the scraper left them: unevaluated and kept.
+// It takes the session_id returned by SubmitRecommendations to undo that
+// whole batch, or listing_ids to undo single listings; a session whose
+// listings have all been undone that way is marked undone too. With
+// forget=true the recommender is also asked to drop them from its training
+// samples.
+func (h *Handler) UndoRecommendations(c *gin.Context) {
+	var listingIDs []int
+	var session *models.ReviewSession
+
+	if sessionID := c.PostForm("session_id"); sessionID != "" {
+		id, err := strconv.ParseUint(sessionID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session_id"})
+			return
+		}
+		session = &models.ReviewSession{}
+		if err := h.db.First(session, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Review session not found"})
+				return
+			}
+			log.Printf("Error loading review session %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load review session"})
+			return
+		}
+		if session.UndoneAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Review session was already undone"})
+			return
+		}
+		listingIDs = session.ListingIDs
+	} else {
+		for _, idStr := range c.PostFormArray("listing_ids") {
+			if id, err := strconv.Atoi(idStr); err == nil {
+				listingIDs = append(listingIDs, id)
+			}
+		}
+		if len(listingIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session_id or listing_ids is required"})
+			return
+		}
+	}
+
+	var undone int64
+	undoneSessions := []uint{}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Scraped listings are saved as kept until they are reviewed
+		result := tx.Model(&models.Listing{}).Where("id IN ?", listingIDs).
+			Updates(map[string]interface{}{"evaluated": false, "kept": true})
+		if result.Error != nil {
+			return result.Error
+		}
+		undone = result.RowsAffected
+
+		if session != nil {
+			undoneSessions = append(undoneSessions, session.ID)
+			return tx.Model(session).Update("undone_at", time.Now()).Error
+		}
+		var err error
+		undoneSessions, err = markUndoneSessions(tx, listingIDs)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error undoing recommendations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo recommendations"})
+		return
+	}
+
+	response := gin.H{"success": true, "undone": undone, "undone_sessions": undoneSessions}
+	if forget, _ := strconv.ParseBool(c.PostForm("forget")); forget {
+		if _, err := h.externalService.ForgetSamples(listingIDs); err != nil {
+			log.Printf("Error asking the recommender to forget samples: %v", err)
+			response["forgotten"] = false
+		} else {
+			response["forgotten"] = true
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// markUndoneSessions marks the sessions holding any of listingIDs undone once
+// none of their listings is evaluated any more, returning their IDs
+func markUndoneSessions(tx *gorm.DB, listingIDs []int) ([]uint, error) {
+	var sessions []models.ReviewSession
+	if err := tx.Where("undone_at IS NULL").
+		Where(jsonArrayContainsAny(tx, "listing_ids"), listingIDs).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	marked := []uint{}
+	now := time.Now()
+	for _, session := range sessions {
+		var evaluated int64
+		if err := tx.Model(&models.Listing{}).
+			Where("id IN ? AND evaluated = ?", []int(session.ListingIDs), true).
+			Count(&evaluated).Error; err != nil {
+			return nil, err
+		}
+		if evaluated > 0 {
+			continue
+		}
+		if err := tx.Model(&session).Update("undone_at", now).Error; err != nil {
+			return nil, err
+		}
+		marked = append(marked, session.ID)
+	}
+	return marked, nil
+}
+
+// GetReviewSessions handles GET /recommendations/sessions/, listing past
+// submissions, newest first
+func (h *Handler) GetReviewSessions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	var sessions []models.ReviewSession
+	if err := h.db.Order("created_at DESC, id DESC").Limit(limit).Find(&sessions).Error; err != nil {
+		log.Printf("Error fetching review sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
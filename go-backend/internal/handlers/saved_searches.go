@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// savedSearchParams are the search parameters a saved search may store: the
+// filters and the sort, but not the page
+var savedSearchParams = func() map[string]bool {
+	names := map[string]bool{"sort": true}
+	for _, param := range searchFilterParams {
+		names[param.Name] = true
+	}
+	return names
+}()
+
+// CreateSavedSearch handles POST /searches/, saving a named set of search
+// parameters from a JSON body such as
+// {"name": "jazz LPs", "params": {"genre_style": "Jazz", "format": ["LP"]}}.
+// Parameter values are strings, numbers, booleans or lists of them for
+// repeatable filters. Names are unique.
+func (h *Handler) CreateSavedSearch(c *gin.Context) {
+	var req struct {
+		Name   string                 `json:"name"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	params, err := savedSearchQuery(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	if err := h.db.Model(&models.SavedSearch{}).Where("name = ?", name).Count(&existing).Error; err != nil {
+		log.Printf("Error checking saved search %q: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A saved search with this name already exists"})
+		return
+	}
+
+	search := models.SavedSearch{Name: name, Params: params}
+	if err := h.db.Create(&search).Error; err != nil {
+		log.Printf("Error saving search %q: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, search)
+}
+
+// savedSearchQuery turns the params of a saved search request into query
+// parameters, refusing ones SearchListings doesn't take
+func savedSearchQuery(params map[string]interface{}) (models.QueryParams, error) {
+	query := models.QueryParams{}
+	for name, value := range params {
+		if !savedSearchParams[name] {
+			return nil, fmt.Errorf("unknown search parameter %q", name)
+		}
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case string, float64, bool:
+				query[name] = append(query[name], fmt.Sprint(v))
+			default:
+				return nil, fmt.Errorf("search parameter %q must be a string, number or boolean, or a list of them", name)
+			}
+		}
+	}
+	return query, nil
+}
+
+// GetSavedSearches handles GET /searches/, listing the saved searches by name
+func (h *Handler) GetSavedSearches(c *gin.Context) {
+	var searches []models.SavedSearch
+	if err := h.db.Order("name").Find(&searches).Error; err != nil {
+		log.Printf("Error fetching saved searches: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved searches"})
+		return
+	}
+	c.JSON(http.StatusOK, searches)
+}
+
+// RunSavedSearch handles GET /searches/:id/run, answering like
+// SearchListings with the saved parameters. Parameters in the request, such
+// as page, take precedence over the saved ones.
+func (h *Handler) RunSavedSearch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	var search models.SavedSearch
+	if err := h.db.First(&search, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+			return
+		}
+		log.Printf("Error loading saved search %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load saved search"})
+		return
+	}
+
+	query := url.Values{}
+	for name, values := range search.Params {
+		query[name] = values
+	}
+	for name, values := range c.Request.URL.Query() {
+		query[name] = values
+	}
+	// The search reads its filters from the request, and its page links
+	// repeat them
+	c.Request.URL.RawQuery = query.Encode()
+
+	h.SearchListings(c)
+}
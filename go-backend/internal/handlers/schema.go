@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+	"discogs-api/internal/scraper"
+	"discogs-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// schemaParam documents a single route parameter. In is "path", "query",
+// "header", "body" (a JSON request field), "form" (a form-encoded request
+// field) or "multipart" (a multipart form field; Type "file" for uploads).
+type schemaParam struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// schemaRoute documents one route. Response names a component schema, with
+// a "[]" prefix for arrays; empty means a free-form JSON object.
+type schemaRoute struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Params   []schemaParam
+	Response string
+}
+
+// schemaComponents are the response structs exposed under components/schemas
+var schemaComponents = map[string]reflect.Type{
+	"Record":             reflect.TypeOf(models.Record{}),
+	"Seller":             reflect.TypeOf(models.Seller{}),
+	"Listing":            reflect.TypeOf(models.Listing{}),
+	"RecordOfTheDay":     reflect.TypeOf(models.RecordOfTheDay{}),
+	"PriceHistory":       reflect.TypeOf(models.PriceHistory{}),
+	"ScrapeRun":          reflect.TypeOf(models.ScrapeRun{}),
+	"ReviewItem":         reflect.TypeOf(ReviewItem{}),
+	"ReviewSession":      reflect.TypeOf(models.ReviewSession{}),
+	"SavedSearch":        reflect.TypeOf(models.SavedSearch{}),
+	"FailedListing":      reflect.TypeOf(models.FailedListing{}),
+	"DashboardStats":     reflect.TypeOf(DashboardStats{}),
+	"SellerStats":        reflect.TypeOf(SellerStats{}),
+	"SellerListings":     reflect.TypeOf(SellerListings{}),
+	"SellerUnevaluated":  reflect.TypeOf(SellerUnevaluated{}),
+	"PriceDrop":          reflect.TypeOf(PriceDrop{}),
+	"GenreStats":         reflect.TypeOf(GenreStats{}),
+	"NameCount":          reflect.TypeOf(NameCount{}),
+	"ScraperConfig":      reflect.TypeOf(scraper.ConfigSummary{}),
+	"RateLimitState":     reflect.TypeOf(scraper.RateLimitState{}),
+	"ImportResult":       reflect.TypeOf(services.ImportResult{}),
+	"ScoreWeights":       reflect.TypeOf(config.ScoreWeights{}),
+	"ScoreWeightsUpdate": reflect.TypeOf(ScoreWeightsUpdate{}),
+	"PredictionRefresh":  reflect.TypeOf(PredictionRefresh{}),
+	"RefreshAll":         reflect.TypeOf(services.RefreshAllStatus{}),
+	"PriceStats":         reflect.TypeOf(PriceStats{}),
+}
+
+// searchFilterParams are the filters shared by search, count and export
+var searchFilterParams = []schemaParam{
+	{Name: "q", In: "query", Type: "string", Description: "Match artist, title or label"},
+	{Name: "genre_style", In: "query", Type: "string", Description: "Match a genre or style"},
+	{Name: "min_year", In: "query", Type: "integer", Description: "Used with max_year"},
+	{Name: "max_year", In: "query", Type: "integer", Description: "Used with min_year"},
+	{Name: "format", In: "query", Type: "string", Description: "Record format, case-insensitive; repeat for several"},
+	{Name: "currency", In: "query", Type: "string", Description: "Only sellers pricing in this currency"},
+	{Name: "min_price", In: "query", Type: "number", Description: "Used with max_price; in currency, or BASE_CURRENCY when currency is not set"},
+	{Name: "max_price", In: "query", Type: "number", Description: "Used with min_price"},
+	{Name: "min_wants", In: "query", Type: "integer"},
+	{Name: "max_haves", In: "query", Type: "integer"},
+	{Name: "condition", In: "query", Type: "string", Description: "Exact media condition"},
+	{Name: "min_condition", In: "query", Type: "string", Description: "Media condition at or above this grade, e.g. VG+"},
+	{Name: "seller", In: "query", Type: "string", Description: "Partial seller name"},
+	{Name: "ships_from", In: "query", Type: "string", Description: "Country the listing ships from, case-insensitive; repeat for several"},
+	{Name: "added_after", In: "query", Type: "string", Description: "Listings first stored on or after this date (2006-01-02) or RFC 3339 time"},
+	{Name: "added_before", In: "query", Type: "string", Description: "Listings first stored on or before this date, or before this RFC 3339 time"},
+	{Name: "include_deleted", In: "query", Type: "boolean", Description: "Include listings no longer for sale"},
+	{Name: "tag", In: "query", Type: "string", Description: "Only records with this tag; repeat for several"},
+	{Name: "tag_match", In: "query", Type: "string", Description: "any (default) or all of the tags"},
+}
+
+// schemaRoutes is the hand-maintained list of documented routes. Keep it in
+// step with setupRoutes in main.go; the integration tests check every
+// registered route appears here.
+var schemaRoutes = []schemaRoute{
+	// Dashboard
+	{Method: "GET", Path: "/dashboard/", Tag: "dashboard", Summary: "Dashboard statistics and record of the day",
+		Params: []schemaParam{
+			{Name: "force_refresh", In: "query", Type: "string", Description: "1 to pick a new record of the day"},
+		},
+		Response: "DashboardStats"},
+	{Method: "GET", Path: "/api-dashboard/", Tag: "dashboard", Summary: "Legacy alias for /dashboard/", Response: "DashboardStats"},
+	{Method: "GET", Path: "/api/dashboard/listings/", Tag: "dashboard", Summary: "Listings shown on the dashboard", Response: "[]Listing"},
+	{Method: "POST", Path: "/api/refresh-record-of-the-day/", Tag: "dashboard", Summary: "Pick a new record of the day"},
+
+	// Search
+	{Method: "GET", Path: "/search/results/", Tag: "search", Summary: "Search listings, 20 per page",
+		Params: append(searchFilterParams,
+			schemaParam{Name: "sort", In: "query", Type: "string", Description: "score_desc (default), price_asc, price_desc, year_asc, year_desc, condition_desc, condition_asc, hotness_desc or recent_desc"},
+			schemaParam{Name: "page", In: "query", Type: "integer"},
+		)},
+	{Method: "GET", Path: "/autocomplete/genre/", Tag: "search", Summary: "Genre suggestions",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "GET", Path: "/autocomplete/condition/", Tag: "search", Summary: "Condition suggestions",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "GET", Path: "/autocomplete/styles/", Tag: "search", Summary: "Style suggestions",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "GET", Path: "/autocomplete/format/", Tag: "search", Summary: "Format suggestions",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "GET", Path: "/autocomplete/ships-from/", Tag: "search", Summary: "Suggestions for the countries listings ship from",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "GET", Path: "/autocomplete/tag/", Tag: "search", Summary: "Record tag suggestions, most used first",
+		Params: []schemaParam{{Name: "term", In: "query", Type: "string"}}, Response: "[]string"},
+	{Method: "POST", Path: "/searches/", Tag: "search", Summary: "Save a named set of search filters",
+		Params: []schemaParam{
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "Unique"},
+			{Name: "params", In: "body", Type: "object", Description: "Search filters and sort, e.g. {\"genre_style\": \"Jazz\", \"format\": [\"LP\"]}"},
+		},
+		Response: "SavedSearch"},
+	{Method: "GET", Path: "/searches/", Tag: "search", Summary: "Saved searches by name", Response: "[]SavedSearch"},
+	{Method: "GET", Path: "/searches/:id/run", Tag: "search", Summary: "Run a saved search; answers like /search/results/",
+		Params: []schemaParam{
+			{Name: "id", In: "path", Type: "integer", Required: true},
+			{Name: "page", In: "query", Type: "integer", Description: "Other search parameters override the saved ones too"},
+		}},
+
+	// Listings
+	{Method: "GET", Path: "/listings/", Tag: "listings", Summary: "Listings with their record and seller, in the order of the IDs given",
+		Params: []schemaParam{
+			{Name: "ids", In: "query", Type: "string", Required: true,
+				Description: "Comma-separated listing IDs, at most 100; unknown IDs are left out"},
+		},
+		Response: "[]Listing"},
+	{Method: "GET", Path: "/listings/count/", Tag: "listings", Summary: "Count listings matching the search filters",
+		Params: searchFilterParams},
+	{Method: "GET", Path: "/listings/:id/price-history/", Tag: "listings", Summary: "Prices observed for a listing across scrapes",
+		Params: []schemaParam{{Name: "id", In: "path", Type: "integer", Required: true}}},
+	{Method: "GET", Path: "/api/price-drops/", Tag: "listings", Summary: "Listings whose latest price dropped, biggest drops first",
+		Params: []schemaParam{
+			{Name: "min_drop_percent", In: "query", Type: "number", Description: "Defaults to 10"},
+			{Name: "kept", In: "query", Type: "boolean"},
+			{Name: "predicted_keeper", In: "query", Type: "boolean"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Defaults to 50, at most 200"},
+		},
+		Response: "[]PriceDrop"},
+	{Method: "GET", Path: "/export-listings", Tag: "listings", Summary: "Download listings matching the search filters as CSV",
+		Params: searchFilterParams},
+	{Method: "POST", Path: "/api/import/csv", Tag: "listings", Summary: "Import listings from a CSV in the export format",
+		Params: []schemaParam{
+			{Name: "file", In: "multipart", Type: "file", Required: true,
+				Description: "Needs Record Artist, Record Title, Seller, Record Price and Media Condition columns"},
+		},
+		Response: "ImportResult"},
+
+	// Sellers
+	{Method: "POST", Path: "/by-seller/search/", Tag: "sellers", Summary: "Listings for sellers matching a name",
+		Params: []schemaParam{
+			{Name: "seller", In: "body", Type: "string", Required: true, Description: "Case-insensitive partial seller name"},
+			{Name: "exact", In: "body", Type: "boolean", Description: "Match the seller name exactly"},
+		},
+		Response: "SellerListings"},
+	{Method: "POST", Path: "/data/:seller", Tag: "sellers", Summary: "Trigger the Python scraper for a seller",
+		Params: []schemaParam{{Name: "seller", In: "path", Type: "string", Required: true}}},
+	{Method: "GET", Path: "/records/", Tag: "records", Summary: "Records, newest first",
+		Params: []schemaParam{
+			{Name: "tag", In: "query", Type: "string", Description: "Only records with this tag"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Defaults to 50, at most 200"},
+		},
+		Response: "[]Record"},
+	{Method: "PATCH", Path: "/records/:id/notes", Tag: "records", Summary: "Set a record's notes and tags; returns the record",
+		Params: []schemaParam{
+			{Name: "id", In: "path", Type: "integer", Required: true},
+			{Name: "notes", In: "body", Type: "string", Description: "Omit to keep the current notes"},
+			{Name: "tags", In: "body", Type: "array", Description: "Replaces the tags; stored lowercase, at most 20 of 50 characters each"},
+		},
+		Response: "Record"},
+	{Method: "GET", Path: "/records/seller/:seller/", Tag: "sellers", Summary: "Records a seller has listed",
+		Params: []schemaParam{{Name: "seller", In: "path", Type: "string", Required: true}}, Response: "[]Record"},
+	{Method: "GET", Path: "/api/sellers/stats/", Tag: "sellers", Summary: "Listing counts and last scrape time per seller",
+		Params:   []schemaParam{{Name: "stale_days", In: "query", Type: "integer", Description: "Only sellers not scraped in this many days"}},
+		Response: "[]SellerStats"},
+	{Method: "GET", Path: "/api/unevaluated/by-seller/", Tag: "sellers", Summary: "Unevaluated and predicted keeper listing counts per seller, most unevaluated first",
+		Params:   []schemaParam{{Name: "min_count", In: "query", Type: "integer", Description: "Only sellers with at least this many unevaluated listings"}},
+		Response: "[]SellerUnevaluated"},
+	{Method: "GET", Path: "/sellers/:seller", Tag: "sellers", Summary: "A seller's currency, rating, last scrape time and listing count; 404 if unknown",
+		Params: []schemaParam{{Name: "seller", In: "path", Type: "string", Required: true}}, Response: "SellerStats"},
+
+	// Recommendations
+	{Method: "GET", Path: "/recommendation-predictions/", Tag: "recommendations", Summary: "Model predictions for listings",
+		Params: []schemaParam{
+			{Name: "listing_ids", In: "query", Type: "integer", Description: "Repeat for each listing", Required: true},
+			{Name: "exclude_evaluated", In: "query", Type: "boolean",
+				Description: "Leave out listings already evaluated; defaults to PREDICTIONS_EXCLUDE_EVALUATED"},
+		}},
+	{Method: "GET", Path: "/recommendations/queue/", Tag: "recommendations", Summary: "The next unevaluated listings to review, with their predictions merged in",
+		Params: []schemaParam{
+			{Name: "size", In: "query", Type: "integer", Description: "Defaults to 20; larger sizes are cut to 100"},
+			{Name: "order", In: "query", Type: "string", Description: "probability (default), score, demand or random"},
+		},
+		Response: "[]ReviewItem"},
+	{Method: "POST", Path: "/submit-scoring-selections/", Tag: "recommendations", Summary: "Submit evaluated listings and train the model; returns the session_id to undo them with",
+		Params: []schemaParam{
+			{Name: "listing_ids", In: "form", Type: "integer", Description: "Repeat for each evaluated listing"},
+			{Name: "keeper_ids", In: "form", Type: "integer", Description: "Repeat for each kept listing"},
+			{Name: "refresh_predictions", In: "form", Type: "boolean",
+				Description: "Re-predict unevaluated listings in the background once the model is trained"},
+		}},
+	{Method: "POST", Path: "/recommendations/undo/", Tag: "recommendations", Summary: "Put a submitted batch, or single listings, back in the review queue",
+		Params: []schemaParam{
+			{Name: "session_id", In: "form", Type: "integer", Description: "The session_id returned on submission, to undo the whole batch"},
+			{Name: "listing_ids", In: "form", Type: "integer", Description: "Repeat for each listing to undo, without a session_id"},
+			{Name: "forget", In: "form", Type: "boolean", Description: "Also ask the recommender to drop them from its training samples"},
+		}},
+	{Method: "GET", Path: "/recommendations/sessions/", Tag: "recommendations", Summary: "Past submissions, newest first",
+		Params:   []schemaParam{{Name: "limit", In: "query", Type: "integer", Description: "Defaults to 50, at most 200"}},
+		Response: "[]ReviewSession"},
+	{Method: "GET", Path: "/model-performance-stats/", Tag: "recommendations", Summary: "Model accuracy history and keeper threshold"},
+	{Method: "GET", Path: "/api/predictions/refresh", Tag: "recommendations", Summary: "Progress of the current or last prediction refresh",
+		Response: "PredictionRefresh"},
+	{Method: "POST", Path: "/api/predictions/refresh", Tag: "recommendations", Summary: "Re-predict every unevaluated listing in the background",
+		Response: "PredictionRefresh"},
+	{Method: "GET", Path: "/api/score/weights", Tag: "recommendations", Summary: "Weights used to score listings",
+		Response: "ScoreWeights"},
+	{Method: "PUT", Path: "/api/score/weights", Tag: "recommendations", Summary: "Change the weights used to score listings",
+		Params: []schemaParam{
+			{Name: "wants_haves", In: "body", Type: "number", Description: "Weight of the wants/haves ratio; omitted weights are unchanged"},
+			{Name: "deal", In: "body", Type: "number", Description: "Weight of the price against the suggested price"},
+			{Name: "condition", In: "body", Type: "number", Description: "Weight of the media condition"},
+			{Name: "rescore", In: "query", Type: "boolean", Description: "Rescore every listing with the new weights"},
+		},
+		Response: "ScoreWeightsUpdate"},
+	{Method: "POST", Path: "/add-to-wantlist/", Tag: "recommendations", Summary: "Add a record to the Discogs wantlist",
+		Params: []schemaParam{{Name: "record_id", In: "form", Type: "string", Required: true}}},
+	{Method: "POST", Path: "/vote-record-of-the-day/bulk/", Tag: "recommendations", Summary: "Vote on several past records of the day at once",
+		Params: []schemaParam{
+			{Name: "votes", In: "body", Type: "array", Required: true, Description: "Up to 100 of {id, desirability, novelty}; invalid votes are reported per vote and skipped"},
+		}},
+	{Method: "POST", Path: "/vote-record-of-the-day/:id/", Tag: "recommendations", Summary: "Vote on the record of the day",
+		Params: []schemaParam{
+			{Name: "id", In: "path", Type: "integer", Required: true},
+			{Name: "desirability", In: "form", Type: "number", Required: true, Description: "Between VOTE_MIN and VOTE_MAX, 1-5 by default"},
+			{Name: "novelty", In: "form", Type: "number", Required: true, Description: "Between VOTE_MIN and VOTE_MAX, 1-5 by default"},
+		}},
+	{Method: "POST", Path: "/record-of-the-day/:id/recompute", Tag: "recommendations", Summary: "Recalculate the average votes of a record of the day",
+		Params: []schemaParam{{Name: "id", In: "path", Type: "integer", Required: true}}},
+
+	// Scraper
+	{Method: "POST", Path: "/api/scraper/go/:seller", Tag: "scraper", Summary: "Scrape a seller's inventory with the Go scraper",
+		Params: []schemaParam{
+			{Name: "seller", In: "path", Type: "string", Required: true},
+			{Name: "full_scan", In: "query", Type: "boolean", Description: "Scan every page and mark missing listings unavailable"},
+			{Name: "Idempotency-Key", In: "header", Type: "string", Description: "Repeating a request with the same key returns the first response instead of scraping again"},
+		}},
+	{Method: "GET", Path: "/api/scraper/go/:seller/stream", Tag: "scraper", Summary: "Scrape a seller, streaming progress as server-sent events",
+		Params: []schemaParam{
+			{Name: "seller", In: "path", Type: "string", Required: true},
+			{Name: "full_scan", In: "query", Type: "boolean"},
+		}},
+	{Method: "POST", Path: "/api/scraper/refresh-all", Tag: "scraper", Summary: "Queue incremental scrapes of every seller, least recently scraped first",
+		Response: "RefreshAll"},
+	{Method: "GET", Path: "/api/scraper/refresh-all", Tag: "scraper", Summary: "Progress of the current or last refresh of all sellers",
+		Response: "RefreshAll"},
+	{Method: "GET", Path: "/api/scraper/stats", Tag: "scraper", Summary: "Scraper and rate limiter statistics"},
+	{Method: "GET", Path: "/api/scraper/config", Tag: "scraper", Summary: "Effective scraper configuration, credentials redacted",
+		Response: "ScraperConfig"},
+	{Method: "GET", Path: "/api/scraper/ratelimit", Tag: "scraper", Summary: "Current rate limit window and the quota Discogs last reported",
+		Response: "RateLimitState"},
+	{Method: "GET", Path: "/api/scraper/test", Tag: "scraper", Summary: "Check the Discogs API connection"},
+	{Method: "GET", Path: "/api/scraper/runs/", Tag: "scraper", Summary: "Recent scrape runs, newest first",
+		Params: []schemaParam{
+			{Name: "seller", In: "query", Type: "string"},
+			{Name: "status", In: "query", Type: "string", Description: "running, success or failed"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Defaults to 50, at most 200"},
+		},
+		Response: "[]ScrapeRun"},
+	{Method: "GET", Path: "/api/scraper/failures", Tag: "scraper", Summary: "Scraped listings that couldn't be saved, most recently failed first",
+		Params: []schemaParam{
+			{Name: "seller", In: "query", Type: "string"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Defaults to 50, at most 200"},
+		},
+		Response: "[]FailedListing"},
+	{Method: "POST", Path: "/api/scraper/failures/:id/retry", Tag: "scraper", Summary: "Save a failed listing again, removing it from the failures if that works",
+		Params: []schemaParam{{Name: "id", In: "path", Type: "integer", Required: true}}},
+	{Method: "DELETE", Path: "/api/scraper/inventory/:seller", Tag: "scraper", Summary: "Forget the record IDs tracked for a seller so the next scrape processes everything",
+		Params: []schemaParam{{Name: "seller", In: "path", Type: "string", Required: true}}},
+
+	// Catalog stats
+	{Method: "GET", Path: "/api/stats/genres/", Tag: "stats", Summary: "Records per genre and per style, most common first",
+		Params: []schemaParam{
+			{Name: "seller", In: "query", Type: "string", Description: "Only records this seller lists"},
+			{Name: "kept", In: "query", Type: "boolean", Description: "Only records with a kept (or not kept) listing"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Top N of each; defaults to 20"},
+		},
+		Response: "GenreStats"},
+	{Method: "GET", Path: "/api/stats/decades/", Tag: "stats", Summary: "Records per decade, oldest first, then unknown years",
+		Params: []schemaParam{
+			{Name: "seller", In: "query", Type: "string", Description: "Only records this seller lists"},
+			{Name: "kept", In: "query", Type: "boolean", Description: "Only records with a kept (or not kept) listing"},
+		},
+		Response: "[]NameCount"},
+	{Method: "GET", Path: "/api/stats/tags/", Tag: "stats", Summary: "Records per tag, most used first",
+		Params: []schemaParam{
+			{Name: "seller", In: "query", Type: "string", Description: "Only records this seller lists"},
+			{Name: "kept", In: "query", Type: "boolean", Description: "Only records with a kept (or not kept) listing"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Top N; defaults to 20"},
+		},
+		Response: "[]NameCount"},
+	{Method: "GET", Path: "/api/stats/prices/", Tag: "stats", Summary: "Price count, range, average and median of the listings matching a search",
+		Params: append(searchFilterParams,
+			schemaParam{Name: "convert_to", In: "query", Type: "string", Description: "Convert prices to this currency; 503 without exchange rates"},
+		),
+		Response: "PriceStats"},
+	{Method: "GET", Path: "/api/conditions/", Tag: "stats", Summary: "Media conditions of current listings with their counts, best condition first",
+		Params:   []schemaParam{{Name: "seller", In: "query", Type: "string", Description: "Only this seller's listings"}},
+		Response: "[]NameCount"},
+
+	{Method: "GET", Path: "/api/schema/", Tag: "meta", Summary: "This OpenAPI document"},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  gin.H
+)
+
+// GetAPISchema handles GET /api/schema/
+func (h *Handler) GetAPISchema(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = buildOpenAPI()
+	})
+	c.JSON(http.StatusOK, openAPIDoc)
+}
+
+// buildOpenAPI assembles the OpenAPI 3 document from schemaRoutes and schemaComponents
+func buildOpenAPI() gin.H {
+	paths := gin.H{}
+	for _, route := range schemaRoutes {
+		path := openAPIPath(route.Path)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = openAPIOperation(route)
+	}
+
+	schemas := gin.H{}
+	for name, t := range schemaComponents {
+		schemas[name] = typeSchema(t, false)
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "Discogs API",
+			"version": "1.0",
+		},
+		"paths":      paths,
+		"components": gin.H{"schemas": schemas},
+	}
+}
+
+// openAPIPath converts gin's :param segments to OpenAPI's {param}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func openAPIOperation(route schemaRoute) gin.H {
+	op := gin.H{
+		"tags":    []string{route.Tag},
+		"summary": route.Summary,
+	}
+
+	var params []gin.H
+	bodies := map[string]gin.H{}
+	for _, p := range route.Params {
+		schema := gin.H{"type": p.Type}
+		if p.Type == "file" {
+			schema = gin.H{"type": "string", "format": "binary"}
+		}
+		switch p.In {
+		case "path", "query", "header":
+			param := gin.H{"name": p.Name, "in": p.In, "schema": schema, "required": p.Required}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		case "body", "form", "multipart":
+			contentType := "application/json"
+			switch p.In {
+			case "form":
+				contentType = "application/x-www-form-urlencoded"
+			case "multipart":
+				contentType = "multipart/form-data"
+			}
+			body, ok := bodies[contentType]
+			if !ok {
+				body = gin.H{"type": "object", "properties": gin.H{}}
+				bodies[contentType] = body
+			}
+			if p.Description != "" {
+				schema["description"] = p.Description
+			}
+			body["properties"].(gin.H)[p.Name] = schema
+			if p.Required {
+				required, _ := body["required"].([]string)
+				body["required"] = append(required, p.Name)
+			}
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if len(bodies) > 0 {
+		content := gin.H{}
+		for contentType, schema := range bodies {
+			content[contentType] = gin.H{"schema": schema}
+		}
+		op["requestBody"] = gin.H{"content": content}
+	}
+
+	op["responses"] = gin.H{
+		"200": gin.H{
+			"description": "OK",
+			"content": gin.H{
+				"application/json": gin.H{"schema": responseSchema(route.Response)},
+			},
+		},
+	}
+	return op
+}
+
+func responseSchema(response string) gin.H {
+	if strings.HasPrefix(response, "[]") {
+		return gin.H{"type": "array", "items": responseSchema(strings.TrimPrefix(response, "[]"))}
+	}
+	switch response {
+	case "":
+		return gin.H{"type": "object"}
+	case "string":
+		return gin.H{"type": "string"}
+	}
+	return gin.H{"$ref": "#/components/schemas/" + response}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// typeSchema describes a Go type as an OpenAPI schema using its json tags.
+// Component structs nested inside other types are referenced, not inlined.
+func typeSchema(t reflect.Type, allowRef bool) gin.H {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema gin.H
+	switch {
+	case t == timeType:
+		schema = gin.H{"type": "string", "format": "date-time"}
+	case t == deletedAtType:
+		schema = gin.H{"type": "string", "format": "date-time"}
+		nullable = true
+	case allowRef && componentName(t) != "":
+		ref := gin.H{"$ref": "#/components/schemas/" + componentName(t)}
+		if nullable {
+			return gin.H{"allOf": []gin.H{ref}, "nullable": true}
+		}
+		return ref
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			schema = gin.H{"type": "boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema = gin.H{"type": "integer"}
+		case reflect.Float32, reflect.Float64:
+			schema = gin.H{"type": "number"}
+		case reflect.String:
+			schema = gin.H{"type": "string"}
+		case reflect.Slice, reflect.Array:
+			schema = gin.H{"type": "array", "items": typeSchema(t.Elem(), true)}
+		case reflect.Map:
+			schema = gin.H{"type": "object", "additionalProperties": true}
+		case reflect.Struct:
+			schema = structSchema(t)
+		default:
+			schema = gin.H{}
+		}
+	}
+
+	if nullable {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+func structSchema(t reflect.Type) gin.H {
+	properties := gin.H{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			// encoding/json inlines the fields of embedded structs
+			for name, property := range structSchema(field.Type)["properties"].(gin.H) {
+				properties[name] = property
+			}
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+		properties[name] = typeSchema(field.Type, true)
+	}
+	return gin.H{"type": "object", "properties": properties}
+}
+
+func componentName(t reflect.Type) string {
+	for name, component := range schemaComponents {
+		if component == t {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScoreWeightsUpdate is the response to changing the score weights
+type ScoreWeightsUpdate struct {
+	Weights config.ScoreWeights `json:"weights"`
+	// Rescored is how many listings' scores changed, when a rescore was asked for
+	Rescored *int `json:"rescored,omitempty"`
+}
+
+// GetScoreWeights handles GET /api/score/weights
+func (h *Handler) GetScoreWeights(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scorer.Weights())
+}
+
+// UpdateScoreWeights handles PUT /api/score/weights. Weights left out of the
+// body keep their current value. With rescore=true every listing is scored
+// again with the new weights; otherwise they apply to listings saved from now on.
+func (h *Handler) UpdateScoreWeights(c *gin.Context) {
+	rescore := false
+	if value := c.Query("rescore"); value != "" {
+		var err error
+		if rescore, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rescore must be true or false"})
+			return
+		}
+	}
+
+	weights := h.scorer.Weights()
+	if err := c.ShouldBindJSON(&weights); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := h.scorer.SetWeights(weights); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := ScoreWeightsUpdate{Weights: weights}
+	if rescore {
+		rescored, err := services.RescoreListings(h.db, h.scorer)
+		if err != nil {
+			log.Printf("Error rescoring listings: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Weights updated but rescoring failed"})
+			return
+		}
+		response.Rescored = &rescored
+	}
+
+	c.JSON(http.StatusOK, response)
+}
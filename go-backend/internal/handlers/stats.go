@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"discogs-api/internal/condition"
+	"discogs-api/internal/currency"
+	"discogs-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// statsBatchSize is how many records are scanned at a time when aggregating
+const statsBatchSize = 1000
+
+// NameCount is one bucket of a catalog distribution
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// GenreStats is the genre and style distribution of the catalog
+type GenreStats struct {
+	Genres []NameCount `json:"genres"`
+	Styles []NameCount `json:"styles"`
+}
+
+// GetGenreStats handles GET /api/stats/genres/
+func (h *Handler) GetGenreStats(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	// Genres and styles are JSON arrays, so count them in Go rather than
+	// with database-specific unnesting
+	genreCounts := make(map[string]int)
+	styleCounts := make(map[string]int)
+
+	var batch []models.Record
+	result := h.statsRecords(c).Select("id, genres, styles").
+		FindInBatches(&batch, statsBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, record := range batch {
+				for _, genre := range record.Genres {
+					genreCounts[genre]++
+				}
+				for _, style := range record.Styles {
+					styleCounts[style]++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		log.Printf("Error computing genre stats: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute genre stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GenreStats{
+		Genres: topCounts(genreCounts, limit),
+		Styles: topCounts(styleCounts, limit),
+	})
+}
+
+// GetDecadeStats handles GET /api/stats/decades/
+func (h *Handler) GetDecadeStats(c *gin.Context) {
+	var rows []struct {
+		Decade *int
+		Count  int
+	}
+	err := h.statsRecords(c).
+		Select("CASE WHEN year > 0 THEN (year / 10) * 10 END AS decade, COUNT(*) AS count").
+		Group("decade").
+		Order("decade").
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("Error computing decade stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute decade stats"})
+		return
+	}
+
+	decades := make([]NameCount, 0, len(rows))
+	unknown := 0
+	for _, row := range rows {
+		if row.Decade == nil {
+			unknown += row.Count
+			continue
+		}
+		decades = append(decades, NameCount{Name: fmt.Sprintf("%ds", *row.Decade), Count: row.Count})
+	}
+	if unknown > 0 {
+		decades = append(decades, NameCount{Name: "unknown", Count: unknown})
+	}
+
+	c.JSON(http.StatusOK, decades)
+}
+
+// GetConditions handles GET /api/conditions/, listing the media conditions
+// of current listings, best first, with how many listings have each.
+// Abbreviations are counted under the canonical name; conditions that aren't
+// Discogs grades come last. ?seller= counts only that seller's listings.
+func (h *Handler) GetConditions(c *gin.Context) {
+	var rows []struct {
+		MediaCondition string
+		Count          int
+	}
+	query := h.db.Model(&models.Listing{})
+	if seller := c.Query("seller"); seller != "" {
+		query = query.Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id").
+			Where("discogs_seller.name = ?", seller)
+	}
+	err := query.Select("discogs_listing.media_condition, COUNT(*) AS count").
+		Group("discogs_listing.media_condition").
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("Error counting conditions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count conditions"})
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[condition.Normalize(row.MediaCondition)] += row.Count
+	}
+
+	conditions := make([]NameCount, 0, len(counts))
+	for name, count := range counts {
+		conditions = append(conditions, NameCount{Name: name, Count: count})
+	}
+	sort.Slice(conditions, func(i, j int) bool {
+		ri, rj := condition.Rank(conditions[i].Name), condition.Rank(conditions[j].Name)
+		if ri != rj {
+			return ri > rj
+		}
+		return conditions[i].Name < conditions[j].Name
+	})
+
+	c.JSON(http.StatusOK, conditions)
+}
+
+// PriceStats summarizes the prices of the listings matching a search. The
+// figures are nil when no listing has a price in Currency.
+type PriceStats struct {
+	Count   int64    `json:"count"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	Average *float64 `json:"average"`
+	Median  *float64 `json:"median"`
+	// Currency is the prices' currency, or empty when the listings are
+	// priced in several currencies that couldn't be converted
+	Currency string `json:"currency"`
+}
+
+// GetPriceStats handles GET /api/stats/prices/, summarizing the prices of
+// the listings matching the search filters. ?convert_to= converts prices to
+// that currency. Otherwise prices are in the currency filter's currency or,
+// when there is none, the base currency; without exchange rates they're
+// summarized as they are.
+func (h *Handler) GetPriceStats(c *gin.Context) {
+	expr, args := "discogs_listing.record_price", []interface{}(nil)
+	stats := PriceStats{Currency: requestCurrency(c)}
+
+	if target := strings.ToUpper(strings.TrimSpace(c.Query("convert_to"))); target != "" {
+		code, ok := currency.Normalize(target)
+		if !ok {
+			code = target
+		}
+		converted, convertedArgs, ok := h.convertedPriceExpr(code)
+		if !ok {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Exchange rates are unavailable"})
+			return
+		}
+		expr, args, stats.Currency = converted, convertedArgs, code
+	} else if stats.Currency == "" {
+		if converted, convertedArgs, ok := h.basePriceExpr(); ok {
+			expr, args = converted, convertedArgs
+			stats.Currency = strings.ToUpper(h.config.External.BaseCurrency)
+			if stats.Currency == "" {
+				stats.Currency = "USD"
+			}
+		}
+	}
+
+	query := h.searchListingQuery(c).DB()
+	var row struct {
+		Count   int64
+		Min     *float64
+		Max     *float64
+		Average *float64
+		Median  *float64
+	}
+	selects := "COUNT(" + expr + ") AS count, MIN(" + expr + ") AS min, MAX(" + expr + ") AS max, AVG(" + expr + ") AS average"
+	selectArgs := repeatArgs(args, 4)
+	median := medianSQL(h.db, expr)
+	if median != "" {
+		selects += ", " + median + " AS median"
+		selectArgs = append(selectArgs, args...)
+	}
+	if err := query.Session(&gorm.Session{}).Select(selects, selectArgs...).Scan(&row).Error; err != nil {
+		log.Printf("Error computing price stats: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute price stats"})
+		return
+	}
+
+	if median == "" && row.Count > 0 {
+		// Average the one or two middle prices
+		var middle []float64
+		err := query.Session(&gorm.Session{}).
+			Where(expr+" IS NOT NULL", args...).
+			Clauses(clause.OrderBy{Expression: clause.Expr{SQL: expr, Vars: args}}).
+			Offset(int((row.Count-1)/2)).Limit(int(2-row.Count%2)).
+			Select(expr+" AS price", args...).
+			Scan(&middle).Error
+		if err != nil {
+			log.Printf("Error computing median price: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute price stats"})
+			return
+		}
+		if len(middle) > 0 {
+			var sum float64
+			for _, price := range middle {
+				sum += price
+			}
+			m := sum / float64(len(middle))
+			row.Median = &m
+		}
+	}
+
+	stats.Count = row.Count
+	stats.Min, stats.Max = roundPrice(row.Min), roundPrice(row.Max)
+	stats.Average, stats.Median = roundPrice(row.Average), roundPrice(row.Median)
+	c.JSON(http.StatusOK, stats)
+}
+
+// repeatArgs returns args n times over, for an expression used n times
+func repeatArgs(args []interface{}, n int) []interface{} {
+	repeated := make([]interface{}, 0, len(args)*n)
+	for i := 0; i < n; i++ {
+		repeated = append(repeated, args...)
+	}
+	return repeated
+}
+
+// roundPrice rounds a price to cents
+func roundPrice(price *float64) *float64 {
+	if price == nil {
+		return nil
+	}
+	rounded := math.Round(*price*100) / 100
+	return &rounded
+}
+
+// statsRecords scopes the records counted by the stats endpoints. With
+// ?seller= or ?kept= only records with a matching current listing count.
+func (h *Handler) statsRecords(c *gin.Context) *gorm.DB {
+	query := h.db.Model(&models.Record{})
+
+	seller := c.Query("seller")
+	kept, keptErr := strconv.ParseBool(c.Query("kept"))
+	if seller == "" && keptErr != nil {
+		return query
+	}
+
+	listings := h.db.Model(&models.Listing{}).Select("discogs_listing.record_id")
+	if seller != "" {
+		listings = listings.Joins("JOIN discogs_seller ON discogs_listing.seller_id = discogs_seller.id").
+			Where("discogs_seller.name = ?", seller)
+	}
+	if keptErr == nil {
+		listings = listings.Where("discogs_listing.kept = ?", kept)
+	}
+	return query.Where("discogs_record.id IN (?)", listings)
+}
+
+// topCounts sorts counts descending, then by name, and keeps the first limit
+func topCounts(counts map[string]int, limit int) []NameCount {
+	entries := make([]NameCount, 0, len(counts))
+	for name, count := range counts {
+		entries = append(entries, NameCount{Name: name, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
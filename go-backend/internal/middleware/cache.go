@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseCacheMaxEntries bounds the cache; when full, expired entries are
+// dropped and, failing that, everything is
+const responseCacheMaxEntries = 1000
+
+// encodingHeaders aren't cached, nor is Accept-Encoding in Vary: cached
+// bodies are unencoded, and each hit is encoded afresh for the client asking
+var encodingHeaders = []string{"Content-Encoding", "Content-Length"}
+
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// ResponseCache keeps the responses of expensive GET endpoints in memory for
+// a TTL, keyed by path and query, so repeated requests don't recompute them.
+// A nil cache or a zero TTL caches nothing.
+type ResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cachedResponse
+}
+
+// NewResponseCache returns a cache keeping responses for ttl
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedResponse),
+	}
+}
+
+// Handler returns a gin.HandlerFunc serving GET requests from the cache.
+// Misses run the handler and keep its response if it was a 200 that didn't
+// set Cache-Control: no-store. A request sending Cache-Control: no-cache (or
+// Pragma: no-cache) skips the cached copy and refreshes it. Responses carry
+// X-Cache: HIT or MISS.
+func (rc *ResponseCache) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rc == nil || rc.ttl <= 0 || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()
+		if !noCache(c.Request) {
+			if entry, ok := rc.get(key); ok {
+				serveCached(c, entry)
+				return
+			}
+		}
+
+		writer := &cacheWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Header("X-Cache", "MISS")
+		c.Next()
+
+		if writer.Status() != http.StatusOK || writer.skip ||
+			strings.Contains(writer.Header().Get("Cache-Control"), "no-store") {
+			return
+		}
+		// The body is kept as the handler wrote it; encoding headers added
+		// by outer middleware such as Gzip describe this response only
+		header := writer.Header().Clone()
+		for _, name := range encodingHeaders {
+			header.Del(name)
+		}
+		removeVary(header, "Accept-Encoding")
+		rc.set(key, cachedResponse{
+			header: header,
+			body:   writer.body.Bytes(),
+		})
+	}
+}
+
+// ClearOnWrite returns a gin.HandlerFunc that empties the cache after every
+// successful request that isn't a GET, HEAD or OPTIONS, since any of them
+// may have changed what the cached endpoints report
+func (rc *ResponseCache) ClearOnWrite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() < http.StatusBadRequest {
+			rc.Clear()
+		}
+	}
+}
+
+// Clear drops every entry, e.g. after a scrape has added records
+func (rc *ResponseCache) Clear() {
+	if rc == nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries = make(map[string]cachedResponse)
+}
+
+func (rc *ResponseCache) get(key string) (cachedResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if !rc.now().Before(entry.expires) {
+		delete(rc.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+func (rc *ResponseCache) set(key string, entry cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := rc.now()
+	if len(rc.entries) >= responseCacheMaxEntries {
+		for key, entry := range rc.entries {
+			if !now.Before(entry.expires) {
+				delete(rc.entries, key)
+			}
+		}
+		if len(rc.entries) >= responseCacheMaxEntries {
+			rc.entries = make(map[string]cachedResponse)
+		}
+	}
+	entry.expires = now.Add(rc.ttl)
+	rc.entries[key] = entry
+}
+
+// serveCached writes a cached response, or 304 Not Modified when the client
+// already has it
+func serveCached(c *gin.Context, entry cachedResponse) {
+	header := c.Writer.Header()
+	for name, values := range entry.header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set("X-Cache", "HIT")
+
+	if etag := entry.header.Get("ETag"); etag != "" && ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		header.Del("Content-Length")
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Write(entry.body)
+	c.Abort()
+}
+
+// noCache reports whether the client asked for a fresh response
+func noCache(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Cache-Control"), "no-cache") ||
+		strings.Contains(r.Header.Get("Pragma"), "no-cache")
+}
+
+// removeVary drops token from the Vary header, keeping the others
+func removeVary(header http.Header, token string) {
+	var kept []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, token) {
+				kept = append(kept, name)
+			}
+		}
+	}
+	header.Del("Vary")
+	if len(kept) > 0 {
+		header.Set("Vary", strings.Join(kept, ", "))
+	}
+}
+
+// ETagMatches reports whether an If-None-Match header matches the given ETag
+func ETagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWriter copies a response body as it is written. A flushed response is
+// streaming and isn't kept.
+type cacheWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+	skip bool
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *cacheWriter) Flush() {
+	w.skip = true
+	w.ResponseWriter.Flush()
+}
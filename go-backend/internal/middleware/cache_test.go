@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheClock is a settable now for ResponseCache
+type cacheClock struct{ t time.Time }
+
+func (c *cacheClock) now() time.Time { return c.t }
+
+func newTestCache(ttl time.Duration) (*ResponseCache, *cacheClock) {
+	clock := &cacheClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	rc := NewResponseCache(ttl)
+	rc.now = clock.now
+	return rc, clock
+}
+
+// cachedRouter serves /count through rc, answering with how often the
+// handler has run
+func cachedRouter(rc *ResponseCache, handler func(c *gin.Context)) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	router.GET("/count", rc.Handler(), func(c *gin.Context) {
+		calls++
+		if handler != nil {
+			handler(c)
+		}
+		c.String(http.StatusOK, "%d", calls)
+	})
+	return router, &calls
+}
+
+func getCount(router http.Handler, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/count", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	rc, clock := newTestCache(time.Minute)
+	router, calls := cachedRouter(rc, nil)
+
+	w := getCount(router, nil)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+
+	clock.t = clock.t.Add(time.Minute - time.Second)
+	w = getCount(router, nil)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "1", w.Body.String())
+
+	// At the TTL the entry is gone and the handler runs again
+	clock.t = clock.t.Add(time.Second)
+	w = getCount(router, nil)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.Equal(t, "2", w.Body.String())
+	assert.Equal(t, 2, *calls)
+}
+
+func TestResponseCacheEvictsWhenFull(t *testing.T) {
+	rc, clock := newTestCache(time.Minute)
+	fill := func(n int, prefix string) {
+		for i := 0; i < n; i++ {
+			rc.set(fmt.Sprintf("%s%d", prefix, i), cachedResponse{})
+		}
+	}
+
+	// A full cache drops its expired entries first
+	fill(responseCacheMaxEntries-1, "old")
+	clock.t = clock.t.Add(30 * time.Second)
+	rc.set("fresh", cachedResponse{})
+	require.Len(t, rc.entries, responseCacheMaxEntries)
+	clock.t = clock.t.Add(45 * time.Second)
+	rc.set("new", cachedResponse{})
+	assert.Len(t, rc.entries, 2)
+	assert.Contains(t, rc.entries, "fresh")
+	assert.Contains(t, rc.entries, "new")
+
+	// and empties when none have expired
+	fill(responseCacheMaxEntries-2, "live")
+	require.Len(t, rc.entries, responseCacheMaxEntries)
+	rc.set("last", cachedResponse{})
+	assert.Len(t, rc.entries, 1)
+	assert.Contains(t, rc.entries, "last")
+}
+
+func TestResponseCacheNoCacheRefreshes(t *testing.T) {
+	rc, _ := newTestCache(time.Minute)
+	router, _ := cachedRouter(rc, nil)
+
+	getCount(router, nil)
+	for _, header := range []http.Header{
+		{"Cache-Control": {"no-cache"}},
+		{"Pragma": {"no-cache"}},
+	} {
+		w := getCount(router, header)
+		assert.Equal(t, "MISS", w.Header().Get("X-Cache"), "%v", header)
+	}
+
+	// The refreshed response replaced the cached one
+	w := getCount(router, nil)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.Equal(t, "3", w.Body.String())
+}
+
+func TestResponseCacheSkipsFlushedResponses(t *testing.T) {
+	rc, _ := newTestCache(time.Minute)
+	router, calls := cachedRouter(rc, func(c *gin.Context) {
+		c.Writer.WriteString("streamed ")
+		c.Writer.Flush()
+	})
+
+	getCount(router, nil)
+	w := getCount(router, nil)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.Equal(t, "streamed 2", w.Body.String())
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, rc.entries)
+}
+
+func TestResponseCacheDropsEncodingHeaders(t *testing.T) {
+	rc, _ := newTestCache(time.Minute)
+	router, _ := cachedRouter(rc, func(c *gin.Context) {
+		c.Header("Vary", "Origin, Accept-Encoding")
+		c.Writer.Header().Add("Vary", "Cookie")
+		c.Header("Content-Encoding", "gzip")
+	})
+
+	getCount(router, nil)
+	w := getCount(router, nil)
+	require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	// Only Accept-Encoding leaves Vary; the rest still applies to the hit
+	assert.Equal(t, []string{"Origin, Cookie"}, w.Header().Values("Vary"))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
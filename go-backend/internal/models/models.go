@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // StringSlice is a custom type for handling JSON arrays in PostgreSQL
@@ -59,50 +61,181 @@ func (f *FloatSlice) Scan(value interface{}) error {
 	}
 }
 
+// IntSlice is a custom type for handling JSON arrays of integers
+type IntSlice []int
+
+func (s IntSlice) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return "[]", nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *IntSlice) Scan(value interface{}) error {
+	if value == nil {
+		*s = IntSlice{}
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return errors.New("cannot scan into IntSlice")
+	}
+}
+
+// QueryParams is a set of URL query parameters, stored as a JSON object of
+// string lists like url.Values
+type QueryParams map[string][]string
+
+func (p QueryParams) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *QueryParams) Scan(value interface{}) error {
+	if value == nil {
+		*p = QueryParams{}
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return errors.New("cannot scan into QueryParams")
+	}
+}
+
+// JSONMap is a custom type for handling JSON objects with arbitrary values.
+// Numbers read back as float64, as encoding/json decodes them.
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(m)
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	if value == nil {
+		*m = JSONMap{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("cannot scan into JSONMap")
+	}
+
+	// Decode into a fresh map; unmarshalling into *m would keep stale keys
+	decoded := JSONMap{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded == nil {
+		// A stored JSON null
+		decoded = JSONMap{}
+	}
+	*m = decoded
+	return nil
+}
+
 // Record represents a music record
 type Record struct {
-	ID             uint        `json:"id" gorm:"primaryKey"`
-	DiscogsID      string      `json:"discogs_id" gorm:"uniqueIndex;not null"`
-	Artist         string      `json:"artist" gorm:"not null"`
-	Title          string      `json:"title" gorm:"not null"`
-	Format         string      `json:"format" gorm:"default:''"`
-	Label          string      `json:"label" gorm:"type:text"`
-	Catno          *string     `json:"catno"`
-	Wants          int         `json:"wants" gorm:"default:0"`
-	Haves          int         `json:"haves" gorm:"default:0"`
-	Added          time.Time   `json:"added" gorm:"default:CURRENT_TIMESTAMP"`
-	Genres         StringSlice `json:"genres" gorm:"type:jsonb;default:'[]'"`
-	Styles         StringSlice `json:"styles" gorm:"type:jsonb;default:'[]'"`
-	SuggestedPrice string      `json:"suggested_price" gorm:"default:''"`
-	Year           *int        `json:"year"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	DiscogsID string `json:"discogs_id" gorm:"uniqueIndex;not null"`
+	Artist    string `json:"artist" gorm:"not null"`
+	Title     string `json:"title" gorm:"not null"`
+	Format    string `json:"format" gorm:"default:''"`
+	// FormatDescriptions are Discogs' format descriptions, e.g. "Album", "Reissue"
+	FormatDescriptions StringSlice `json:"format_descriptions" gorm:"type:jsonb;default:'[]'"`
+	Label              string      `json:"label" gorm:"type:text"`
+	Catno              *string     `json:"catno"`
+	Wants              int         `json:"wants" gorm:"default:0"`
+	Haves              int         `json:"haves" gorm:"default:0"`
+	Added              time.Time   `json:"added" gorm:"default:CURRENT_TIMESTAMP"`
+	Genres             StringSlice `json:"genres" gorm:"type:jsonb;default:'[]'"`
+	Styles             StringSlice `json:"styles" gorm:"type:jsonb;default:'[]'"`
+	SuggestedPrice     string      `json:"suggested_price" gorm:"default:''"`
+	Year               *int        `json:"year"`
+	// Metadata holds release fields without a column of their own, e.g.
+	// country, released and community_rating
+	Metadata JSONMap `json:"metadata" gorm:"type:jsonb;default:'{}'"`
+	// Notes and Tags are the collector's own annotations, e.g. "already own
+	// on CD" or "to-buy"; tags are stored lowercase
+	Notes     string      `json:"notes" gorm:"type:text;default:''"`
+	Tags      StringSlice `json:"tags" gorm:"type:jsonb;default:'[]'"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	// WantsHavesRatio is computed on load and not stored
+	WantsHavesRatio float64 `json:"wants_haves_ratio" gorm:"-"`
+}
+
+// WantsToHaves returns the community wants/haves ratio for the record.
+// Records nobody owns yet are divided by one so the ratio stays finite.
+func (r Record) WantsToHaves() float64 {
+	if r.Haves <= 0 {
+		return float64(r.Wants)
+	}
+	return float64(r.Wants) / float64(r.Haves)
+}
+
+// AfterFind populates computed fields after a record is loaded
+func (r *Record) AfterFind(tx *gorm.DB) error {
+	r.WantsHavesRatio = r.WantsToHaves()
+	return nil
 }
 
 // Seller represents a record seller
 type Seller struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"`
-	Currency  string    `json:"currency" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null"`
+	Currency string `json:"currency" gorm:"not null"`
+	// Rating is the seller's positive feedback percentage on Discogs, nil
+	// until a scrape sees one
+	Rating        *float64   `json:"rating"`
+	FeedbackCount int        `json:"feedback_count" gorm:"default:0"`
+	LastScrapedAt *time.Time `json:"last_scraped_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // Listing represents a record listing by a seller
 type Listing struct {
-	ID               uint    `json:"id" gorm:"primaryKey"`
-	SellerID         uint    `json:"seller_id" gorm:"not null"`
-	Seller           Seller  `json:"seller" gorm:"foreignKey:SellerID"`
-	RecordID         uint    `json:"record_id" gorm:"not null"`
-	Record           Record  `json:"record" gorm:"foreignKey:RecordID"`
-	RecordPrice      float64 `json:"record_price" gorm:"type:decimal(6,2);not null"`
-	MediaCondition   string  `json:"media_condition" gorm:"not null"`
-	Score            float64 `json:"score" gorm:"type:decimal(6,2);default:0.00"`
-	Kept             bool    `json:"kept" gorm:"default:false"`
-	Evaluated        bool    `json:"evaluated" gorm:"default:false"`
-	PredictedKeeper  bool    `json:"predicted_keeper" gorm:"default:false"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID              uint    `json:"id" gorm:"primaryKey"`
+	SellerID        uint    `json:"seller_id" gorm:"not null"`
+	Seller          Seller  `json:"seller" gorm:"foreignKey:SellerID"`
+	RecordID        uint    `json:"record_id" gorm:"not null"`
+	Record          Record  `json:"record" gorm:"foreignKey:RecordID"`
+	RecordPrice     float64 `json:"record_price" gorm:"type:decimal(6,2);not null"`
+	MediaCondition  string  `json:"media_condition" gorm:"not null"`
+	Score           float64 `json:"score" gorm:"type:decimal(6,2);default:0.00"`
+	Kept            bool    `json:"kept" gorm:"default:false"`
+	Evaluated       bool    `json:"evaluated" gorm:"default:false"`
+	PredictedKeeper bool    `json:"predicted_keeper" gorm:"default:false"`
+	// ShipsFrom is the country the listing ships from, empty when unknown
+	ShipsFrom         string    `json:"ships_from" gorm:"index;default:''"`
+	KeeperProbability *float64  `json:"keeper_probability"`
+	DiscogsListingID  *int      `json:"discogs_listing_id" gorm:"index"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// DeletedAt is set when the listing disappears from the seller's inventory
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 // RecommendationModel stores ML model data
@@ -130,25 +263,68 @@ type RecommendationMetrics struct {
 
 // RecordOfTheDay tracks daily record selections
 type RecordOfTheDay struct {
-	ID                   uint       `json:"id" gorm:"primaryKey"`
-	Date                 time.Time  `json:"date" gorm:"uniqueIndex;type:date"`
-	ListingID            uint       `json:"listing_id" gorm:"not null"`
-	Listing              Listing    `json:"listing" gorm:"foreignKey:ListingID"`
-	CreatedAt            time.Time  `json:"created_at"`
-	ModelScore           float64    `json:"model_score"`
-	EntropyMeasure       float64    `json:"entropy_measure"`
-	SystemTemperature    float64    `json:"system_temperature"`
-	UtilityTerm          *float64   `json:"utility_term"`
-	EntropyTerm          *float64   `json:"entropy_term"`
-	FreeEnergy           *float64   `json:"free_energy"`
-	SelectionProbability *float64   `json:"selection_probability"`
-	TotalCandidates      *int       `json:"total_candidates"`
-	ClusterCount         *int       `json:"cluster_count"`
-	SelectionMethod      string     `json:"selection_method" gorm:"default:'thermodynamic_boltzmann'"`
-	DesirabilityVotes    FloatSlice `json:"desirability_votes" gorm:"type:jsonb;default:'[]'"`
-	NoveltyVotes         FloatSlice `json:"novelty_votes" gorm:"type:jsonb;default:'[]'"`
-	AverageDesirability  float64    `json:"average_desirability" gorm:"default:0.0"`
-	AverageNovelty       float64    `json:"average_novelty" gorm:"default:0.0"`
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Date      time.Time `json:"date" gorm:"uniqueIndex;type:date"`
+	ListingID uint      `json:"listing_id" gorm:"not null"`
+	Listing   Listing   `json:"listing" gorm:"foreignKey:ListingID"`
+	CreatedAt time.Time `json:"created_at"`
+	// Breakdown is the thermodynamic service's explanation of the pick, as
+	// it sent it
+	Breakdown JSONMap `json:"breakdown" gorm:"type:jsonb;default:'{}'"`
+	// SelectionMethod repeats the breakdown's selection_method to filter on
+	SelectionMethod string `json:"selection_method" gorm:"default:'thermodynamic_boltzmann'"`
+
+	// Deprecated: picks made before Breakdown existed kept their breakdown
+	// in these columns. New picks leave them empty; see LegacyBreakdown.
+	ModelScore           float64  `json:"model_score"`
+	EntropyMeasure       float64  `json:"entropy_measure"`
+	SystemTemperature    float64  `json:"system_temperature"`
+	UtilityTerm          *float64 `json:"utility_term"`
+	EntropyTerm          *float64 `json:"entropy_term"`
+	FreeEnergy           *float64 `json:"free_energy"`
+	SelectionProbability *float64 `json:"selection_probability"`
+	TotalCandidates      *int     `json:"total_candidates"`
+	ClusterCount         *int     `json:"cluster_count"`
+
+	DesirabilityVotes   FloatSlice `json:"desirability_votes" gorm:"type:jsonb;default:'[]'"`
+	NoveltyVotes        FloatSlice `json:"novelty_votes" gorm:"type:jsonb;default:'[]'"`
+	AverageDesirability float64    `json:"average_desirability" gorm:"default:0.0"`
+	AverageNovelty      float64    `json:"average_novelty" gorm:"default:0.0"`
+}
+
+// LegacyBreakdown rebuilds the breakdown of a pick saved before Breakdown
+// existed from its individual columns
+func (r RecordOfTheDay) LegacyBreakdown() JSONMap {
+	return JSONMap{
+		"model_score":           r.ModelScore,
+		"entropy_measure":       r.EntropyMeasure,
+		"system_temperature":    r.SystemTemperature,
+		"utility_term":          r.UtilityTerm,
+		"entropy_term":          r.EntropyTerm,
+		"free_energy":           r.FreeEnergy,
+		"selection_probability": r.SelectionProbability,
+		"total_candidates":      r.TotalCandidates,
+		"cluster_count":         r.ClusterCount,
+		"selection_method":      r.SelectionMethod,
+	}
+}
+
+// UpdateAverages sets the average ratings from the vote lists; a list with
+// no votes averages 0
+func (r *RecordOfTheDay) UpdateAverages() {
+	r.AverageDesirability = average(r.DesirabilityVotes)
+	r.AverageNovelty = average(r.NoveltyVotes)
+}
+
+func average(votes []float64) float64 {
+	if len(votes) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, vote := range votes {
+		sum += vote
+	}
+	return sum / float64(len(votes))
 }
 
 // RecordOfTheDayFeedback stores user feedback
@@ -161,6 +337,68 @@ type RecordOfTheDayFeedback struct {
 	CreatedAt          time.Time      `json:"created_at"`
 }
 
+// PriceHistory is a price observed for a listing during a scrape
+type PriceHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ListingID  uint      `json:"listing_id" gorm:"index;not null"`
+	Price      float64   `json:"price" gorm:"type:decimal(8,2);not null"`
+	Currency   string    `json:"currency"`
+	ObservedAt time.Time `json:"observed_at" gorm:"index"`
+}
+
+// Scrape run statuses
+const (
+	ScrapeRunRunning = "running"
+	ScrapeRunSuccess = "success"
+	ScrapeRunFailed  = "failed"
+)
+
+// ScrapeRun records a single scrape of a seller's inventory
+type ScrapeRun struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Seller     string     `json:"seller" gorm:"index;not null"`
+	StartedAt  time.Time  `json:"started_at" gorm:"index"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Total      int        `json:"total"`
+	New        int        `json:"new"`
+	Errors     int        `json:"errors"`
+	Status     string     `json:"status" gorm:"default:'running'"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// FailedListing is a scraped listing that couldn't be saved, kept with its
+// last error so it can be inspected and retried
+type FailedListing struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Seller        string    `json:"seller" gorm:"index;not null"`
+	ListingID     int       `json:"listing_id" gorm:"index"`   // Discogs marketplace listing ID
+	DiscogsID     int       `json:"discogs_id"`                // Discogs release ID
+	Listing       JSONMap   `json:"listing" gorm:"type:jsonb"` // The listing as scraped
+	Error         string    `json:"error"`
+	Failures      int       `json:"failures"` // Failed saves of this listing so far
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at" gorm:"index"`
+}
+
+// ReviewSession is one submission of evaluated listings, kept as a history
+// of review activity and so the whole batch can be undone
+type ReviewSession struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	ListingIDs IntSlice   `json:"listing_ids" gorm:"type:jsonb"`
+	KeeperIDs  IntSlice   `json:"keeper_ids" gorm:"type:jsonb"`
+	Accuracy   *float64   `json:"accuracy"` // Of the model trained on the batch; nil if training failed
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+	UndoneAt   *time.Time `json:"undone_at"` // Set once the batch was undone
+}
+
+// SavedSearch is a named set of search filters that can be run again
+type SavedSearch struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	Name      string      `json:"name" gorm:"uniqueIndex;not null"`
+	Params    QueryParams `json:"params" gorm:"type:jsonb;default:'{}'"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
 // TableName methods for custom table names to match Django
 func (Record) TableName() string {
 	return "discogs_record"
@@ -189,3 +427,23 @@ func (RecordOfTheDay) TableName() string {
 func (RecordOfTheDayFeedback) TableName() string {
 	return "discogs_recordofthedayfeedback"
 }
+
+func (ScrapeRun) TableName() string {
+	return "discogs_scraperun"
+}
+
+func (PriceHistory) TableName() string {
+	return "discogs_pricehistory"
+}
+
+func (FailedListing) TableName() string {
+	return "discogs_failedlisting"
+}
+
+func (ReviewSession) TableName() string {
+	return "discogs_reviewsession"
+}
+
+func (SavedSearch) TableName() string {
+	return "discogs_savedsearch"
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONMapRoundTrip(t *testing.T) {
+	maps := []JSONMap{
+		{"country": "UK", "rating": 4.5, "count": float64(12), "tags": []interface{}{"a", "b"}},
+		{"nested": map[string]interface{}{"ok": true}, "none": nil},
+	}
+
+	for _, original := range maps {
+		value, err := original.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Drivers hand back []byte or string
+		for _, stored := range []interface{}{value, string(value.([]byte))} {
+			var got JSONMap
+			if err := got.Scan(stored); err != nil {
+				t.Fatalf("Scan(%T): %v", stored, err)
+			}
+			if !reflect.DeepEqual(got, original) {
+				t.Errorf("round trip of %v via %T = %v", original, stored, got)
+			}
+		}
+	}
+}
+
+func TestJSONMapEmpty(t *testing.T) {
+	for _, m := range []JSONMap{nil, {}} {
+		if value, err := m.Value(); err != nil || value != "{}" {
+			t.Errorf("%#v.Value() = %v, %v, want {}", m, value, err)
+		}
+	}
+
+	for _, stored := range []interface{}{nil, "{}", []byte("null")} {
+		var got JSONMap
+		if err := got.Scan(stored); err != nil {
+			t.Fatalf("Scan(%#v): %v", stored, err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("Scan(%#v) = %#v, want an empty map", stored, got)
+		}
+	}
+}
+
+func TestJSONMapScanReplaces(t *testing.T) {
+	m := JSONMap{"stale": true}
+	if err := m.Scan(`{"fresh": true}`); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, JSONMap{"fresh": true}) {
+		t.Errorf("Scan kept old keys: %v", m)
+	}
+
+	if err := m.Scan(42); err == nil {
+		t.Error("Scan(int) accepted")
+	}
+	if err := m.Scan(`[1, 2]`); err == nil {
+		t.Error("Scan of a JSON array accepted")
+	}
+}
+
+func TestRecordOfTheDayUpdateAverages(t *testing.T) {
+	r := RecordOfTheDay{DesirabilityVotes: FloatSlice{1, 2, 4.5}, AverageNovelty: 3}
+	r.UpdateAverages()
+	if r.AverageDesirability != 2.5 {
+		t.Errorf("AverageDesirability = %v, want 2.5", r.AverageDesirability)
+	}
+	if r.AverageNovelty != 0 {
+		t.Errorf("AverageNovelty = %v with no votes, want 0", r.AverageNovelty)
+	}
+}
+
+func TestIntSliceRoundTrip(t *testing.T) {
+	original := IntSlice{3, 1, 2}
+	value, err := original.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stored := range []interface{}{value, string(value.([]byte))} {
+		var got IntSlice
+		if err := got.Scan(stored); err != nil {
+			t.Fatalf("Scan(%T): %v", stored, err)
+		}
+		if !reflect.DeepEqual(got, original) {
+			t.Errorf("round trip via %T = %v, want %v", stored, got, original)
+		}
+	}
+
+	if value, err := IntSlice(nil).Value(); err != nil || value != "[]" {
+		t.Errorf("IntSlice(nil).Value() = %v, %v, want []", value, err)
+	}
+	var got IntSlice
+	if err := got.Scan(nil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("Scan(nil) = %#v, %v, want an empty slice", got, err)
+	}
+}
+
+func TestQueryParamsRoundTrip(t *testing.T) {
+	original := QueryParams{"genre_style": {"Jazz"}, "format": {"LP", "CD"}}
+	value, err := original.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stored := range []interface{}{value, string(value.([]byte))} {
+		var got QueryParams
+		if err := got.Scan(stored); err != nil {
+			t.Fatalf("Scan(%T): %v", stored, err)
+		}
+		if !reflect.DeepEqual(got, original) {
+			t.Errorf("round trip via %T = %v, want %v", stored, got, original)
+		}
+	}
+
+	if value, err := QueryParams(nil).Value(); err != nil || value != "{}" {
+		t.Errorf("QueryParams(nil).Value() = %v, %v, want {}", value, err)
+	}
+	var got QueryParams
+	if err := got.Scan(nil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("Scan(nil) = %#v, %v, want an empty map", got, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package scoring rates listings on their record's demand, their price
+// against the Discogs suggestion and their condition
+package scoring
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"discogs-api/internal/condition"
+	"discogs-api/internal/config"
+	"discogs-api/internal/currency"
+	"discogs-api/internal/models"
+)
+
+// MaxScore is the score of a listing that is perfect on every factor
+const MaxScore = 10
+
+// neutral is the factor given when there is nothing to judge by, such as a
+// record without a suggested price
+const neutral = 0.5
+
+// Score rates a listing from 0 to MaxScore. The listing's Record and Seller
+// must be loaded. Each factor is between 0 and 1 and the score is their
+// weighted mean, rounded to cents to fit the score column.
+func Score(weights config.ScoreWeights, listing models.Listing) float64 {
+	total := weights.WantsHaves + weights.Deal + weights.Condition
+	if total <= 0 {
+		return 0
+	}
+
+	sum := weights.WantsHaves*demand(listing.Record) +
+		weights.Deal*deal(listing) +
+		weights.Condition*float64(condition.Rank(listing.MediaCondition))/float64(condition.Rank(condition.Mint))
+	return math.Round(sum/total*MaxScore*100) / 100
+}
+
+// demand maps the wants/haves ratio onto 0-1, reaching a half when as many
+// people want the record as have it
+func demand(record models.Record) float64 {
+	ratio := record.WantsToHaves()
+	return ratio / (1 + ratio)
+}
+
+// deal compares the suggested price with the asking price the same way, so
+// a listing at the suggested price scores a half and cheaper ones more. The
+// suggestion is only used when it is in the seller's currency.
+func deal(listing models.Listing) float64 {
+	suggested, ok := suggestedPrice(listing.Record.SuggestedPrice, listing.Seller.Currency)
+	if !ok || listing.RecordPrice <= 0 {
+		return neutral
+	}
+	ratio := suggested / listing.RecordPrice
+	return ratio / (1 + ratio)
+}
+
+// suggestedPrice parses a suggestion stored as "25.00 USD", reporting false
+// when it is missing, malformed or in a currency other than want
+func suggestedPrice(suggestion, want string) (float64, bool) {
+	fields := strings.Fields(suggestion)
+	if len(fields) != 2 {
+		return 0, false
+	}
+	price, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || price <= 0 {
+		return 0, false
+	}
+	code, ok := currency.Normalize(fields[1])
+	if !ok {
+		return 0, false
+	}
+	if wantCode, ok := currency.Normalize(want); !ok || wantCode != code {
+		return 0, false
+	}
+	return price, true
+}
+
+// Scorer holds the weights in use, which can be changed while the server runs
+type Scorer struct {
+	mu      sync.RWMutex
+	weights config.ScoreWeights
+}
+
+// NewScorer scores with the given weights, falling back to the defaults with
+// a warning when they are invalid
+func NewScorer(weights config.ScoreWeights) *Scorer {
+	if err := weights.Validate(); err != nil {
+		log.Printf("Warning: %v, using the default score weights", err)
+		weights = config.DefaultScoreWeights()
+	}
+	return &Scorer{weights: weights}
+}
+
+// Weights returns the weights in use
+func (s *Scorer) Weights() config.ScoreWeights {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.weights
+}
+
+// SetWeights replaces the weights if they are valid
+func (s *Scorer) SetWeights(weights config.ScoreWeights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weights = weights
+	return nil
+}
+
+// Score rates a listing with the weights in use
+func (s *Scorer) Score(listing models.Listing) float64 {
+	return Score(s.Weights(), listing)
+}
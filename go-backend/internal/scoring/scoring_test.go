@@ -0,0 +1,72 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+)
+
+func listing(wants, haves int, price float64, suggested, mediaCondition string) models.Listing {
+	return models.Listing{
+		RecordPrice:    price,
+		MediaCondition: mediaCondition,
+		Record:         models.Record{Wants: wants, Haves: haves, SuggestedPrice: suggested},
+		Seller:         models.Seller{Currency: "USD"},
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights config.ScoreWeights
+		listing models.Listing
+		want    float64
+	}{
+		// Demand 0.5, deal 0.5, condition 1
+		{"even", config.DefaultScoreWeights(), listing(10, 10, 20, "20.00 USD", "Mint (M)"), 6.67},
+		{"demand only", config.ScoreWeights{WantsHaves: 1}, listing(30, 10, 20, "", "Poor (P)"), 7.5},
+		{"cheap", config.ScoreWeights{Deal: 1}, listing(0, 0, 10, "30.00 USD", ""), 7.5},
+		{"suggestion in another currency", config.ScoreWeights{Deal: 1}, listing(0, 0, 10, "30.00 EUR", ""), 5},
+		{"no suggestion", config.ScoreWeights{Deal: 1}, listing(0, 0, 10, "", ""), 5},
+		{"condition only", config.ScoreWeights{Condition: 2}, listing(0, 0, 10, "", "VG"), 6.25},
+		{"unknown condition", config.ScoreWeights{Condition: 1}, listing(0, 0, 10, "", "Sealed"), 0},
+		{"no weights", config.ScoreWeights{}, listing(10, 1, 10, "", "M"), 0},
+	}
+
+	for _, tt := range tests {
+		if got := Score(tt.weights, tt.listing); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("%s: Score() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScorerSetWeights(t *testing.T) {
+	scorer := NewScorer(config.ScoreWeights{WantsHaves: math.NaN()})
+	if got := scorer.Weights(); got != config.DefaultScoreWeights() {
+		t.Errorf("invalid weights kept: %+v", got)
+	}
+
+	invalid := []config.ScoreWeights{
+		{WantsHaves: math.Inf(1)},
+		{Deal: -1, Condition: 1},
+		{},
+	}
+	for _, weights := range invalid {
+		if err := scorer.SetWeights(weights); err == nil {
+			t.Errorf("SetWeights(%+v) accepted", weights)
+		}
+	}
+	if got := scorer.Weights(); got != config.DefaultScoreWeights() {
+		t.Errorf("rejected weights applied: %+v", got)
+	}
+
+	weights := config.ScoreWeights{Condition: 1}
+	if err := scorer.SetWeights(weights); err != nil {
+		t.Fatal(err)
+	}
+	if got := scorer.Score(listing(100, 1, 10, "", "Mint (M)")); got != MaxScore {
+		t.Errorf("Score() = %v, want only condition to count", got)
+	}
+}
@@ -17,13 +17,13 @@ type TokenData struct {
 	Secret string `json:"secret"`
 }
 
-// LoadTokens loads OAuth tokens from file
+// LoadTokens loads OAuth tokens from the token file in the data directory
 func LoadTokens() (*TokenData, error) {
-	if _, err := os.Stat(TokenFile); os.IsNotExist(err) {
+	if _, err := os.Stat(dataPath(TokenFile)); os.IsNotExist(err) {
 		return nil, nil
 	}
 
-	data, err := os.ReadFile(TokenFile)
+	data, err := os.ReadFile(dataPath(TokenFile))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read token file: %w", err)
 	}
@@ -36,7 +36,7 @@ func LoadTokens() (*TokenData, error) {
 	return &tokens, nil
 }
 
-// SaveTokens saves OAuth tokens to file
+// SaveTokens saves OAuth tokens to the token file in the data directory
 func SaveTokens(token, secret string) error {
 	tokens := TokenData{
 		Token:  token,
@@ -48,7 +48,7 @@ func SaveTokens(token, secret string) error {
 		return fmt.Errorf("failed to marshal tokens: %w", err)
 	}
 
-	if err := os.WriteFile(TokenFile, data, 0600); err != nil {
+	if err := writeDataFile(TokenFile, data, 0600); err != nil {
 		return fmt.Errorf("failed to write token file: %w", err)
 	}
 
@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dataDir is where the token and inventory files are kept; empty is the
+// working directory
+var dataDir string
+
+// SetDataDir points the token and inventory files at dir, so the CLI and the
+// server share state wherever they are started from. Call it before
+// scraping; an empty dir uses the working directory.
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+// DataDir returns the directory set with SetDataDir
+func DataDir() string {
+	return dataDir
+}
+
+// dataPath is the path of a state file in the data directory
+func dataPath(name string) string {
+	if dataDir == "" {
+		return name
+	}
+	return filepath.Join(dataDir, name)
+}
+
+// writeDataFile writes a state file, creating the data directory if needed.
+// The data goes to a temporary file that is renamed into place, so readers
+// never see a partly written file.
+func writeDataFile(name string, data []byte, perm os.FileMode) error {
+	if dataDir != "" {
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	path := dataPath(name)
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
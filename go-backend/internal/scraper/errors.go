@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors the scraper wraps with %w so callers can tell failures apart with
+// errors.Is
+var (
+	// ErrRateLimited is returned when Discogs answers 429 Too Many Requests
+	ErrRateLimited = errors.New("rate limited by Discogs")
+	// ErrUnauthorized is returned when Discogs rejects the OAuth credentials
+	ErrUnauthorized = errors.New("not authorized by Discogs")
+	// ErrSellerNotFound is returned when Discogs has no inventory for a seller
+	ErrSellerNotFound = errors.New("seller inventory not found")
+	// ErrParse is returned when a Discogs response can't be decoded
+	ErrParse = errors.New("failed to parse Discogs response")
+)
+
+// DefaultRetryAfter is how long to wait after a 429 that doesn't say. Discogs
+// counts requests over a moving one minute window.
+const DefaultRetryAfter = time.Minute
+
+// RateLimitError is the ErrRateLimited a 429 response produces, carrying
+// how long Discogs asked callers to wait
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrRateLimited, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrRateLimited) match
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter reports how long to wait before retrying after err, if err is
+// a rate limit
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, true
+	}
+	return 0, false
+}
+
+// statusError describes a Discogs response other than 200, wrapping the
+// error matching its status where there is one
+func statusError(resp *http.Response) error {
+	status := resp.StatusCode
+	switch status {
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("API returned status %d: %w", status, &RateLimitError{RetryAfter: retryAfter(resp.Header)})
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("API returned status %d: %w", status, ErrUnauthorized)
+	case http.StatusNotFound:
+		return fmt.Errorf("API returned status %d: %w", status, ErrSellerNotFound)
+	}
+	return fmt.Errorf("API returned status %d", status)
+}
+
+// retryAfter reads a Retry-After header given in seconds, falling back to
+// DefaultRetryAfter
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return DefaultRetryAfter
+}
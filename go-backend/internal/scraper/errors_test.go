@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetInventoryErrorTypes(t *testing.T) {
+	inTempDir(t)
+
+	for _, tc := range []struct {
+		name   string
+		handle http.HandlerFunc
+		want   error
+	}{
+		{"rate limited", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTooManyRequests) }, ErrRateLimited},
+		{"unauthorized", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) }, ErrUnauthorized},
+		{"forbidden", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) }, ErrUnauthorized},
+		{"bad json", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("<html>")) }, ErrParse},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handle)
+			defer server.Close()
+
+			s, err := NewScraper("", "", WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.GetInventory("seller")
+			if !errors.Is(err, tc.want) {
+				t.Errorf("got %v, want an error wrapping %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	if err := statusError(&http.Response{StatusCode: http.StatusNotFound}); !errors.Is(err, ErrSellerNotFound) {
+		t.Errorf("404 gave %v", err)
+	}
+	err := statusError(&http.Response{StatusCode: http.StatusBadGateway})
+	for _, typed := range []error{ErrRateLimited, ErrUnauthorized, ErrSellerNotFound, ErrParse} {
+		if errors.Is(err, typed) {
+			t.Errorf("502 wraps %v", typed)
+		}
+	}
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "30")
+	err := statusError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: header})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("429 gave %v", err)
+	}
+	if wait, ok := RetryAfter(err); !ok || wait != 30*time.Second {
+		t.Errorf("RetryAfter = %v, %v; want 30s", wait, ok)
+	}
+
+	err = statusError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+	if wait, _ := RetryAfter(err); wait != DefaultRetryAfter {
+		t.Errorf("RetryAfter without a header = %v, want %v", wait, DefaultRetryAfter)
+	}
+
+	if _, ok := RetryAfter(ErrParse); ok {
+		t.Error("RetryAfter matched a parse error")
+	}
+}
@@ -0,0 +1,443 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// roundTripFunc serves canned responses in place of the network
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// timeoutError looks like a client timeout to isTimeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "fixture timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// inventoryFixture is a seller inventory served page by page. Every odd
+// release is a keeper; even releases are CDs.
+type inventoryFixture struct {
+	mu       sync.Mutex
+	items    int
+	requests map[int]int // requests per page
+	// fail, if set, may replace the response for a page and attempt
+	fail func(page, attempt int) (*http.Response, error)
+}
+
+func (f *inventoryFixture) RoundTrip(r *http.Request) (*http.Response, error) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+	f.mu.Lock()
+	if f.requests == nil {
+		f.requests = make(map[int]int)
+	}
+	f.requests[page]++
+	attempt := f.requests[page]
+	f.mu.Unlock()
+
+	if f.fail != nil {
+		if resp, err := f.fail(page, attempt); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	var listings []DiscogsListing
+	for id := (page-1)*perPage + 1; id <= page*perPage && id <= f.items; id++ {
+		listing := benchmarkListing()
+		listing.ID = 1000 + id
+		listing.Release.ID = id
+		listing.Release.Stats.Community = DiscogsCommunityStats{InWantlist: 10, InCollection: 1}
+		if id%2 == 0 {
+			listing.Release.Format = "CD, Album"
+		}
+		listings = append(listings, listing)
+	}
+
+	body, _ := json.Marshal(DiscogsInventoryResponse{
+		Listings: listings,
+		Pagination: DiscogsPagination{
+			Page:    page,
+			Pages:   (f.items + perPage - 1) / perPage,
+			PerPage: perPage,
+			Items:   f.items,
+		},
+	})
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Discogs-Ratelimit", "60")
+	header.Set("X-Discogs-Ratelimit-Used", strconv.Itoa(attempt))
+	header.Set("X-Discogs-Ratelimit-Remaining", strconv.Itoa(60-attempt))
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       nopCloser{bytes.NewReader(body)},
+		Request:    r,
+	}, nil
+}
+
+type nopCloser struct{ *bytes.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+func statusResponse(status int) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       nopCloser{bytes.NewReader(nil)},
+	}
+}
+
+func newFixtureScraper(t *testing.T, fixture *inventoryFixture, perPage int) *Scraper {
+	inTempDir(t)
+	config := DefaultConfig("", "")
+	config.PerPage = perPage
+	config.PageDelay = 0
+	s, err := NewScraperWithConfig(config, WithHTTPClient(&http.Client{Transport: fixture}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFullScanWalksEveryPage(t *testing.T) {
+	fixture := &inventoryFixture{items: 7}
+	s := newFixtureScraper(t, fixture, 4)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalPages != 2 || result.PagesScanned != 2 {
+		t.Errorf("pages: total=%d scanned=%d, want 2 and 2", result.TotalPages, result.PagesScanned)
+	}
+	if len(result.SeenIDs) != 7 {
+		t.Errorf("saw %d releases, want 7", len(result.SeenIDs))
+	}
+	if result.TotalRecords != 4 {
+		t.Errorf("found %d keepers, want 4", result.TotalRecords)
+	}
+	if !result.CoversInventory() {
+		t.Error("a clean full scan should cover the inventory")
+	}
+}
+
+func TestProcessPageStopsAtPreviouslySeenRecord(t *testing.T) {
+	fixture := &inventoryFixture{items: 4}
+	s := newFixtureScraper(t, fixture, 4)
+
+	listings, ids, stop, err := s.processPage(context.Background(), "seller", 1, map[int]bool{3: true}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stop {
+		t.Error("expected processPage to stop at release 3")
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("ids = %v, want [1 2]", ids)
+	}
+	if len(listings) != 1 || listings[0].DiscogsID != 1 {
+		t.Errorf("keepers = %+v, want only release 1", listings)
+	}
+}
+
+func TestProcessPageTagsNewListings(t *testing.T) {
+	fixture := &inventoryFixture{items: 4}
+	s := newFixtureScraper(t, fixture, 4)
+
+	listings, ids, stop, err := s.processPage(context.Background(), "seller", 1, map[int]bool{3: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop || len(ids) != 4 {
+		t.Errorf("stop=%v ids=%v, want the whole page without stopping", stop, ids)
+	}
+	if len(listings) != 2 || !listings[0].IsNew || listings[1].IsNew {
+		t.Errorf("keepers = %+v, want release 1 new and release 3 not", listings)
+	}
+}
+
+func TestNewReleasesAreCountedSeparatelyFromKeepers(t *testing.T) {
+	fixture := &inventoryFixture{items: 7}
+	s := newFixtureScraper(t, fixture, 10)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRecords != 4 || result.NewRecords != 7 || result.NewKeepers != 4 {
+		t.Errorf("first scrape: keepers=%d new=%d new keepers=%d, want 4, 7 and 4",
+			result.TotalRecords, result.NewRecords, result.NewKeepers)
+	}
+
+	// Two more releases are listed; only the odd one is a keeper
+	fixture.items = 9
+	result, err = s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRecords != 5 || result.NewRecords != 2 || result.NewKeepers != 1 {
+		t.Errorf("second scrape: keepers=%d new=%d new keepers=%d, want 5, 2 and 1",
+			result.TotalRecords, result.NewRecords, result.NewKeepers)
+	}
+	for _, listing := range result.Listings {
+		if listing.IsNew != (listing.DiscogsID == 9) {
+			t.Errorf("release %d: IsNew = %v", listing.DiscogsID, listing.IsNew)
+		}
+	}
+}
+
+func TestPageDelayIsApplied(t *testing.T) {
+	fixture := &inventoryFixture{items: 3}
+	s := newFixtureScraper(t, fixture, 1)
+	s.config.PageDelay = 100 * time.Millisecond
+
+	start := time.Now()
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PagesScanned != 3 {
+		t.Fatalf("scanned %d pages, want 3", result.PagesScanned)
+	}
+	// Pauses come between pages, not after the last one
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("three pages took %v, want at least two page delays", elapsed)
+	}
+}
+
+func TestEmptyInventoryIsNotAnError(t *testing.T) {
+	fixture := &inventoryFixture{items: 0}
+	s := newFixtureScraper(t, fixture, 4)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.TotalRecords != 0 || result.Note != NoteEmptyInventory {
+		t.Errorf("unexpected result: success=%v total=%d note=%q", result.Success, result.TotalRecords, result.Note)
+	}
+	if !result.CoversInventory() {
+		t.Error("a full scan of an empty inventory covers it")
+	}
+}
+
+func TestMissingInventoryIsNotAnError(t *testing.T) {
+	fixture := &inventoryFixture{items: 4}
+	fixture.fail = func(page, attempt int) (*http.Response, error) {
+		return statusResponse(http.StatusNotFound), nil
+	}
+	s := newFixtureScraper(t, fixture, 4)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || len(result.Listings) != 0 || result.Note != NoteNoPublicInventory {
+		t.Errorf("unexpected result: success=%v listings=%d note=%q", result.Success, len(result.Listings), result.Note)
+	}
+	if result.CoversInventory() {
+		t.Error("a missing inventory must not be reconciled")
+	}
+	if len(fixture.requests) != 1 {
+		t.Errorf("requested pages %v, want only the page count", fixture.requests)
+	}
+}
+
+func TestTimedOutPagesAreRetried(t *testing.T) {
+	fixture := &inventoryFixture{items: 2}
+	fixture.fail = func(page, attempt int) (*http.Response, error) {
+		// The first request is the page count; the next two page fetches time out
+		if page == 1 && attempt >= 2 && attempt <= 3 {
+			return nil, timeoutError{}
+		}
+		return nil, nil
+	}
+	s := newFixtureScraper(t, fixture, 4)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FailedPages != 0 || result.TotalRecords != 1 {
+		t.Errorf("failed=%d total=%d, want 0 and 1", result.FailedPages, result.TotalRecords)
+	}
+	if fixture.requests[1] != 4 {
+		t.Errorf("page 1 requested %d times, want 4", fixture.requests[1])
+	}
+}
+
+func TestHungPageIsSkippedAfterPageTimeout(t *testing.T) {
+	fixture := &inventoryFixture{items: 6}
+	s := newFixtureScraper(t, fixture, 2)
+	s.config.PageTimeout = 50 * time.Millisecond
+
+	// Page 2 never answers; only the page deadline ends the request
+	hung := 0
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Query().Get("page") == "2" {
+			hung++
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		return fixture.RoundTrip(r)
+	})}
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FailedPages != 1 || result.TimedOutPages != 1 {
+		t.Errorf("failed=%d timed out=%d, want 1 and 1", result.FailedPages, result.TimedOutPages)
+	}
+	if result.TotalRecords != 2 || result.PagesScanned != 3 {
+		t.Errorf("total=%d scanned=%d, want the other pages' 2 keepers from 3 pages",
+			result.TotalRecords, result.PagesScanned)
+	}
+	if hung != 1 {
+		t.Errorf("page 2 requested %d times, want no retries once its time ran out", hung)
+	}
+}
+
+func TestFailedPageBlocksReconciliation(t *testing.T) {
+	fixture := &inventoryFixture{items: 6}
+	fixture.fail = func(page, attempt int) (*http.Response, error) {
+		if page == 2 {
+			return statusResponse(http.StatusInternalServerError), nil
+		}
+		return nil, nil
+	}
+	s := newFixtureScraper(t, fixture, 4)
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FailedPages != 1 {
+		t.Errorf("failed pages = %d, want 1", result.FailedPages)
+	}
+	if result.CoversInventory() {
+		t.Error("a scan with a failed page must not cover the inventory")
+	}
+}
+
+func TestInventoryBlockedMidScrapeKeepsEarlierPages(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusUnauthorized} {
+		fixture := &inventoryFixture{items: 12}
+		fixture.fail = func(page, attempt int) (*http.Response, error) {
+			if page >= 2 {
+				return statusResponse(status), nil
+			}
+			return nil, nil
+		}
+		s := newFixtureScraper(t, fixture, 4)
+
+		var saved []ParsedListing
+		result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{
+			FullScan: true,
+			OnPage:   func(listings []ParsedListing) { saved = append(saved, listings...) },
+		})
+		if err != nil {
+			t.Fatalf("status %d: %v", status, err)
+		}
+		if !result.Success || result.Note != NoteInventoryNotAccessible || !result.InventoryNotAccessible {
+			t.Errorf("status %d: success=%v note=%q", status, result.Success, result.Note)
+		}
+		// Page 1 has releases 1-4, of which 1 and 3 are keepers
+		if result.TotalRecords != 2 || len(saved) != 2 {
+			t.Errorf("status %d: kept %d listings, saved %d, want page 1's 2", status, result.TotalRecords, len(saved))
+		}
+		if result.BlockedAtPage != 2 || result.PagesScanned != 1 || result.FailedPages != 0 {
+			t.Errorf("status %d: blocked at %d, scanned %d, failed %d, want 2, 1 and 0",
+				status, result.BlockedAtPage, result.PagesScanned, result.FailedPages)
+		}
+		if fixture.requests[3] != 0 {
+			t.Errorf("status %d: page 3 was requested after page 2 was refused", status)
+		}
+		if result.CoversInventory() {
+			t.Errorf("status %d: an inaccessible inventory must not be reconciled", status)
+		}
+	}
+}
+
+func TestMaxListingsStopsAfterCurrentPage(t *testing.T) {
+	// Each page of 4 releases holds 2 keepers
+	fixture := &inventoryFixture{items: 20}
+	s := newFixtureScraper(t, fixture, 4)
+	s.config.MaxListings = 3
+
+	result, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.ListingCapReached {
+		t.Error("the cap was reached but not reported")
+	}
+	// Page 2 reaches the cap and is kept whole
+	if result.TotalRecords != 4 || result.PagesScanned != 2 {
+		t.Errorf("kept %d listings from %d pages, want 4 from 2", result.TotalRecords, result.PagesScanned)
+	}
+	if fixture.requests[3] != 0 {
+		t.Error("page 3 was requested after the cap was reached")
+	}
+	if result.CoversInventory() {
+		t.Error("a capped scan must not cover the inventory")
+	}
+	// Tracking is left alone, so the next scrape doesn't stop before the
+	// pages the cap left unread
+	if tracked, err := GetUserInventory("seller"); err != nil || len(tracked.RecordIDs) != 0 {
+		t.Errorf("tracking after a capped scan = %v, %v; want none", tracked, err)
+	}
+
+	// Without a cap every page is scanned
+	s.config.MaxListings = 0
+	result, err = s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ListingCapReached || result.PagesScanned != 5 {
+		t.Errorf("uncapped scan: cap reached %v, scanned %d pages, want false and 5", result.ListingCapReached, result.PagesScanned)
+	}
+	if tracked, err := GetUserInventory("seller"); err != nil || len(tracked.RecordIDs) != 20 {
+		t.Errorf("tracking after a full scan = %v, %v; want 20 releases", tracked, err)
+	}
+}
+
+func TestRateLimitStateTracksDiscogsQuota(t *testing.T) {
+	fixture := &inventoryFixture{items: 6}
+	s := newFixtureScraper(t, fixture, 4)
+
+	if state := s.RateLimitState(); state.Discogs != nil || state.CurrentWindow != 0 {
+		t.Errorf("before any request: %+v", state)
+	}
+
+	if _, err := s.GetInventoryWithOptions("seller", ScrapeOptions{FullScan: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The page count request, then pages 1 and 2; page 2's is the last response
+	state := s.RateLimitState()
+	if state.CurrentWindow != 3 || state.RequestsLastMinute != 3 {
+		t.Errorf("window=%d last minute=%d, want 3 and 3", state.CurrentWindow, state.RequestsLastMinute)
+	}
+	if state.Discogs == nil {
+		t.Fatal("expected the Discogs quota from the response headers")
+	}
+	if state.Discogs.Limit != 60 || state.Discogs.Used != 1 || state.Discogs.Remaining != 59 {
+		t.Errorf("quota = %+v, want limit 60, used 1, remaining 59", *state.Discogs)
+	}
+}
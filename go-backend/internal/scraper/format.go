@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseFormats reads a release's format field. The marketplace returns it as
+// a string such as "LP, Album, RE" or a list of such strings; release data
+// nests objects with name, qty, descriptions and text. Both shapes are
+// normalised by newFormat, so the same format gives the same descriptions
+// whichever way it arrives.
+func parseFormats(data interface{}) []DiscogsFormat {
+	switch v := data.(type) {
+	case string:
+		return []DiscogsFormat{parseFormatString(v)}
+	case []string:
+		formats := make([]DiscogsFormat, 0, len(v))
+		for _, item := range v {
+			formats = append(formats, parseFormatString(item))
+		}
+		return formats
+	case []DiscogsFormat:
+		formats := make([]DiscogsFormat, 0, len(v))
+		for _, f := range v {
+			formats = append(formats, newFormat(f.Name, f.Qty, f.Text, f.Descriptions))
+		}
+		return formats
+	case []interface{}:
+		formats := make([]DiscogsFormat, 0, len(v))
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				formats = append(formats, parseFormatString(item))
+			case map[string]interface{}:
+				formats = append(formats, parseFormatObject(item))
+			}
+		}
+		return formats
+	case map[string]interface{}:
+		return []DiscogsFormat{parseFormatObject(v)}
+	}
+	return []DiscogsFormat{}
+}
+
+// formatNames are the Discogs format names, which lead a format string when
+// it has one. Marketplace strings usually don't: "LP, Album, RE" is all
+// descriptions, as in {"name": "Vinyl", "descriptions": ["LP", "Album", ...]}.
+var formatNames = map[string]bool{
+	"vinyl": true, "acetate": true, "flexi-disc": true, "lathe cut": true,
+	"shellac": true, "cd": true, "cdr": true, "sacd": true, "dvd": true,
+	"dvdr": true, "blu-ray": true, "cassette": true, "8-track cartridge": true,
+	"reel-to-reel": true, "minidisc": true, "dat": true, "vhs": true,
+	"laserdisc": true, "box set": true, "all media": true, "file": true,
+}
+
+func parseFormatString(s string) DiscogsFormat {
+	parts := strings.Split(s, ",")
+	if first := strings.TrimSpace(parts[0]); formatNames[strings.ToLower(first)] {
+		return newFormat(first, "", "", parts[1:])
+	}
+	return newFormat("", "", "", parts)
+}
+
+func parseFormatObject(m map[string]interface{}) DiscogsFormat {
+	name, _ := m["name"].(string)
+	text, _ := m["text"].(string)
+	// qty is a string in the API but tolerate a number
+	var qty string
+	if v, ok := m["qty"]; ok && v != nil {
+		qty = fmt.Sprint(v)
+	}
+	return newFormat(name, qty, text, interfaceToStringSlice(m["descriptions"]))
+}
+
+// newFormat builds a format from either payload shape, trimming every field
+// and dropping empty descriptions
+func newFormat(name, qty, text string, descriptions []string) DiscogsFormat {
+	format := DiscogsFormat{
+		Name: strings.TrimSpace(name),
+		Qty:  strings.TrimSpace(qty),
+		Text: strings.TrimSpace(text),
+	}
+	for _, d := range descriptions {
+		if d = strings.TrimSpace(d); d != "" {
+			format.Descriptions = append(format.Descriptions, d)
+		}
+	}
+	return format
+}
+
+// String joins the name and descriptions as the marketplace does, e.g.
+// "Vinyl, LP, Album"
+func (f DiscogsFormat) String() string {
+	parts := make([]string, 0, len(f.Descriptions)+1)
+	if f.Name != "" {
+		parts = append(parts, f.Name)
+	}
+	parts = append(parts, f.Descriptions...)
+	return strings.Join(parts, ", ")
+}
+
+// formatStrings returns one string per format for the keeper's format check
+func formatStrings(formats []DiscogsFormat) []string {
+	result := make([]string, len(formats))
+	for i, f := range formats {
+		result[i] = f.String()
+	}
+	return result
+}
+
+// formatDescriptions returns every description across the formats, free text
+// included, without duplicates
+func formatDescriptions(formats []DiscogsFormat) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	add := func(d string) {
+		key := strings.ToLower(d)
+		if d == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		result = append(result, d)
+	}
+	for _, f := range formats {
+		for _, d := range f.Descriptions {
+			add(d)
+		}
+		add(f.Text)
+	}
+	return result
+}
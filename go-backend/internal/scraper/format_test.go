@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      string
+		formats      []string
+		descriptions []string
+	}{
+		{"marketplace string", `"LP, Album, RE"`, []string{"LP, Album, RE"}, []string{"LP", "Album", "RE"}},
+		{"string list", `["LP, Album", "7\", Single"]`, []string{"LP, Album", "7\", Single"}, []string{"LP", "Album", "7\"", "Single"}},
+		{"named string", `"Vinyl, LP, Album"`, []string{"Vinyl, LP, Album"}, []string{"LP", "Album"}},
+		{"release objects", `[
+			{"name": "Vinyl", "qty": "2", "descriptions": ["LP", "Album", "Reissue"], "text": "180g"},
+			{"name": "All Media", "qty": "1", "descriptions": ["Limited Edition", "album"]}
+		]`, []string{"Vinyl, LP, Album, Reissue", "All Media, Limited Edition, album"},
+			[]string{"LP", "Album", "Reissue", "180g", "Limited Edition"}},
+		{"object without descriptions", `[{"name": "CD", "qty": 1}]`, []string{"CD"}, []string{}},
+		{"missing", `null`, []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		var release DiscogsRelease
+		if err := json.Unmarshal([]byte(`{"format": `+tt.payload+`}`), &release); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		formats := parseFormats(release.Format)
+		if got := formatStrings(formats); !reflect.DeepEqual(got, tt.formats) {
+			t.Errorf("%s: formats = %q, want %q", tt.name, got, tt.formats)
+		}
+		if got := formatDescriptions(formats); !reflect.DeepEqual(got, tt.descriptions) {
+			t.Errorf("%s: descriptions = %q, want %q", tt.name, got, tt.descriptions)
+		}
+	}
+
+	var release DiscogsRelease
+	json.Unmarshal([]byte(`{"format": [{"name": "Vinyl", "qty": "2", "descriptions": ["LP"]}]}`), &release)
+	if got := parseFormats(release.Format)[0]; got.Qty != "2" || got.Name != "Vinyl" {
+		t.Errorf("parsed %+v, want two Vinyl", got)
+	}
+}
+
+func TestParseFormatsShapesAgree(t *testing.T) {
+	// The same format as a marketplace string and as a release object
+	shapes := []struct {
+		payload string
+		name    string
+	}{
+		{`"Vinyl, LP, Album, Reissue"`, "Vinyl"},
+		{`[{"name": "Vinyl", "qty": "1", "descriptions": [" LP", "Album ", "Reissue"]}]`, "Vinyl"},
+		{`"LP, Album, Reissue"`, ""},
+		{`["LP, Album, Reissue"]`, ""},
+	}
+	want := []string{"LP", "Album", "Reissue"}
+
+	for _, shape := range shapes {
+		var release DiscogsRelease
+		if err := json.Unmarshal([]byte(`{"format": `+shape.payload+`}`), &release); err != nil {
+			t.Fatalf("%s: %v", shape.payload, err)
+		}
+		formats := parseFormats(release.Format)
+		if len(formats) != 1 {
+			t.Fatalf("%s: %d formats, want 1", shape.payload, len(formats))
+		}
+		if formats[0].Name != shape.name {
+			t.Errorf("%s: name = %q, want %q", shape.payload, formats[0].Name, shape.name)
+		}
+		if !reflect.DeepEqual(formats[0].Descriptions, want) {
+			t.Errorf("%s: descriptions = %q, want %q", shape.payload, formats[0].Descriptions, want)
+		}
+	}
+}
+
+func TestKeeperFormatDescriptions(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.Keeper.RequiredDescriptions = []string{"album"}
+	config.Keeper.ExcludedDescriptions = []string{"Promo"}
+	s := &Scraper{config: config}
+
+	listing := func(format string) DiscogsListing {
+		var l DiscogsListing
+		if err := json.Unmarshal([]byte(`{
+			"condition": "Very Good Plus (VG+)",
+			"price": {"value": 20, "currency": "USD"},
+			"release": {"format": `+format+`, "stats": {"community": {"in_wantlist": 10, "in_collection": 5}}}
+		}`), &l); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	if !s.isKeeper(listing(`[{"name": "Vinyl", "qty": "1", "descriptions": ["LP", "Album", "Reissue"]}]`)) {
+		t.Error("LP album rejected")
+	}
+	if s.isKeeper(listing(`[{"name": "Vinyl", "qty": "1", "descriptions": ["LP", "Album", "Promo"]}]`)) {
+		t.Error("promo accepted")
+	}
+	if s.isKeeper(listing(`[{"name": "Vinyl", "qty": "1", "descriptions": ["LP", "Compilation"]}]`)) {
+		t.Error("compilation accepted without Album")
+	}
+	if !s.isKeeper(listing(`"LP, Album"`)) {
+		t.Error("marketplace format string rejected")
+	}
+	if got := s.KeeperRejections()[RejectDescription]; got != 2 {
+		t.Errorf("%d description rejections, want 2", got)
+	}
+
+	parsed, err := s.parseListing(listing(`[{"name": "Vinyl", "qty": "1", "descriptions": ["LP", "Album"], "text": "180g"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"LP", "Album", "180g"}; !reflect.DeepEqual(parsed.FormatDescriptions, want) {
+		t.Errorf("FormatDescriptions = %q, want %q", parsed.FormatDescriptions, want)
+	}
+}
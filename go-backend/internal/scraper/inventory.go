@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -12,13 +13,17 @@ const InventoryFileName = "user_inventories.json"
 // InventoryFile represents the structure of the inventory JSON file
 type InventoryFile map[string]UserInventoryData
 
-// LoadInventoryJSON loads the inventory tracking file
+// inventoryMu serializes read-modify-write cycles of the inventory file, so
+// concurrent scrapes of different users don't drop each other's updates
+var inventoryMu sync.Mutex
+
+// LoadInventoryJSON loads the inventory tracking file from the data directory
 func LoadInventoryJSON() (InventoryFile, error) {
-	if _, err := os.Stat(InventoryFileName); os.IsNotExist(err) {
+	if _, err := os.Stat(dataPath(InventoryFileName)); os.IsNotExist(err) {
 		return make(InventoryFile), nil
 	}
 
-	data, err := os.ReadFile(InventoryFileName)
+	data, err := os.ReadFile(dataPath(InventoryFileName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read inventory file: %w", err)
 	}
@@ -31,14 +36,16 @@ func LoadInventoryJSON() (InventoryFile, error) {
 	return inventory, nil
 }
 
-// SaveInventoryJSON saves the inventory tracking file
+// SaveInventoryJSON saves the inventory tracking file to the data directory.
+// It replaces the whole file, so callers that loaded it first should hold
+// inventoryMu throughout, as UpdateUserInventory does.
 func SaveInventoryJSON(inventory InventoryFile) error {
 	data, err := json.MarshalIndent(inventory, "", "    ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal inventory: %w", err)
 	}
 
-	if err := os.WriteFile(InventoryFileName, data, 0644); err != nil {
+	if err := writeDataFile(InventoryFileName, data, 0644); err != nil {
 		return fmt.Errorf("failed to write inventory file: %w", err)
 	}
 
@@ -47,6 +54,9 @@ func SaveInventoryJSON(inventory InventoryFile) error {
 
 // UpdateUserInventory updates the inventory tracking for a specific user
 func UpdateUserInventory(username string, recordIDs []int) error {
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+
 	inventory, err := LoadInventoryJSON()
 	if err != nil {
 		return fmt.Errorf("failed to load inventory: %w", err)
@@ -110,6 +120,31 @@ func GetUserInventory(username string) (*UserInventoryData, error) {
 	}, nil
 }
 
+// ClearUserInventory forgets the record IDs tracked for a user, so their
+// next scrape treats every listing as new. It returns how many IDs were
+// cleared, zero for a user with no tracking.
+func ClearUserInventory(username string) (int, error) {
+	inventoryMu.Lock()
+	defer inventoryMu.Unlock()
+
+	inventory, err := LoadInventoryJSON()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load inventory: %w", err)
+	}
+
+	data, exists := inventory[username]
+	if !exists {
+		return 0, nil
+	}
+
+	delete(inventory, username)
+	if err := SaveInventoryJSON(inventory); err != nil {
+		return 0, err
+	}
+
+	return len(data.RecordIDs), nil
+}
+
 // HasSeenRecord checks if a record ID has been seen before for a user
 func HasSeenRecord(username string, recordID int) (bool, error) {
 	userData, err := GetUserInventory(username)
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClearUserInventory(t *testing.T) {
+	inTempDir(t)
+	if err := UpdateUserInventory("alice", []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateUserInventory("bob", []int{4}); err != nil {
+		t.Fatal(err)
+	}
+
+	cleared, err := ClearUserInventory("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleared != 3 {
+		t.Errorf("cleared %d IDs, want 3", cleared)
+	}
+
+	alice, err := GetUserInventory("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alice.RecordIDs) != 0 {
+		t.Errorf("alice still tracks %v", alice.RecordIDs)
+	}
+	bob, err := GetUserInventory("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bob.RecordIDs, []int{4}) {
+		t.Errorf("bob tracks %v, want other users untouched", bob.RecordIDs)
+	}
+
+	if cleared, err := ClearUserInventory("alice"); err != nil || cleared != 0 {
+		t.Errorf("clearing again = %d, %v; want 0, nil", cleared, err)
+	}
+}
+
+func TestDataDir(t *testing.T) {
+	inTempDir(t)
+	dir := filepath.Join(t.TempDir(), "state")
+	SetDataDir(dir)
+	t.Cleanup(func() { SetDataDir("") })
+
+	if err := UpdateUserInventory("alice", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveTokens("token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{InventoryFileName, TokenFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not in the data directory: %v", name, err)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s written to the working directory", name)
+		}
+	}
+
+	tokens, err := LoadTokens()
+	if err != nil || tokens == nil || tokens.Token != "token" {
+		t.Errorf("LoadTokens = %+v, %v", tokens, err)
+	}
+	alice, err := GetUserInventory("alice")
+	if err != nil || !reflect.DeepEqual(alice.RecordIDs, []int{1}) {
+		t.Errorf("GetUserInventory = %+v, %v", alice, err)
+	}
+}
+
+func TestUpdateUserInventoryConcurrent(t *testing.T) {
+	inTempDir(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := UpdateUserInventory(fmt.Sprintf("user%d", i), []int{i}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	inventory, err := LoadInventoryJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inventory) != 20 {
+		t.Errorf("inventory tracks %d users, want 20", len(inventory))
+	}
+}
@@ -0,0 +1,255 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"discogs-api/internal/condition"
+)
+
+// WantsComparison is how a release's wants are compared with its haves
+type WantsComparison string
+
+const (
+	// WantsGreater requires more wants than haves (the default)
+	WantsGreater WantsComparison = "greater"
+	// WantsGreaterOrEqual also accepts releases wanted exactly as often as owned
+	WantsGreaterOrEqual WantsComparison = "greater_or_equal"
+	// WantsMinRatio requires wants/haves to reach MinWantsRatio
+	WantsMinRatio WantsComparison = "ratio"
+	// WantsAny skips the wants/haves check
+	WantsAny WantsComparison = "any"
+)
+
+// KeeperCriteria describes which listings are worth keeping
+type KeeperCriteria struct {
+	// Format must appear in at least one of the release's format strings
+	Format string `json:"format"`
+	// Conditions lists the accepted media conditions
+	Conditions []string `json:"conditions"`
+	// WantsComparison decides how wants are compared with haves; empty means WantsGreater
+	WantsComparison WantsComparison `json:"wants_comparison"`
+	// MinWantsRatio is the minimum wants/haves ratio for WantsMinRatio
+	MinWantsRatio float64 `json:"min_wants_ratio,omitempty"`
+	// RequiredDescriptions must all appear among the release's format
+	// descriptions, e.g. "Album"; matching ignores case
+	RequiredDescriptions []string `json:"required_descriptions,omitempty"`
+	// ExcludedDescriptions rejects releases with any of these descriptions,
+	// e.g. "Promo"
+	ExcludedDescriptions []string `json:"excluded_descriptions,omitempty"`
+	// MinSellerRating rejects listings from sellers whose positive feedback
+	// percentage is below it, or who have no feedback; 0 means no limit
+	MinSellerRating float64 `json:"min_seller_rating,omitempty"`
+	// MaxKeeperPrice rejects listings priced above it, in the listing's own
+	// currency; 0 means no limit
+	MaxKeeperPrice float64 `json:"max_keeper_price,omitempty"`
+	// AlwaysKeep and NeverKeep are Discogs release IDs that are kept, or
+	// rejected, whatever the other criteria say, e.g. a personal grail or a
+	// known bootleg
+	AlwaysKeep []int `json:"always_keep,omitempty"`
+	NeverKeep  []int `json:"never_keep,omitempty"`
+}
+
+// RejectReason names the check a listing failed to be a keeper
+type RejectReason string
+
+const (
+	RejectFormat      RejectReason = "format"
+	RejectDescription RejectReason = "description"
+	RejectCondition   RejectReason = "condition"
+	RejectWants       RejectReason = "wants"
+	RejectPrice       RejectReason = "price"
+	RejectSeller      RejectReason = "seller"
+	// RejectNeverKeep means the release is on the NeverKeep list
+	RejectNeverKeep RejectReason = "never_keep"
+)
+
+// KeeperRule names what decided whether a listing is a keeper
+type KeeperRule string
+
+const (
+	KeptAlways    KeeperRule = "always_keep"
+	RejectedNever KeeperRule = "never_keep"
+	// DecidedByCriteria means the release is on neither list and the
+	// format, condition, wants and other checks decided
+	DecidedByCriteria KeeperRule = "criteria"
+)
+
+// KeeperCandidate is what the keeper criteria look at in a listing
+type KeeperCandidate struct {
+	// ReleaseID is matched against the AlwaysKeep and NeverKeep lists
+	ReleaseID int
+	// Formats holds one string per release format, e.g. "Vinyl, LP, Album"
+	Formats      []string
+	Descriptions []string
+	Condition    string
+	Wants        int
+	Haves        int
+	Price        float64
+	// SellerRating is the seller's positive feedback percentage; nil when
+	// the seller has no feedback
+	SellerRating *float64
+}
+
+// Validate checks the wants comparison is one the evaluator understands and
+// the price ceiling and seller rating are in range
+func (k KeeperCriteria) Validate() error {
+	if k.MaxKeeperPrice < 0 {
+		return fmt.Errorf("keeper max price %.2f is negative", k.MaxKeeperPrice)
+	}
+	if k.MinSellerRating < 0 || k.MinSellerRating > 100 {
+		return fmt.Errorf("keeper min seller rating %.1f is not a percentage", k.MinSellerRating)
+	}
+	for _, id := range k.AlwaysKeep {
+		if containsID(k.NeverKeep, id) {
+			return fmt.Errorf("release %d is on both the always-keep and never-keep lists", id)
+		}
+	}
+
+	switch k.WantsComparison {
+	case "", WantsGreater, WantsGreaterOrEqual, WantsAny:
+		return nil
+	case WantsMinRatio:
+		if k.MinWantsRatio <= 0 {
+			return fmt.Errorf("keeper wants comparison %q needs a positive minimum ratio", k.WantsComparison)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown keeper wants comparison %q", k.WantsComparison)
+}
+
+// DefaultKeeperCriteria returns the rule used at scrape time: an LP in good
+// condition that more people want than have
+func DefaultKeeperCriteria() KeeperCriteria {
+	return KeeperCriteria{
+		Format: "LP",
+		Conditions: []string{
+			"Near Mint (NM or M-)",
+			"Very Good Plus (VG+)",
+			"Very Good (VG)",
+			"Good Plus (G+)",
+		},
+		WantsComparison: WantsGreater,
+	}
+}
+
+// EvaluateKeeper reports whether a listing meets the criteria
+func EvaluateKeeper(criteria KeeperCriteria, candidate KeeperCandidate) bool {
+	reason, _ := keeperRejection(criteria, candidate)
+	return reason == ""
+}
+
+// keeperRule returns what decides whether candidate is a keeper: one of the
+// override lists, which are consulted first, or the other criteria
+func keeperRule(criteria KeeperCriteria, candidate KeeperCandidate) KeeperRule {
+	switch {
+	case containsID(criteria.NeverKeep, candidate.ReleaseID):
+		return RejectedNever
+	case containsID(criteria.AlwaysKeep, candidate.ReleaseID):
+		return KeptAlways
+	}
+	return DecidedByCriteria
+}
+
+// keeperRejection returns which check a listing fails and why, or "" if it
+// passes
+func keeperRejection(criteria KeeperCriteria, candidate KeeperCandidate) (RejectReason, string) {
+	switch keeperRule(criteria, candidate) {
+	case RejectedNever:
+		return RejectNeverKeep, fmt.Sprintf("Release %d is on the never-keep list", candidate.ReleaseID)
+	case KeptAlways:
+		return "", ""
+	}
+
+	mediaCondition, wants, haves, price := candidate.Condition, candidate.Wants, candidate.Haves, candidate.Price
+
+	if criteria.Format != "" {
+		matched := false
+		for _, f := range candidate.Formats {
+			if strings.Contains(f, criteria.Format) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return RejectFormat, fmt.Sprintf("Not %s format", criteria.Format)
+		}
+	}
+
+	for _, required := range criteria.RequiredDescriptions {
+		if !hasDescription(candidate.Descriptions, required) {
+			return RejectDescription, fmt.Sprintf("Not described as %s", required)
+		}
+	}
+	for _, excluded := range criteria.ExcludedDescriptions {
+		if hasDescription(candidate.Descriptions, excluded) {
+			return RejectDescription, fmt.Sprintf("Described as %s", excluded)
+		}
+	}
+
+	accepted := false
+	for _, c := range criteria.Conditions {
+		if condition.Normalize(c) == condition.Normalize(mediaCondition) {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return RejectCondition, fmt.Sprintf("Poor condition (%s)", mediaCondition)
+	}
+
+	switch criteria.WantsComparison {
+	case "", WantsGreater:
+		if wants <= haves {
+			return RejectWants, fmt.Sprintf("Wants (%d) not greater than haves (%d)", wants, haves)
+		}
+	case WantsGreaterOrEqual:
+		if wants < haves {
+			return RejectWants, fmt.Sprintf("Wants (%d) less than haves (%d)", wants, haves)
+		}
+	case WantsMinRatio:
+		// Releases nobody owns divide by one, as models.Record.WantsToHaves does
+		ratio := float64(wants)
+		if haves > 0 {
+			ratio = float64(wants) / float64(haves)
+		}
+		if ratio < criteria.MinWantsRatio {
+			return RejectWants, fmt.Sprintf("Wants/haves ratio %.2f below %.2f", ratio, criteria.MinWantsRatio)
+		}
+	}
+
+	if criteria.MaxKeeperPrice > 0 && price > criteria.MaxKeeperPrice {
+		return RejectPrice, fmt.Sprintf("Price %.2f above %.2f", price, criteria.MaxKeeperPrice)
+	}
+
+	if criteria.MinSellerRating > 0 {
+		if candidate.SellerRating == nil {
+			return RejectSeller, "Seller has no feedback"
+		}
+		if *candidate.SellerRating < criteria.MinSellerRating {
+			return RejectSeller, fmt.Sprintf("Seller rating %.1f%% below %.1f%%", *candidate.SellerRating, criteria.MinSellerRating)
+		}
+	}
+
+	return "", ""
+}
+
+// hasDescription reports whether descriptions contains want, ignoring case
+func hasDescription(descriptions []string, want string) bool {
+	for _, d := range descriptions {
+		if strings.EqualFold(d, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsID reports whether ids holds id
+func containsID(ids []int, id int) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
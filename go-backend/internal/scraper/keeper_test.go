@@ -0,0 +1,291 @@
+package scraper
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// vgLP is a VG condition LP with the given community stats and price
+func vgLP(wants, haves int, price float64) KeeperCandidate {
+	return KeeperCandidate{Formats: []string{"LP"}, Condition: "Very Good (VG)", Wants: wants, Haves: haves, Price: price}
+}
+
+func TestEvaluateKeeper(t *testing.T) {
+	criteria := DefaultKeeperCriteria()
+
+	tests := []struct {
+		name      string
+		format    []string
+		condition string
+		wants     int
+		haves     int
+		reason    string
+	}{
+		{"keeper", []string{"Vinyl", "LP, Album"}, "Very Good Plus (VG+)", 100, 50, ""},
+		{"abbreviated condition", []string{"LP"}, "VG+", 100, 50, ""},
+		{"not an LP", []string{"CD, Album"}, "Very Good Plus (VG+)", 100, 50, "Not LP format"},
+		{"no formats", nil, "Very Good Plus (VG+)", 100, 50, "Not LP format"},
+		{"poor condition", []string{"LP"}, "Fair (F)", 100, 50, "Poor condition (Fair (F))"},
+		{"wants equal haves", []string{"LP"}, "Near Mint (NM or M-)", 50, 50, "Wants (50) not greater than haves (50)"},
+		{"wants below haves", []string{"LP"}, "Good Plus (G+)", 10, 50, "Wants (10) not greater than haves (50)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := KeeperCandidate{Formats: tt.format, Condition: tt.condition, Wants: tt.wants, Haves: tt.haves, Price: 10}
+			if _, got := keeperRejection(criteria, candidate); got != tt.reason {
+				t.Errorf("keeperRejection() = %q, want %q", got, tt.reason)
+			}
+			if got := EvaluateKeeper(criteria, candidate); got != (tt.reason == "") {
+				t.Errorf("EvaluateKeeper() = %v, want %v", got, tt.reason == "")
+			}
+		})
+	}
+}
+
+func TestEvaluateKeeperWantsComparison(t *testing.T) {
+	tests := []struct {
+		comparison WantsComparison
+		minRatio   float64
+		wants      int
+		haves      int
+		want       bool
+	}{
+		{WantsGreater, 0, 51, 50, true},
+		{WantsGreater, 0, 50, 50, false},
+		{"", 0, 50, 50, false},
+		{WantsGreaterOrEqual, 0, 50, 50, true},
+		{WantsGreaterOrEqual, 0, 49, 50, false},
+		{WantsMinRatio, 0.8, 40, 50, true},
+		{WantsMinRatio, 0.8, 39, 50, false},
+		{WantsMinRatio, 1, 50, 50, true},
+		{WantsMinRatio, 2, 3, 0, true},
+		{WantsAny, 0, 1, 500, true},
+	}
+
+	for _, tt := range tests {
+		criteria := DefaultKeeperCriteria()
+		criteria.WantsComparison = tt.comparison
+		criteria.MinWantsRatio = tt.minRatio
+
+		if got := EvaluateKeeper(criteria, vgLP(tt.wants, tt.haves, 10)); got != tt.want {
+			t.Errorf("%s (ratio %.1f) wants=%d haves=%d: got %v, want %v",
+				tt.comparison, tt.minRatio, tt.wants, tt.haves, got, tt.want)
+		}
+	}
+}
+
+func TestKeeperCriteriaValidate(t *testing.T) {
+	criteria := DefaultKeeperCriteria()
+	if err := criteria.Validate(); err != nil {
+		t.Errorf("default criteria invalid: %v", err)
+	}
+
+	criteria.WantsComparison = WantsMinRatio
+	if criteria.Validate() == nil {
+		t.Error("ratio mode without a minimum ratio should be invalid")
+	}
+
+	criteria.WantsComparison = "sometimes"
+	if criteria.Validate() == nil {
+		t.Error("unknown comparison should be invalid")
+	}
+
+	criteria = DefaultKeeperCriteria()
+	criteria.MaxKeeperPrice = -1
+	if criteria.Validate() == nil {
+		t.Error("negative max price should be invalid")
+	}
+}
+
+func TestEvaluateKeeperMaxPrice(t *testing.T) {
+	tests := []struct {
+		maxPrice float64
+		price    float64
+		reason   RejectReason
+	}{
+		{0, 1000, ""},
+		{40, 39.99, ""},
+		{40, 40, ""},
+		{40, 40.01, RejectPrice},
+	}
+
+	for _, tt := range tests {
+		criteria := DefaultKeeperCriteria()
+		criteria.MaxKeeperPrice = tt.maxPrice
+
+		reason, detail := keeperRejection(criteria, vgLP(100, 50, tt.price))
+		if reason != tt.reason {
+			t.Errorf("max %.2f price %.2f: rejected for %q (%s), want %q",
+				tt.maxPrice, tt.price, reason, detail, tt.reason)
+		}
+	}
+
+	criteria := DefaultKeeperCriteria()
+	criteria.MaxKeeperPrice = 40
+	if _, detail := keeperRejection(criteria, vgLP(100, 50, 40.01)); detail != "Price 40.01 above 40.00" {
+		t.Errorf("price rejection = %q", detail)
+	}
+}
+
+func TestEvaluateKeeperMinSellerRating(t *testing.T) {
+	criteria := DefaultKeeperCriteria()
+	criteria.MinSellerRating = 98.5
+
+	rating := func(r float64) *float64 { return &r }
+	tests := []struct {
+		rating *float64
+		reason RejectReason
+	}{
+		{rating(100), ""},
+		{rating(98.5), ""},
+		{rating(98.4), RejectSeller},
+		{nil, RejectSeller},
+	}
+	for _, tt := range tests {
+		candidate := vgLP(100, 50, 10)
+		candidate.SellerRating = tt.rating
+		if reason, detail := keeperRejection(criteria, candidate); reason != tt.reason {
+			t.Errorf("rating %v: rejected for %q (%s), want %q", tt.rating, reason, detail, tt.reason)
+		}
+	}
+
+	criteria.MinSellerRating = 0
+	if !EvaluateKeeper(criteria, vgLP(100, 50, 10)) {
+		t.Error("unrated seller rejected without a minimum rating")
+	}
+
+	criteria.MinSellerRating = 101
+	if criteria.Validate() == nil {
+		t.Error("rating above 100% should be invalid")
+	}
+}
+
+func TestSellerRating(t *testing.T) {
+	rating := func(r float64) *float64 { return &r }
+	tests := []struct {
+		payload string
+		want    *float64
+	}{
+		{`{"rating": "99.8", "stars": 5.0, "total": 1234}`, rating(99.8)},
+		{`{"rating": 97, "total": 12}`, rating(97)},
+		{`{"rating": "0.0", "stars": 0, "total": 0}`, nil},
+		{`{"rating": "n/a", "total": 3}`, nil},
+		{`{}`, nil},
+	}
+	for _, tt := range tests {
+		var seller DiscogsSeller
+		if err := json.Unmarshal([]byte(`{"username": "seller", "stats": `+tt.payload+`}`), &seller); err != nil {
+			t.Fatalf("%s: %v", tt.payload, err)
+		}
+		got := sellerRating(seller.Stats)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: sellerRating() = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestKeeperRejections(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.Keeper.MaxKeeperPrice = 20
+	s := &Scraper{config: config}
+
+	listing := func(price float64, condition string) DiscogsListing {
+		var l DiscogsListing
+		l.Condition = condition
+		l.Price.Value = price
+		l.Release.Format = "LP, Album"
+		l.Release.Stats.Community.InWantlist = 10
+		l.Release.Stats.Community.InCollection = 5
+		return l
+	}
+
+	s.isKeeper(listing(25, "Very Good (VG)"))
+	s.isKeeper(listing(30, "Very Good (VG)"))
+	s.isKeeper(listing(10, "Poor (P)"))
+	if !s.isKeeper(listing(20, "Very Good (VG)")) {
+		t.Error("listing at the max price rejected")
+	}
+
+	got := s.KeeperRejections()
+	if got[RejectPrice] != 2 || got[RejectCondition] != 1 || len(got) != 2 {
+		t.Errorf("KeeperRejections() = %v, want 2 price and 1 condition", got)
+	}
+}
+
+func TestKeeperOverrides(t *testing.T) {
+	criteria := DefaultKeeperCriteria()
+	criteria.AlwaysKeep = []int{100}
+	criteria.NeverKeep = []int{200}
+
+	// A grail in poor condition and wanted by nobody is still kept
+	grail := KeeperCandidate{ReleaseID: 100, Formats: []string{"CD"}, Condition: "Poor (P)", Wants: 0, Haves: 50}
+	if !EvaluateKeeper(criteria, grail) {
+		t.Error("release on the always-keep list rejected")
+	}
+	if rule := keeperRule(criteria, grail); rule != KeptAlways {
+		t.Errorf("keeperRule() = %q, want %q", rule, KeptAlways)
+	}
+
+	// A bootleg that meets every criterion is still rejected
+	bootleg := vgLP(100, 50, 10)
+	bootleg.ReleaseID = 200
+	reason, detail := keeperRejection(criteria, bootleg)
+	if reason != RejectNeverKeep || detail != "Release 200 is on the never-keep list" {
+		t.Errorf("keeperRejection() = %q (%s), want %q", reason, detail, RejectNeverKeep)
+	}
+
+	other := vgLP(100, 50, 10)
+	other.ReleaseID = 300
+	if rule := keeperRule(criteria, other); rule != DecidedByCriteria {
+		t.Errorf("keeperRule() = %q, want %q", rule, DecidedByCriteria)
+	}
+
+	if err := criteria.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	criteria.AlwaysKeep = append(criteria.AlwaysKeep, 200)
+	if criteria.Validate() == nil {
+		t.Error("release on both lists should be invalid")
+	}
+}
+
+func TestKeeperDecisions(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.Keeper.AlwaysKeep = []int{1}
+	config.Keeper.NeverKeep = []int{2}
+	s := &Scraper{config: config}
+
+	listing := func(releaseID int, condition string) DiscogsListing {
+		var l DiscogsListing
+		l.Condition = condition
+		l.Release.ID = releaseID
+		l.Release.Format = "LP, Album"
+		l.Release.Stats.Community.InWantlist = 10
+		l.Release.Stats.Community.InCollection = 5
+		return l
+	}
+
+	if !s.isKeeper(listing(1, "Poor (P)")) {
+		t.Error("always-keep release rejected")
+	}
+	if s.isKeeper(listing(2, "Near Mint (NM or M-)")) {
+		t.Error("never-keep release kept")
+	}
+	s.isKeeper(listing(3, "Near Mint (NM or M-)"))
+	s.isKeeper(listing(4, "Poor (P)"))
+
+	got := s.KeeperDecisions()
+	want := map[KeeperRule]int{KeptAlways: 1, RejectedNever: 1, DecidedByCriteria: 2}
+	if len(got) != len(want) {
+		t.Errorf("KeeperDecisions() = %v, want %v", got, want)
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("KeeperDecisions()[%s] = %d, want %d", rule, got[rule], n)
+		}
+	}
+	if rejections := s.KeeperRejections(); rejections[RejectNeverKeep] != 1 || rejections[RejectCondition] != 1 {
+		t.Errorf("KeeperRejections() = %v, want 1 never_keep and 1 condition", rejections)
+	}
+}
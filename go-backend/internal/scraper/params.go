@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// inventorySorts are the sort keys the Discogs inventory endpoint accepts
+var inventorySorts = []string{"listed", "price", "item", "artist", "label", "catno", "audio", "status", "location"}
+
+// inventorySortOrders are the sort directions the inventory endpoint accepts
+var inventorySortOrders = []string{"asc", "desc"}
+
+// inventoryStatuses are the listing statuses the inventory endpoint accepts.
+// Only the owner of an inventory sees anything but For Sale.
+var inventoryStatuses = []string{"All", "Deleted", "Draft", "Expired", "For Sale", "Sold", "Suspended", "Violation"}
+
+// normalizeInventoryParams checks Sort, SortOrder and Status against the
+// values Discogs allows, rewriting them in the form Discogs expects. Empty
+// values are left out of requests so Discogs' defaults apply.
+func (c *ScraperConfig) normalizeInventoryParams() error {
+	var err error
+	if c.Sort, err = allowedValue("sort", c.Sort, inventorySorts); err != nil {
+		return err
+	}
+	if c.SortOrder, err = allowedValue("sort order", c.SortOrder, inventorySortOrders); err != nil {
+		return err
+	}
+	if c.Status, err = allowedValue("status", c.Status, inventoryStatuses); err != nil {
+		return err
+	}
+	return nil
+}
+
+// allowedValue returns the entry of allowed matching value, ignoring case
+func allowedValue(name, value string, allowed []string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown inventory %s %q, want one of %s", name, value, strings.Join(allowed, ", "))
+}
+
+// inventoryURL is the address of one page of a seller's inventory
+func (s *Scraper) inventoryURL(username string, page int) string {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(s.config.PerPage))
+	if s.config.Sort != "" {
+		query.Set("sort", s.config.Sort)
+	}
+	if s.config.SortOrder != "" {
+		query.Set("sort_order", s.config.SortOrder)
+	}
+	if s.config.Status != "" {
+		query.Set("status", s.config.Status)
+	}
+	return fmt.Sprintf("%s/users/%s/inventory?%s", s.config.BaseURL, url.PathEscape(username), query.Encode())
+}
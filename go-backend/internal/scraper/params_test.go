@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeInventoryParams(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.Sort = "Listed"
+	config.SortOrder = "DESC"
+	config.Status = "for sale"
+
+	if err := config.normalizeInventoryParams(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Sort != "listed" || config.SortOrder != "desc" || config.Status != "For Sale" {
+		t.Errorf("normalized to %q %q %q", config.Sort, config.SortOrder, config.Status)
+	}
+
+	for _, bad := range []func(c *ScraperConfig){
+		func(c *ScraperConfig) { c.Sort = "newest" },
+		func(c *ScraperConfig) { c.SortOrder = "descending" },
+		func(c *ScraperConfig) { c.Status = "Available" },
+	} {
+		config := DefaultConfig("", "")
+		bad(config)
+		if err := config.normalizeInventoryParams(); err == nil {
+			t.Errorf("expected an error for sort=%q order=%q status=%q", config.Sort, config.SortOrder, config.Status)
+		}
+	}
+}
+
+func TestNewScraperRejectsUnknownInventorySort(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.Sort = "popularity"
+
+	if _, err := NewScraperWithConfig(config); err == nil {
+		t.Fatal("expected an error for an unknown sort")
+	}
+}
+
+func TestInventoryURL(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.PerPage = 50
+	s := &Scraper{config: config}
+
+	u, err := url.Parse(s.inventoryURL("record shop", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.EscapedPath() != "/users/record%20shop/inventory" {
+		t.Errorf("path = %q", u.EscapedPath())
+	}
+	if got := u.Query().Encode(); got != "page=3&per_page=50" {
+		t.Errorf("default query = %q, want only paging", got)
+	}
+
+	config.Sort, config.SortOrder, config.Status = "listed", "desc", "For Sale"
+	u, _ = url.Parse(s.inventoryURL("seller", 1))
+	query := u.Query()
+	if query.Get("sort") != "listed" || query.Get("sort_order") != "desc" || query.Get("status") != "For Sale" {
+		t.Errorf("query = %v", query)
+	}
+}
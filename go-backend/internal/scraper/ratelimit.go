@@ -2,32 +2,120 @@ package scraper
 
 import (
 	"log"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// RateLimitConfig tunes a RateLimitTracker. Requests are counted in windows
+// of Window; the sleep between requests grows by SleepStep while the current
+// window plus the last History windows hold more than SlowDownAbove requests,
+// and shrinks by SleepStep while they hold fewer than SpeedUpBelow.
+type RateLimitConfig struct {
+	Window        time.Duration
+	MaxPerWindow  int // Reported only; the sleep adjustment does the limiting
+	History       int // Completed windows counted alongside the current one
+	SlowDownAbove int
+	SpeedUpBelow  int
+	SleepStep     time.Duration
+}
+
+// DefaultRateLimitConfig suits Discogs' 60 requests per minute: 15-second
+// windows of at most 15 requests, slowing down above 75% of the limit and
+// speeding up again below 67%
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Window:        15 * time.Second,
+		MaxPerWindow:  15,
+		History:       4, // 1 minute
+		SlowDownAbove: 45,
+		SpeedUpBelow:  40,
+		SleepStep:     100 * time.Millisecond,
+	}
+}
+
+// Clock tells a RateLimitTracker the time and does its sleeping, so tests
+// can move time on by hand
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
 // RateLimitTracker implements sliding window rate limiting for Discogs API
 type RateLimitTracker struct {
-	mu                  sync.Mutex
-	windows             []int
-	currentWindowStart  time.Time
-	currentCount        int
-	sleepTime           time.Duration
-	targetRate          float64
-	windowDuration      time.Duration
-	maxWindowCount      int
+	mu                 sync.Mutex
+	windows            []int
+	currentWindowStart time.Time
+	currentCount       int
+	sleepTime          time.Duration
+	targetRate         float64
+	windowDuration     time.Duration
+	maxWindowCount     int
+	history            int
+	slowDownAbove      int
+	speedUpBelow       int
+	sleepStep          time.Duration
+	clock              Clock
+	quota              *DiscogsQuota // Last quota Discogs reported
 }
 
-// NewRateLimitTracker creates a new rate limit tracker
-// Discogs allows 60 requests per minute, so we use 15-second windows with max 15 requests per window
+// NewRateLimitTracker creates a new rate limit tracker with the default
+// settings
 func NewRateLimitTracker() *RateLimitTracker {
+	return NewRateLimitTrackerWithConfig(DefaultRateLimitConfig())
+}
+
+// NewRateLimitTrackerWithConfig creates a rate limit tracker with the given
+// settings; fields left zero take their default
+func NewRateLimitTrackerWithConfig(config RateLimitConfig) *RateLimitTracker {
+	return NewRateLimitTrackerWithClock(config, realClock{})
+}
+
+// NewRateLimitTrackerWithClock is NewRateLimitTrackerWithConfig reading the
+// time from clock and sleeping through it; a nil clock is the wall clock
+func NewRateLimitTrackerWithClock(config RateLimitConfig, clock Clock) *RateLimitTracker {
+	if clock == nil {
+		clock = realClock{}
+	}
+	defaults := DefaultRateLimitConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MaxPerWindow <= 0 {
+		config.MaxPerWindow = defaults.MaxPerWindow
+	}
+	if config.History <= 0 {
+		config.History = defaults.History
+	}
+	if config.SlowDownAbove <= 0 {
+		config.SlowDownAbove = defaults.SlowDownAbove
+	}
+	if config.SpeedUpBelow <= 0 {
+		config.SpeedUpBelow = defaults.SpeedUpBelow
+	}
+	if config.SleepStep <= 0 {
+		config.SleepStep = defaults.SleepStep
+	}
+
 	return &RateLimitTracker{
-		windows:        make([]int, 0, 4), // Keep last 4 windows (1 minute)
-		targetRate:     0.9,               // 90% of the limit to be safe
-		windowDuration: 15 * time.Second,  // 15-second windows
-		maxWindowCount: 15,                // Max 15 requests per 15-second window
-		sleepTime:      0,
-		currentWindowStart: time.Now(),
+		windows:            make([]int, 0, config.History),
+		targetRate:         0.9, // 90% of the limit to be safe
+		windowDuration:     config.Window,
+		maxWindowCount:     config.MaxPerWindow,
+		history:            config.History,
+		slowDownAbove:      config.SlowDownAbove,
+		speedUpBelow:       config.SpeedUpBelow,
+		sleepStep:          config.SleepStep,
+		clock:              clock,
+		sleepTime:          0,
+		currentWindowStart: clock.Now(),
 	}
 }
 
@@ -36,39 +124,42 @@ func (r *RateLimitTracker) AddRequest(endpoint string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	currentTime := time.Now()
+	r.rollWindow(r.clock.Now())
+	r.currentCount++
+}
 
+// rollWindow closes the current window once currentTime is past it, moving
+// its count into the history and adjusting the sleep. r.mu must be held.
+func (r *RateLimitTracker) rollWindow(currentTime time.Time) {
 	// Check if we need to start a new window
 	if currentTime.Sub(r.currentWindowStart) >= r.windowDuration {
 		// Add current window to history
 		r.windows = append(r.windows, r.currentCount)
 		
-		// Keep only the last 4 windows (1 minute total)
-		if len(r.windows) > 4 {
+		// Keep only the last History windows
+		if len(r.windows) > r.history {
 			r.windows = r.windows[1:]
 		}
 
-		// Calculate total requests in the last minute
+		// Calculate total requests over the tracked windows
 		total := r.currentCount
 		for _, count := range r.windows {
 			total += count
 		}
 
-		log.Printf("Window complete - Total requests in last minute: %d", total)
+		log.Printf("Window complete - Total requests in tracked windows: %d", total)
 
 		// Adjust sleep time based on total requests
-		if total > 45 { // 75% of 60 requests per minute
-			r.sleepTime += 100 * time.Millisecond
-		} else if total < 40 { // Less than 67% of limit
-			r.sleepTime = maxDuration(0, r.sleepTime-100*time.Millisecond)
+		if total > r.slowDownAbove {
+			r.sleepTime += r.sleepStep
+		} else if total < r.speedUpBelow {
+			r.sleepTime = maxDuration(0, r.sleepTime-r.sleepStep)
 		}
 
 		// Reset for new window
 		r.currentCount = 0
 		r.currentWindowStart = currentTime
 	}
-
-	r.currentCount++
 }
 
 // Sleep applies the current sleep duration
@@ -78,7 +169,7 @@ func (r *RateLimitTracker) Sleep() {
 	r.mu.Unlock()
 
 	if sleepDuration > 0 {
-		time.Sleep(sleepDuration)
+		r.clock.Sleep(sleepDuration)
 	}
 }
 
@@ -95,6 +186,70 @@ func (r *RateLimitTracker) GetCurrentRate() (int, time.Duration) {
 	return total, r.sleepTime
 }
 
+// Summary returns the tracker's settings along with its current rate
+func (r *RateLimitTracker) Summary() RateLimitSummary {
+	requests, sleepTime := r.GetCurrentRate()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RateLimitSummary{
+		Window:             r.windowDuration.String(),
+		MaxPerWindow:       r.maxWindowCount,
+		TargetRate:         r.targetRate,
+		RequestsLastMinute: requests,
+		CurrentSleep:       sleepTime.String(),
+	}
+}
+
+// ObserveHeaders records the quota Discogs reports in a response's rate
+// limit headers; responses without X-Discogs-Ratelimit-Remaining are ignored
+func (r *RateLimitTracker) ObserveHeaders(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-Discogs-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(header.Get("X-Discogs-Ratelimit"))
+	used, _ := strconv.Atoi(header.Get("X-Discogs-Ratelimit-Used"))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quota = &DiscogsQuota{
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+		UpdatedAt: r.clock.Now(),
+	}
+}
+
+// State returns the current window, the completed windows still counted and
+// the last quota Discogs reported. A window that has run out is closed first,
+// as the next request would, so an idle scraper doesn't report a stale one.
+func (r *RateLimitTracker) State() RateLimitState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rollWindow(r.clock.Now())
+
+	total := r.currentCount
+	for _, count := range r.windows {
+		total += count
+	}
+	state := RateLimitState{
+		CurrentWindow:      r.currentCount,
+		WindowStartedAt:    r.currentWindowStart,
+		RecentWindows:      append([]int{}, r.windows...),
+		RequestsLastMinute: total,
+		Window:             r.windowDuration.String(),
+		MaxPerWindow:       r.maxWindowCount,
+		SleepTime:          r.sleepTime.String(),
+	}
+	if r.quota != nil {
+		quota := *r.quota
+		state.Discogs = &quota
+	}
+	return state
+}
+
 func maxDuration(a, b time.Duration) time.Duration {
 	if a > b {
 		return a
@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when the test, or a sleep, moves it
+type fakeClock struct {
+	t     time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.t = c.t.Add(d)
+}
+
+func newFakeTracker(config RateLimitConfig) (*RateLimitTracker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	return NewRateLimitTrackerWithClock(config, clock), clock
+}
+
+func addRequests(r *RateLimitTracker, n int) {
+	for i := 0; i < n; i++ {
+		r.AddRequest("test")
+	}
+}
+
+func TestRateLimitTrackerDefaults(t *testing.T) {
+	summary := NewRateLimitTracker().Summary()
+	if summary.Window != "15s" || summary.MaxPerWindow != 15 {
+		t.Errorf("window=%s max=%d, want 15s and 15", summary.Window, summary.MaxPerWindow)
+	}
+
+	// Zero fields take the defaults
+	r := NewRateLimitTrackerWithConfig(RateLimitConfig{Window: time.Minute})
+	if r.windowDuration != time.Minute || r.history != 4 || r.slowDownAbove != 45 || r.speedUpBelow != 40 || r.sleepStep != 100*time.Millisecond {
+		t.Errorf("unexpected settings: %+v", r)
+	}
+}
+
+func TestRateLimitTrackerWindowRollover(t *testing.T) {
+	r, clock := newFakeTracker(RateLimitConfig{Window: 10 * time.Second, History: 2, SlowDownAbove: 100, SpeedUpBelow: 1})
+
+	addRequests(r, 3)
+	clock.t = clock.t.Add(9 * time.Second)
+	addRequests(r, 1)
+	if state := r.State(); state.CurrentWindow != 4 || len(state.RecentWindows) != 0 {
+		t.Fatalf("still in the first window: %+v", state)
+	}
+
+	// Each window boundary moves the current count into the history, which
+	// keeps only the last two
+	for _, n := range []int{5, 6, 7} {
+		clock.t = clock.t.Add(10 * time.Second)
+		addRequests(r, n)
+	}
+	state := r.State()
+	if state.CurrentWindow != 7 || len(state.RecentWindows) != 2 || state.RecentWindows[0] != 5 || state.RecentWindows[1] != 6 {
+		t.Errorf("current=%d recent=%v, want 7 and [5 6]", state.CurrentWindow, state.RecentWindows)
+	}
+	if state.RequestsLastMinute != 18 {
+		t.Errorf("requests counted = %d, want 18", state.RequestsLastMinute)
+	}
+}
+
+func TestRateLimitTrackerStateRollsOverIdleWindow(t *testing.T) {
+	r, clock := newFakeTracker(RateLimitConfig{Window: 10 * time.Second, History: 2, SlowDownAbove: 100, SpeedUpBelow: 1})
+
+	addRequests(r, 3)
+	start := clock.t
+
+	// No request has come in since the window ran out; State closes it
+	clock.t = clock.t.Add(12 * time.Second)
+	state := r.State()
+	if state.CurrentWindow != 0 || len(state.RecentWindows) != 1 || state.RecentWindows[0] != 3 {
+		t.Errorf("current=%d recent=%v, want 0 and [3]", state.CurrentWindow, state.RecentWindows)
+	}
+	if !state.WindowStartedAt.Equal(clock.t) {
+		t.Errorf("window started at %v, want %v", state.WindowStartedAt, clock.t)
+	}
+
+	// The next request lands in the new window rather than closing it again
+	r.AddRequest("test")
+	state = r.State()
+	if state.CurrentWindow != 1 || len(state.RecentWindows) != 1 || state.WindowStartedAt.Equal(start) {
+		t.Errorf("after a request: %+v, want it counted in the new window", state)
+	}
+}
+
+func TestRateLimitTrackerAdjustsSleep(t *testing.T) {
+	r, clock := newFakeTracker(RateLimitConfig{
+		Window: time.Second, History: 1, SlowDownAbove: 10, SpeedUpBelow: 5, SleepStep: 50 * time.Millisecond,
+	})
+	sleep := func() time.Duration {
+		_, d := r.GetCurrentRate()
+		return d
+	}
+
+	// Busy windows grow the sleep as each one closes
+	for _, want := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond} {
+		addRequests(r, 11)
+		clock.t = clock.t.Add(time.Second)
+		r.AddRequest("test")
+		if got := sleep(); got != want {
+			t.Fatalf("sleep = %v after a busy window, want %v", got, want)
+		}
+	}
+
+	// Quiet windows shrink it again, but never below zero
+	for _, want := range []time.Duration{50 * time.Millisecond, 0, 0} {
+		clock.t = clock.t.Add(time.Second)
+		r.AddRequest("test")
+		if got := sleep(); got != want {
+			t.Fatalf("sleep = %v after a quiet window, want %v", got, want)
+		}
+	}
+}
+
+// closeWindow makes n requests, then moves the clock past the window and
+// reads the state, which closes the window and adjusts the sleep. The
+// window just closed is counted both as the current one and in the history,
+// so it weighs double: the total is 2n plus the three windows before it.
+func closeWindow(r *RateLimitTracker, clock *fakeClock, n int) time.Duration {
+	addRequests(r, n)
+	clock.t = clock.t.Add(15 * time.Second)
+	r.State()
+	_, sleep := r.GetCurrentRate()
+	return sleep
+}
+
+func TestRateLimitTrackerSlowsDownAbove45(t *testing.T) {
+	r, clock := newFakeTracker(DefaultRateLimitConfig())
+
+	r.Sleep()
+	if len(clock.slept) != 0 {
+		t.Fatalf("slept %v before any traffic", clock.slept)
+	}
+
+	// 2*15 = 30, then 2*15 + 15 = 45, which is still fine
+	closeWindow(r, clock, 15)
+	if sleep := closeWindow(r, clock, 15); sleep != 0 {
+		t.Fatalf("sleep = %v at a total of 45, want 0", sleep)
+	}
+
+	// 2*8 + 30 = 46 tips it over
+	if sleep := closeWindow(r, clock, 8); sleep != 100*time.Millisecond {
+		t.Fatalf("sleep = %v at a total of 46, want 100ms", sleep)
+	}
+	if sleep := closeWindow(r, clock, 8); sleep != 200*time.Millisecond {
+		t.Fatalf("sleep = %v while still over 45, want 200ms", sleep)
+	}
+
+	before := clock.t
+	r.Sleep()
+	if len(clock.slept) != 1 || clock.slept[0] != 200*time.Millisecond || clock.t.Sub(before) != 200*time.Millisecond {
+		t.Errorf("slept %v, want one 200ms sleep on the clock", clock.slept)
+	}
+}
+
+func TestRateLimitTrackerSpeedsUpBelow40(t *testing.T) {
+	r, clock := newFakeTracker(DefaultRateLimitConfig())
+
+	// Two busy windows, 80 and then 120 requests counted
+	closeWindow(r, clock, 40)
+	if sleep := closeWindow(r, clock, 40); sleep != 200*time.Millisecond {
+		t.Fatalf("sleep = %v after a burst, want 200ms", sleep)
+	}
+
+	// The burst counts while it is among the last four windows: 80 twice,
+	// then 40, which isn't below 40. Once it has gone the quiet windows bring
+	// the sleep back down a step at a time, never below zero.
+	want := []time.Duration{300, 400, 400, 300, 200, 100, 0, 0}
+	for i, w := range want {
+		if sleep := closeWindow(r, clock, 0); sleep != w*time.Millisecond {
+			t.Fatalf("window %d: sleep = %v, want %v", i, sleep, w*time.Millisecond)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"discogs-api/internal/condition"
+
 	"github.com/dghubble/oauth1"
 )
 
@@ -19,39 +26,171 @@ type Scraper struct {
 	token       *oauth1.Token
 	httpClient  *http.Client
 	rateLimiter *RateLimitTracker
+
+	// rejections counts the listings turned down as keepers, by reason, and
+	// decisions the listings checked, by the rule that decided
+	rejectionsMu sync.Mutex
+	rejections   map[RejectReason]int
+	decisions    map[KeeperRule]int
 }
 
-// NewScraper creates a new scraper instance
-func NewScraper(consumerKey, consumerSecret string) (*Scraper, error) {
-	config := &ScraperConfig{
+// DefaultRequestTimeout is used when no request timeout is configured. Large
+// inventory pages from Discogs regularly take longer than 30 seconds.
+const DefaultRequestTimeout = 60 * time.Second
+
+// DefaultPageDelay is the pause between inventory pages, on top of whatever
+// the rate limiter asks for
+const DefaultPageDelay = time.Second
+
+// DefaultBaseURL is the root of the Discogs API
+const DefaultBaseURL = "https://api.discogs.com"
+
+// Version is reported to Discogs in the default user agent
+const Version = "1.0"
+
+// DefaultUserAgent identifies the scraper to Discogs. Their API terms ask for a
+// descriptive user agent with contact details, so deployments should set
+// SCRAPER_USER_AGENT with a real address.
+const DefaultUserAgent = "wantlist/" + Version + " (+contact: admin@example.com)"
+
+// Discogs media types, sent as the Accept header to pin the API version and
+// how text fields are formatted. v2 is the only version Discogs serves.
+const (
+	// AcceptDiscogs returns text as entered, with Discogs markup
+	AcceptDiscogs = "application/vnd.discogs.v2.discogs+json"
+	// AcceptHTML renders markup in text fields as HTML
+	AcceptHTML = "application/vnd.discogs.v2.html+json"
+	// AcceptPlaintext strips markup from text fields
+	AcceptPlaintext = "application/vnd.discogs.v2.plaintext+json"
+)
+
+// SupportedAcceptTypes lists the media types ScraperConfig.Accept may hold
+var SupportedAcceptTypes = []string{AcceptDiscogs, AcceptHTML, AcceptPlaintext}
+
+// maxTimeoutRetries is how many times a page is re-requested after a timeout
+const maxTimeoutRetries = 2
+
+// ErrRequestTimeout is returned when a Discogs request exceeds the configured timeout
+var ErrRequestTimeout = errors.New("request timed out")
+
+// ErrPageTimeout is returned when an inventory page exceeds PageTimeout
+var ErrPageTimeout = errors.New("page timed out")
+
+// Notes set on successful scrapes that found no inventory, or only part of it
+const (
+	NoteEmptyInventory    = "seller has no listings for sale"
+	NoteNoPublicInventory = "seller has no public inventory"
+	// NoteInventoryNotAccessible means Discogs refused a page partway through,
+	// as it does for private inventories and sellers blocked from selling
+	NoteInventoryNotAccessible = "seller inventory not accessible"
+)
+
+// DefaultConfig returns the scraper configuration used by NewScraper
+func DefaultConfig(consumerKey, consumerSecret string) *ScraperConfig {
+	return &ScraperConfig{
 		ConsumerKey:    consumerKey,
 		ConsumerSecret: consumerSecret,
 		MaxPages:       5, // Reduced for debugging
 		PerPage:        100,
-		BaseURL:        "https://api.discogs.com",
-		UserAgent:      "wantlist/1.0",
+		BaseURL:        DefaultBaseURL,
+		UserAgent:      DefaultUserAgent,
+		RequestTimeout: DefaultRequestTimeout,
+		PageDelay:      DefaultPageDelay,
+		Keeper:         DefaultKeeperCriteria(),
+		RateLimit:      DefaultRateLimitConfig(),
 	}
+}
 
-	oauthConfig, token, err := AuthenticateClient(consumerKey, consumerSecret)
-	if err != nil {
-		return nil, fmt.Errorf("failed to authenticate: %w", err)
+// Option customizes a Scraper created by NewScraper or NewScraperWithConfig
+type Option func(*Scraper)
+
+// WithBaseURL points the scraper at a different Discogs API root, such as a
+// local mock server
+func WithBaseURL(baseURL string) Option {
+	return func(s *Scraper) {
+		s.config.BaseURL = strings.TrimRight(baseURL, "/")
 	}
+}
 
-	httpClient := oauthConfig.Client(oauth1.NoContext, token)
-	httpClient.Timeout = 30 * time.Second
+// WithHTTPClient makes the scraper send requests through client instead of
+// an OAuth-signed client. The client is responsible for any authentication
+// and timeouts; tests use it to serve fixtures from a RoundTripper.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Scraper) {
+		s.httpClient = client
+	}
+}
 
-	return &Scraper{
+// NewScraper creates a new scraper instance
+func NewScraper(consumerKey, consumerSecret string, opts ...Option) (*Scraper, error) {
+	return NewScraperWithConfig(DefaultConfig(consumerKey, consumerSecret), opts...)
+}
+
+// NewScraperWithConfig creates a new scraper instance from an explicit configuration
+func NewScraperWithConfig(config *ScraperConfig, opts ...Option) (*Scraper, error) {
+	if strings.TrimSpace(config.UserAgent) == "" {
+		return nil, errors.New("scraper user agent must not be empty")
+	}
+	if err := validateAccept(config.Accept); err != nil {
+		return nil, err
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	if len(config.Keeper.Conditions) == 0 {
+		config.Keeper = DefaultKeeperCriteria()
+	}
+	if err := config.Keeper.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.normalizeInventoryParams(); err != nil {
+		return nil, err
+	}
+
+	s := &Scraper{
 		config:      config,
-		oauthConfig: oauthConfig,
-		token:       token,
-		httpClient:  httpClient,
-		rateLimiter: NewRateLimitTracker(),
-	}, nil
+		rateLimiter: NewRateLimitTrackerWithConfig(config.RateLimit),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// An injected client is used as is, without OAuth
+	if s.httpClient == nil {
+		oauthConfig, token, err := AuthenticateClient(config.ConsumerKey, config.ConsumerSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+
+		s.oauthConfig = oauthConfig
+		s.token = token
+		s.httpClient = oauthConfig.Client(oauth1.NoContext, token)
+		s.httpClient.Timeout = config.RequestTimeout
+	}
+
+	return s, nil
 }
 
 // GetInventory scrapes a user's inventory with concurrent processing
 func (s *Scraper) GetInventory(username string) (*ScraperResult, error) {
-	log.Printf("=== Starting inventory fetch for %s ===", username)
+	return s.GetInventoryWithOptions(username, ScrapeOptions{})
+}
+
+// GetInventoryWithOptions scrapes a user's inventory using the given options
+func (s *Scraper) GetInventoryWithOptions(username string, opts ScrapeOptions) (*ScraperResult, error) {
+	return s.GetInventoryContext(context.Background(), username, opts)
+}
+
+// GetInventoryContext scrapes a user's inventory, stopping between pages and
+// aborting in-flight requests once ctx is cancelled. Progress is reported on
+// opts.Progress after each page when it is set. Each page's keepers go to
+// opts.OnPage as the page completes, so a cancelled or failed scrape keeps
+// the pages it got through. The release IDs only go to inventory tracking
+// once the scrape has ended on its own with every page read: incremental
+// scrapes stop at the first tracked release, so tracking a partial scrape
+// would skip the pages it never reached.
+func (s *Scraper) GetInventoryContext(ctx context.Context, username string, opts ScrapeOptions) (*ScraperResult, error) {
+	log.Printf("=== Starting inventory fetch for %s (full scan: %v) ===", username, opts.FullScan)
 
 	// Load previous inventory data
 	previousInventory, err := GetUserInventory(username)
@@ -59,15 +198,27 @@ func (s *Scraper) GetInventory(username string) (*ScraperResult, error) {
 		return nil, fmt.Errorf("failed to load previous inventory: %w", err)
 	}
 
+	// A full scan still marks which releases are new, but never stops early
 	previousIDs := make(map[int]bool)
 	for _, id := range previousInventory.RecordIDs {
 		previousIDs[id] = true
 	}
+	stopAtPrevious := !opts.FullScan
 
 	log.Printf("Found %d previous records for %s", len(previousIDs), username)
 
 	// Get total pages
-	totalPages, err := s.getTotalPages(username)
+	totalPages, err := s.getTotalPages(ctx, username)
+	if errors.Is(err, ErrSellerNotFound) {
+		log.Printf("No inventory found for %s; the seller may be suspended or not exist", username)
+		return &ScraperResult{
+			Username:          username,
+			Success:           true,
+			FullScan:          opts.FullScan,
+			Note:              NoteNoPublicInventory,
+			InventoryNotFound: true,
+		}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total pages: %w", err)
 	}
@@ -82,17 +233,67 @@ func (s *Scraper) GetInventory(username string) (*ScraperResult, error) {
 	// Process pages sequentially to avoid rate limits and 404s
 	// const maxConcurrency = 1 // Disable concurrency for debugging
 	// semaphore := make(chan struct{}, maxConcurrency)
-	
+
 	var allListings []ParsedListing
 	var currentIDs []int
+	newIDs := make(map[int]bool)
+	newKeepers := 0
+	failedPages := 0
+	timedOutPages := 0
+	pagesScanned := 0
+	blockedAtPage := 0
+	listingCapReached := false
 
 	// Process pages sequentially from page 1 to avoid 404s and rate limits
 	for page := 1; page <= maxPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("scrape cancelled: %w", err)
+		}
+
 		log.Printf("Processing page %d of %d", page, maxPages)
-		
-		pageListings, pageIDs, shouldStop, err := s.processPage(username, page, previousIDs)
+		pagesScanned++
+
+		pageCtx, cancelPage := s.pageContext(ctx)
+		pageListings, pageIDs, shouldStop, err := s.processPage(pageCtx, username, page, previousIDs, stopAtPrevious)
+		for attempt := 1; errors.Is(err, ErrRequestTimeout) && pageCtx.Err() == nil && attempt <= maxTimeoutRetries; attempt++ {
+			log.Printf("Page %d timed out, retrying (%d/%d)", page, attempt, maxTimeoutRetries)
+			pageListings, pageIDs, shouldStop, err = s.processPage(pageCtx, username, page, previousIDs, stopAtPrevious)
+		}
+		pageExpired := errors.Is(pageCtx.Err(), context.DeadlineExceeded)
+		cancelPage()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("scrape cancelled: %w", ctx.Err())
+		}
+		if err != nil && pageExpired {
+			err = fmt.Errorf("page %d: %w after %s", page, ErrPageTimeout, s.config.PageTimeout)
+		}
+		if errors.Is(err, ErrRateLimited) {
+			// Later pages would be refused too until the wait is over. The
+			// pages before this one have gone to OnPage; fail so the caller
+			// hears how long to wait.
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			// The page count was served, so the credentials are good: Discogs
+			// won't show the rest of this inventory, and every later page
+			// would be refused too. Keep what was gathered and stop.
+			log.Printf("Inventory of %s not accessible from page %d: %v", username, page, err)
+			pagesScanned--
+			blockedAtPage = page
+			s.reportProgress(ctx, opts, PageProgress{
+				Page: page, TotalPages: maxPages, TotalKeepers: len(allListings), Failed: true,
+			})
+			break
+		}
 		if err != nil {
 			log.Printf("Error processing page %d: %v", page, err)
+			failedPages++
+			if pageExpired || errors.Is(err, ErrRequestTimeout) {
+				timedOutPages++
+			}
+			s.reportProgress(ctx, opts, PageProgress{
+				Page: page, TotalPages: maxPages, TotalKeepers: len(allListings), Failed: true,
+			})
 			// Continue to next page instead of stopping
 			continue
 		}
@@ -101,61 +302,124 @@ func (s *Scraper) GetInventory(username string) (*ScraperResult, error) {
 			log.Printf("Found previously seen record on page %d, stopping", page)
 			break
 		}
-		
+
 		allListings = append(allListings, pageListings...)
 		currentIDs = append(currentIDs, pageIDs...)
+		for _, id := range pageIDs {
+			if !previousIDs[id] {
+				newIDs[id] = true
+			}
+		}
+		for _, listing := range pageListings {
+			if listing.IsNew {
+				newKeepers++
+			}
+		}
 		log.Printf("Processed page %d: %d listings, total so far: %d", page, len(pageListings), len(allListings))
-		
+		if opts.OnPage != nil && len(pageListings) > 0 {
+			opts.OnPage(pageListings)
+		}
+		s.reportProgress(ctx, opts, PageProgress{
+			Page: page, TotalPages: maxPages, Keepers: len(pageListings), TotalKeepers: len(allListings),
+		})
+		if s.config.MaxListings > 0 && len(allListings) >= s.config.MaxListings {
+			log.Printf("Reached the cap of %d listings on page %d, stopping", s.config.MaxListings, page)
+			listingCapReached = true
+			break
+		}
+
 		// Add delay between pages to respect rate limits
-		time.Sleep(1 * time.Second)
+		if s.config.PageDelay > 0 && page < maxPages {
+			select {
+			case <-time.After(s.config.PageDelay):
+			case <-ctx.Done():
+			}
+		}
 	}
 
-	// Update inventory tracking
-	if err := UpdateUserInventory(username, currentIDs); err != nil {
-		log.Printf("Warning: failed to update inventory: %v", err)
+	// Update inventory tracking, unless pages were missed, or left unread
+	// after the listing cap, and the next scrape has to read them again
+	if failedPages == 0 && blockedAtPage == 0 && !listingCapReached {
+		if err := UpdateUserInventory(username, currentIDs); err != nil {
+			log.Printf("Warning: failed to update inventory: %v", err)
+		}
+	} else {
+		log.Printf("Not updating inventory tracking for %s: %d pages failed, blocked at page %d, listing cap reached %v",
+			username, failedPages, blockedAtPage, listingCapReached)
 	}
 
 	result := &ScraperResult{
-		Username:     username,
-		TotalRecords: len(allListings),
-		NewRecords:   len(allListings),
-		Listings:     allListings,
-		Success:      true,
+		Username:      username,
+		TotalRecords:  len(allListings),
+		NewRecords:    len(newIDs),
+		NewKeepers:    newKeepers,
+		Listings:      allListings,
+		Success:       true,
+		FullScan:      opts.FullScan,
+		SeenIDs:       currentIDs,
+		FailedPages:   failedPages,
+		TimedOutPages: timedOutPages,
+		PagesScanned:  pagesScanned,
+		TotalPages:    totalPages,
+
+		ListingCapReached: listingCapReached,
+	}
+	if totalPages == 0 {
+		result.Note = NoteEmptyInventory
+	}
+	if blockedAtPage > 0 {
+		result.Note = NoteInventoryNotAccessible
+		result.InventoryNotAccessible = true
+		result.BlockedAtPage = blockedAtPage
 	}
 
-	log.Printf("=== Finished fetching inventory for %s, total records: %d ===", username, len(allListings))
+	log.Printf("=== Finished fetching inventory for %s, keepers: %d, new releases: %d (%d keepers) ===",
+		username, len(allListings), len(newIDs), newKeepers)
 	return result, nil
 }
 
-// processPage processes a single page of inventory
-func (s *Scraper) processPage(username string, page int, previousIDs map[int]bool) ([]ParsedListing, []int, bool, error) {
+// pageContext bounds one inventory page by PageTimeout, if set
+func (s *Scraper) pageContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.config.PageTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.config.PageTimeout)
+}
+
+// processPage processes a single page of inventory. Listings whose release
+// is not in previousIDs are tagged as new; when stopAtPrevious is set the
+// first previously seen release ends the page and the scrape.
+func (s *Scraper) processPage(ctx context.Context, username string, page int, previousIDs map[int]bool, stopAtPrevious bool) ([]ParsedListing, []int, bool, error) {
 	// Apply rate limiting
 	s.rateLimiter.AddRequest(fmt.Sprintf("inventory_page_%d", page))
 	s.rateLimiter.Sleep()
 
-	url := fmt.Sprintf("%s/users/%s/inventory?page=%d&per_page=%d", 
-		s.config.BaseURL, username, page, s.config.PerPage)
-
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := s.newRequest(ctx, s.inventoryURL(username, page))
 	if err != nil {
-		return nil, nil, false, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, false, err
 	}
 
-	req.Header.Set("User-Agent", s.config.UserAgent)
-
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
+		if isTimeout(err) {
+			return nil, nil, false, fmt.Errorf("page %d: %w", page, ErrRequestTimeout)
+		}
 		return nil, nil, false, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	s.rateLimiter.ObserveHeaders(resp.Header)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil, false, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return nil, nil, false, statusError(resp)
 	}
 
 	var inventoryResp DiscogsInventoryResponse
 	if err := json.NewDecoder(resp.Body).Decode(&inventoryResp); err != nil {
-		return nil, nil, false, fmt.Errorf("failed to decode response: %w", err)
+		// The client timeout also covers reading the body
+		if isTimeout(err) {
+			return nil, nil, false, fmt.Errorf("page %d: %w", page, ErrRequestTimeout)
+		}
+		return nil, nil, false, fmt.Errorf("%w: %w", ErrParse, err)
 	}
 
 	var pageListings []ParsedListing
@@ -166,9 +430,9 @@ func (s *Scraper) processPage(username string, page int, previousIDs map[int]boo
 
 	for i, listing := range inventoryResp.Listings {
 		log.Printf("Processing listing %d/%d on page %d", i+1, len(inventoryResp.Listings), page)
-		
+
 		// Check if we've seen this record before
-		if previousIDs[listing.Release.ID] {
+		if stopAtPrevious && previousIDs[listing.Release.ID] {
 			shouldStop = true
 			log.Printf("Found previously seen record %d, stopping", listing.Release.ID)
 			break
@@ -183,6 +447,13 @@ func (s *Scraper) processPage(username string, page int, previousIDs map[int]boo
 				log.Printf("Warning: failed to parse listing %d: %v", listing.ID, err)
 				continue
 			}
+			parsed.IsNew = !previousIDs[listing.Release.ID]
+			if s.config.ReleaseDetails && parsed.IsNew {
+				// Records saved without metadata are filled by a later scrape
+				if parsed.Metadata, err = s.fetchReleaseMetadata(ctx, listing.Release.ID); err != nil {
+					log.Printf("Warning: failed to fetch release %d: %v", listing.Release.ID, err)
+				}
+			}
 			pageListings = append(pageListings, *parsed)
 		}
 	}
@@ -192,44 +463,148 @@ func (s *Scraper) processPage(username string, page int, previousIDs map[int]boo
 	return pageListings, pageIDs, shouldStop, nil
 }
 
+// KeeperCriteria returns the rule this scraper uses to pick keepers
+func (s *Scraper) KeeperCriteria() KeeperCriteria {
+	return s.config.Keeper
+}
+
+// KeeperRejections returns how many listings each keeper check has turned
+// down since the scraper was created
+func (s *Scraper) KeeperRejections() map[RejectReason]int {
+	s.rejectionsMu.Lock()
+	defer s.rejectionsMu.Unlock()
+
+	counts := make(map[RejectReason]int, len(s.rejections))
+	for reason, n := range s.rejections {
+		counts[reason] = n
+	}
+	return counts
+}
+
+func (s *Scraper) countRejection(reason RejectReason) {
+	s.rejectionsMu.Lock()
+	defer s.rejectionsMu.Unlock()
+
+	if s.rejections == nil {
+		s.rejections = make(map[RejectReason]int)
+	}
+	s.rejections[reason]++
+}
+
+// KeeperDecisions returns how many listings each rule has decided since the
+// scraper was created: the always-keep list, the never-keep list, or the
+// criteria
+func (s *Scraper) KeeperDecisions() map[KeeperRule]int {
+	s.rejectionsMu.Lock()
+	defer s.rejectionsMu.Unlock()
+
+	counts := make(map[KeeperRule]int, len(s.decisions))
+	for rule, n := range s.decisions {
+		counts[rule] = n
+	}
+	return counts
+}
+
+func (s *Scraper) countDecision(rule KeeperRule) {
+	s.rejectionsMu.Lock()
+	defer s.rejectionsMu.Unlock()
+
+	if s.decisions == nil {
+		s.decisions = make(map[KeeperRule]int)
+	}
+	s.decisions[rule]++
+}
+
+// reportProgress sends progress to opts.Progress, if set, unless ctx is done
+func (s *Scraper) reportProgress(ctx context.Context, opts ScrapeOptions, progress PageProgress) {
+	if opts.Progress == nil {
+		return
+	}
+	select {
+	case opts.Progress <- progress:
+	case <-ctx.Done():
+	}
+}
+
+// newRequest builds a Discogs API request carrying the configured user agent
+// and, if set, Accept header
+func (s *Scraper) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.config.UserAgent)
+	if s.config.Accept != "" {
+		req.Header.Set("Accept", s.config.Accept)
+	}
+	return req, nil
+}
+
+// validateAccept checks an Accept header is one Discogs serves; empty sends none
+func validateAccept(accept string) error {
+	if accept == "" {
+		return nil
+	}
+	for _, supported := range SupportedAcceptTypes {
+		if accept == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported Discogs Accept header %q, want one of %s",
+		accept, strings.Join(SupportedAcceptTypes, ", "))
+}
+
 // getTotalPages gets the total number of pages for a user's inventory
-func (s *Scraper) getTotalPages(username string) (int, error) {
+func (s *Scraper) getTotalPages(ctx context.Context, username string) (int, error) {
 	s.rateLimiter.AddRequest("inventory_total_pages")
 	s.rateLimiter.Sleep()
 
-	url := fmt.Sprintf("%s/users/%s/inventory?page=1&per_page=1", s.config.BaseURL, username)
-
-	req, err := http.NewRequest("GET", url, nil)
+	// Ask with the same page size and filters the pages are fetched with, or
+	// the page count won't match
+	req, err := s.newRequest(ctx, s.inventoryURL(username, 1))
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return 0, err
 	}
 
-	req.Header.Set("User-Agent", s.config.UserAgent)
-
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	s.rateLimiter.ObserveHeaders(resp.Header)
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return 0, statusError(resp)
 	}
 
 	var inventoryResp DiscogsInventoryResponse
 	if err := json.NewDecoder(resp.Body).Decode(&inventoryResp); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+		return 0, fmt.Errorf("%w: %w", ErrParse, err)
+	}
+
+	// An empty inventory may still report a single page
+	if inventoryResp.Pagination.Items == 0 && len(inventoryResp.Listings) == 0 {
+		return 0, nil
 	}
 
 	return inventoryResp.Pagination.Pages, nil
 }
 
+// isTimeout reports whether err was caused by a request or read timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // Helper function to convert interface{} to []string
 func interfaceToStringSlice(data interface{}) []string {
 	if data == nil {
 		return []string{}
 	}
-	
+
 	switch v := data.(type) {
 	case string:
 		return []string{v}
@@ -248,6 +623,28 @@ func interfaceToStringSlice(data interface{}) []string {
 	}
 }
 
+// sellerRating reads the seller's positive feedback percentage, which
+// Discogs sends as a string. Sellers without feedback have no rating.
+func sellerRating(stats DiscogsSellerStats) *float64 {
+	if stats.Total <= 0 {
+		return nil
+	}
+	var rating float64
+	switch v := stats.Rating.(type) {
+	case float64:
+		rating = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil
+		}
+		rating = parsed
+	default:
+		return nil
+	}
+	return &rating
+}
+
 // isKeeper determines if a listing meets the "keeper" criteria
 func (s *Scraper) isKeeper(listing DiscogsListing) bool {
 	log.Printf("=== DEBUG: Checking listing %d ===", listing.Release.ID)
@@ -256,61 +653,47 @@ func (s *Scraper) isKeeper(listing DiscogsListing) bool {
 	log.Printf("Formats: %v", listing.Release.Format)
 	log.Printf("Condition: %s", listing.Condition)
 	log.Printf("Wants: %d, Haves: %d", listing.Release.Stats.Community.InWantlist, listing.Release.Stats.Community.InCollection)
-	
-	// Convert format to string slice
-	formats := interfaceToStringSlice(listing.Release.Format)
+
+	formats := parseFormats(listing.Release.Format)
 	log.Printf("Parsed formats: %v", formats)
-	
-	// Check LP format
-	isLP := false
-	for _, format := range formats {
-		if strings.Contains(format, "LP") {
-			isLP = true
-			break
-		}
-	}
-	log.Printf("Is LP: %v", isLP)
-	if !isLP {
-		log.Printf("REJECTED: Not LP format")
-		return false
-	}
 
-	// Check condition
-	goodConditions := map[string]bool{
-		"Near Mint (NM or M-)": true,
-		"Very Good Plus (VG+)": true,
-		"Very Good (VG)":       true,
-		"Good Plus (G+)":       true,
+	candidate := KeeperCandidate{
+		ReleaseID:    listing.Release.ID,
+		Formats:      formatStrings(formats),
+		Descriptions: formatDescriptions(formats),
+		Condition:    listing.Condition,
+		Wants:        listing.Release.Stats.Community.InWantlist,
+		Haves:        listing.Release.Stats.Community.InCollection,
+		Price:        listing.Price.Value,
+		SellerRating: sellerRating(listing.Seller.Stats),
 	}
-	isGoodCondition := goodConditions[listing.Condition]
-	log.Printf("Is good condition: %v", isGoodCondition)
-	if !isGoodCondition {
-		log.Printf("REJECTED: Poor condition (%s)", listing.Condition)
+	rule := keeperRule(s.config.Keeper, candidate)
+	s.countDecision(rule)
+	if reason, detail := keeperRejection(s.config.Keeper, candidate); reason != "" {
+		log.Printf("REJECTED: %s", detail)
+		s.countRejection(reason)
 		return false
 	}
 
-	// Check wants vs haves
-	wantsGreaterThanHaves := listing.Release.Stats.Community.InWantlist > listing.Release.Stats.Community.InCollection
-	log.Printf("Wants > Haves: %v", wantsGreaterThanHaves)
-	if !wantsGreaterThanHaves {
-		log.Printf("REJECTED: Wants (%d) not greater than haves (%d)", 
-			listing.Release.Stats.Community.InWantlist, listing.Release.Stats.Community.InCollection)
-		return false
+	if rule == KeptAlways {
+		log.Printf("ACCEPTED: Release is on the always-keep list")
+	} else {
+		log.Printf("ACCEPTED: All criteria met")
 	}
-
-	log.Printf("ACCEPTED: All criteria met")
 	return true
 }
 
 // parseListing converts a Discogs listing to our internal format
 func (s *Scraper) parseListing(listing DiscogsListing) (*ParsedListing, error) {
-	// Add small delay to avoid overwhelming the API
-	time.Sleep(time.Duration(rand.Intn(500)+500) * time.Millisecond)
+	// Optional jitter, e.g. if parsing is ever extended to call the API
+	if delay := s.parseDelay(); delay > 0 {
+		time.Sleep(delay)
+	}
 
 	// Get suggested price if available
 	suggestedPrice := ""
 	if listing.Release.PriceSuggestions != nil && listing.Release.PriceSuggestions.VeryGoodPlus != nil {
-		suggestedPrice = fmt.Sprintf("%.2f %s", 
+		suggestedPrice = fmt.Sprintf("%.2f %s",
 			listing.Release.PriceSuggestions.VeryGoodPlus.Value,
 			listing.Release.PriceSuggestions.VeryGoodPlus.Currency)
 	}
@@ -327,27 +710,138 @@ func (s *Scraper) parseListing(listing DiscogsListing) (*ParsedListing, error) {
 	}
 
 	return &ParsedListing{
-		DiscogsID:       listing.Release.ID,
-		MediaCondition:  listing.Condition,
-		RecordPrice:     listing.Price.Value,
-		Currency:        listing.Price.Currency,
-		Seller:          listing.Seller.Username,
-		Artist:          listing.Release.Artist,
-		Title:           listing.Release.Title,
-		Format:          "LP",
-		Label:           label,
-		Catno:           listing.Release.CatalogNumber,
-		Wants:           listing.Release.Stats.Community.InWantlist,
-		Haves:           listing.Release.Stats.Community.InCollection,
-		Genres:          genres,
-		Styles:          styles,
-		Year:            listing.Release.Year,
-		SuggestedPrice:  suggestedPrice,
-		ScrapedAt:       time.Now(),
+		DiscogsID:          listing.Release.ID,
+		ListingID:          listing.ID,
+		MediaCondition:     condition.Normalize(listing.Condition),
+		RecordPrice:        listing.Price.Value,
+		Currency:           listing.Price.Currency,
+		Seller:             listing.Seller.Username,
+		SellerRating:       sellerRating(listing.Seller.Stats),
+		SellerFeedback:     listing.Seller.Stats.Total,
+		ShipsFrom:          strings.TrimSpace(listing.ShipsFrom),
+		Artist:             listing.Release.Artist,
+		Title:              listing.Release.Title,
+		Format:             "LP",
+		FormatDescriptions: formatDescriptions(parseFormats(listing.Release.Format)),
+		Label:              label,
+		Catno:              listing.Release.CatalogNumber,
+		Wants:              listing.Release.Stats.Community.InWantlist,
+		Haves:              listing.Release.Stats.Community.InCollection,
+		Genres:             genres,
+		Styles:             styles,
+		Year:               listing.Release.Year,
+		SuggestedPrice:     suggestedPrice,
+		ScrapedAt:          time.Now(),
 	}, nil
 }
 
+// fetchReleaseMetadata reads the release fields kept in a record's metadata
+// from /releases/{id}, as the inventory doesn't include them
+func (s *Scraper) fetchReleaseMetadata(ctx context.Context, releaseID int) (map[string]interface{}, error) {
+	s.rateLimiter.AddRequest(fmt.Sprintf("release_%d", releaseID))
+	s.rateLimiter.Sleep()
+
+	req, err := s.newRequest(ctx, fmt.Sprintf("%s/releases/%d", s.config.BaseURL, releaseID))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, ErrRequestTimeout
+		}
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	s.rateLimiter.ObserveHeaders(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var release DiscogsReleaseDetails
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParse, err)
+	}
+	return releaseMetadata(release), nil
+}
+
+// releaseMetadata collects the release fields kept in a record's metadata,
+// leaving out those Discogs didn't send
+func releaseMetadata(release DiscogsReleaseDetails) map[string]interface{} {
+	metadata := make(map[string]interface{})
+	if release.Country != "" {
+		metadata["country"] = release.Country
+	}
+	if release.Released != "" {
+		metadata["released"] = release.Released
+	}
+	if rating := release.Community.Rating; rating != nil && rating.Count > 0 {
+		metadata["community_rating"] = rating.Average
+		metadata["community_rating_count"] = rating.Count
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// parseDelay picks a random pause between ParseDelayMin and ParseDelayMax
+func (s *Scraper) parseDelay() time.Duration {
+	minDelay, maxDelay := s.config.ParseDelayMin, s.config.ParseDelayMax
+	if maxDelay <= 0 {
+		return 0
+	}
+	if minDelay >= maxDelay {
+		return maxDelay
+	}
+	return minDelay + time.Duration(rand.Int63n(int64(maxDelay-minDelay)))
+}
+
+// ConfigSummary returns the configuration this scraper runs with, after
+// defaults are applied, without the consumer key and secret
+func (s *Scraper) ConfigSummary() ConfigSummary {
+	c := s.config
+	return ConfigSummary{
+		ConsumerKey:    redact(c.ConsumerKey),
+		ConsumerSecret: redact(c.ConsumerSecret),
+		BaseURL:        c.BaseURL,
+		UserAgent:      c.UserAgent,
+		Accept:         c.Accept,
+		MaxPages:       c.MaxPages,
+		MaxListings:    c.MaxListings,
+		PerPage:        c.PerPage,
+		RequestTimeout: c.RequestTimeout.String(),
+		PageTimeout:    c.PageTimeout.String(),
+		ParseDelayMin:  c.ParseDelayMin.String(),
+		ParseDelayMax:  c.ParseDelayMax.String(),
+		PageDelay:      c.PageDelay.String(),
+		ReleaseDetails: c.ReleaseDetails,
+		Sort:           c.Sort,
+		SortOrder:      c.SortOrder,
+		Status:         c.Status,
+		DataDir:        DataDir(),
+		Keeper:         c.Keeper,
+		RateLimit:      s.rateLimiter.Summary(),
+	}
+}
+
+// redact hides a secret, keeping only whether it was set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
 // GetRateInfo returns current rate limiting information
 func (s *Scraper) GetRateInfo() (int, time.Duration) {
 	return s.rateLimiter.GetCurrentRate()
 }
+
+// RateLimitState returns the rate limiter's current window and the quota
+// Discogs last reported
+func (s *Scraper) RateLimitState() RateLimitState {
+	return s.rateLimiter.State()
+}
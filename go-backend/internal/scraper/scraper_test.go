@@ -0,0 +1,411 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func benchmarkListing() DiscogsListing {
+	return DiscogsListing{
+		ID:        1,
+		Price:     DiscogsPrice{Value: 24.5, Currency: "USD"},
+		Condition: "Very Good Plus (VG+)",
+		Seller:    DiscogsSeller{Username: "seller"},
+		Release: DiscogsRelease{
+			ID:     42,
+			Title:  "Title",
+			Artist: "Artist",
+			Format: "LP, Album",
+			Label:  []interface{}{"Label"},
+			Genres: []interface{}{"Jazz"},
+			Styles: []interface{}{"Hard Bop"},
+		},
+	}
+}
+
+func BenchmarkParseListing(b *testing.B) {
+	s := &Scraper{config: DefaultConfig("", "")}
+	listing := benchmarkListing()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.parseListing(listing); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseListingLegacyDelay reproduces the old unconditional 500ms-1s pause
+func BenchmarkParseListingLegacyDelay(b *testing.B) {
+	config := DefaultConfig("", "")
+	config.ParseDelayMin = 500 * time.Millisecond
+	config.ParseDelayMax = time.Second
+	s := &Scraper{config: config}
+	listing := benchmarkListing()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.parseListing(listing); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// inventoryPage is one listing as GET /users/{username}/inventory returns it.
+// The release is a summary: no country, release date or rating.
+const inventoryPage = `{
+	"pagination": {"page": 1, "pages": 1, "per_page": 50, "items": 1, "urls": {}},
+	"listings": [{
+		"id": 172723812,
+		"resource_url": "https://api.discogs.com/marketplace/listings/172723812",
+		"uri": "https://www.discogs.com/sell/item/172723812",
+		"status": "For Sale",
+		"condition": "Near Mint (NM or M-)",
+		"sleeve_condition": "Very Good Plus (VG+)",
+		"comments": "Original inner sleeve",
+		"ships_from": "Germany",
+		"posted": "2024-03-01T04:12:33-07:00",
+		"allow_offers": true,
+		"audio": false,
+		"price": {"currency": "EUR", "value": 40.0},
+		"original_price": {"curr_abbr": "EUR", "curr_id": 3, "formatted": "\u20ac40.00", "value": 40.0},
+		"shipping_price": {"currency": "EUR", "value": 12.0},
+		"seller": {
+			"id": 1234567,
+			"username": "seller",
+			"resource_url": "https://api.discogs.com/users/seller",
+			"stats": {"rating": "99.8", "stars": 5.0, "total": 1520}
+		},
+		"release": {
+			"thumbnail": "https://i.discogs.com/thumb.jpeg",
+			"description": "Miles Davis - Kind Of Blue (LP, Album, RE)",
+			"artist": "Miles Davis",
+			"format": "LP, Album, RE",
+			"resource_url": "https://api.discogs.com/releases/42",
+			"title": "Kind Of Blue",
+			"year": 1959,
+			"id": 42,
+			"catalog_number": "CS 8163",
+			"stats": {"community": {"in_wantlist": 900, "in_collection": 800}}
+		}
+	}]
+}`
+
+// releasePage is GET /releases/42, cut down to the fields around those kept
+const releasePage = `{
+	"id": 42,
+	"title": "Kind Of Blue",
+	"country": "US",
+	"released": "1959-08-17",
+	"released_formatted": "17 Aug 1959",
+	"year": 1959,
+	"community": {"have": 800, "want": 900, "rating": {"count": 310, "average": 4.62}, "status": "Accepted"},
+	"formats": [{"name": "Vinyl", "qty": "1", "descriptions": ["LP", "Album", "Reissue"]}]
+}`
+
+func TestReleaseMetadataIsFetched(t *testing.T) {
+	scrape := func(releaseDetails bool) (ParsedListing, []string) {
+		var requested []string
+		client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requested = append(requested, r.URL.Path)
+			body := inventoryPage
+			if r.URL.Path == "/releases/42" {
+				body = releasePage
+			}
+			resp := statusResponse(http.StatusOK)
+			resp.Body = nopCloser{bytes.NewReader([]byte(body))}
+			return resp, nil
+		})}
+
+		inTempDir(t)
+		config := DefaultConfig("", "")
+		config.ReleaseDetails = releaseDetails
+		s, err := NewScraperWithConfig(config, WithHTTPClient(client))
+		if err != nil {
+			t.Fatal(err)
+		}
+		listings, _, _, err := s.processPage(context.Background(), "seller", 1, map[int]bool{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listings) != 1 {
+			t.Fatalf("%d keepers, want 1", len(listings))
+		}
+		return listings[0], requested
+	}
+
+	// The inventory alone has nothing for the metadata
+	parsed, requested := scrape(false)
+	if parsed.Metadata != nil {
+		t.Errorf("Metadata = %v, want none", parsed.Metadata)
+	}
+	if len(requested) != 1 {
+		t.Errorf("requested %v, want only the inventory page", requested)
+	}
+
+	parsed, requested = scrape(true)
+	if len(requested) != 2 || requested[1] != "/releases/42" {
+		t.Errorf("requested %v, want the inventory page then the release", requested)
+	}
+	want := map[string]interface{}{
+		"country":                "US",
+		"released":               "1959-08-17",
+		"community_rating":       4.62,
+		"community_rating_count": 310,
+	}
+	if len(parsed.Metadata) != len(want) {
+		t.Errorf("Metadata = %v, want %v", parsed.Metadata, want)
+	}
+	for key, value := range want {
+		if parsed.Metadata[key] != value {
+			t.Errorf("Metadata[%q] = %v, want %v", key, parsed.Metadata[key], value)
+		}
+	}
+}
+
+func TestParseListingTrimsShipsFrom(t *testing.T) {
+	s := &Scraper{config: DefaultConfig("", "")}
+
+	listing := benchmarkListing()
+	listing.ShipsFrom = " Germany "
+	parsed, err := s.parseListing(listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ShipsFrom != "Germany" {
+		t.Errorf("ShipsFrom = %q, want Germany", parsed.ShipsFrom)
+	}
+}
+
+func TestNewRequestSetsUserAgent(t *testing.T) {
+	config := DefaultConfig("", "")
+	config.UserAgent = "crate-digger/2.0 (+ops@example.org)"
+	s := &Scraper{config: config}
+
+	req, err := s.newRequest(context.Background(), "https://api.discogs.com/users/seller/inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("User-Agent"); got != config.UserAgent {
+		t.Errorf("User-Agent = %q, want %q", got, config.UserAgent)
+	}
+}
+
+func TestNewScraperRejectsEmptyUserAgent(t *testing.T) {
+	config := DefaultConfig("key", "secret")
+	config.UserAgent = "  "
+
+	if _, err := NewScraperWithConfig(config); err == nil {
+		t.Fatal("expected an error for a blank user agent")
+	}
+}
+
+func TestNewRequestSetsAccept(t *testing.T) {
+	config := DefaultConfig("", "")
+	s := &Scraper{config: config}
+
+	req, err := s.newRequest(context.Background(), "https://api.discogs.com/users/seller/inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := req.Header["Accept"]; ok {
+		t.Errorf("Accept = %q, want no header by default", got)
+	}
+
+	config.Accept = AcceptPlaintext
+	req, err = s.newRequest(context.Background(), "https://api.discogs.com/users/seller/inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Accept"); got != AcceptPlaintext {
+		t.Errorf("Accept = %q, want %q", got, AcceptPlaintext)
+	}
+}
+
+func TestNewScraperValidatesAccept(t *testing.T) {
+	for _, accept := range SupportedAcceptTypes {
+		config := DefaultConfig("key", "secret")
+		config.Accept = accept
+		if _, err := NewScraperWithConfig(config, WithHTTPClient(http.DefaultClient)); err != nil {
+			t.Errorf("Accept %q: %v", accept, err)
+		}
+	}
+
+	for _, accept := range []string{"application/json", "application/vnd.discogs.v1.discogs+json"} {
+		config := DefaultConfig("key", "secret")
+		config.Accept = accept
+		if _, err := NewScraperWithConfig(config); err == nil {
+			t.Errorf("Accept %q was accepted", accept)
+		}
+	}
+}
+
+// inventoryServer serves a fake Discogs inventory with one keeper per page
+func inventoryServer(t *testing.T, pages int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listing := benchmarkListing()
+		listing.Release.Stats.Community = DiscogsCommunityStats{InWantlist: 10, InCollection: 1}
+		var page int
+		if _, err := fmt.Sscan(r.URL.Query().Get("page"), &page); err == nil {
+			listing.ID = page
+			listing.Release.ID = page
+		}
+		json.NewEncoder(w).Encode(DiscogsInventoryResponse{
+			Listings:   []DiscogsListing{listing},
+			Pagination: DiscogsPagination{Pages: pages},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// inTempDir runs the test from a scratch directory so inventory tracking
+// files don't land in the source tree
+func inTempDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestGetInventoryContextReportsProgressAndCancels(t *testing.T) {
+	inTempDir(t)
+	server := inventoryServer(t, 3)
+
+	config := DefaultConfig("", "")
+	config.BaseURL = server.URL
+	s := &Scraper{config: config, httpClient: server.Client(), rateLimiter: NewRateLimitTracker()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := make(chan PageProgress)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.GetInventoryContext(ctx, "seller", ScrapeOptions{FullScan: true, Progress: progress})
+		done <- err
+	}()
+
+	first := <-progress
+	if first.Page != 1 || first.TotalPages != 3 || first.Keepers != 1 || first.TotalKeepers != 1 {
+		t.Errorf("unexpected first progress: %+v", first)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case p := <-progress:
+		t.Errorf("unexpected progress after cancel: %+v", p)
+	case <-time.After(5 * time.Second):
+		t.Fatal("scrape did not stop after cancel")
+	}
+}
+
+// The page count must be for the page size the scrape fetches, or complete
+// scans look truncated
+func TestGetTotalPagesUsesPageSize(t *testing.T) {
+	const items = 250
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var perPage int
+		if _, err := fmt.Sscan(r.URL.Query().Get("per_page"), &perPage); err != nil || perPage < 1 {
+			http.Error(w, "bad per_page", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(DiscogsInventoryResponse{
+			Pagination: DiscogsPagination{Pages: (items + perPage - 1) / perPage, Items: items},
+		})
+	}))
+	defer server.Close()
+
+	config := DefaultConfig("", "")
+	config.BaseURL = server.URL
+	s := &Scraper{config: config, httpClient: server.Client(), rateLimiter: NewRateLimitTracker()}
+
+	pages, err := s.getTotalPages(context.Background(), "seller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (items + config.PerPage - 1) / config.PerPage; pages != want {
+		t.Errorf("getTotalPages = %d, want %d for %d items at %d per page", pages, want, items, config.PerPage)
+	}
+}
+
+func TestGetInventoryAgainstFakeDiscogs(t *testing.T) {
+	inTempDir(t)
+	// Stored credentials skip the interactive OAuth flow
+	t.Setenv("DISCOGS_CREDS", `{"token": "token", "secret": "secret"}`)
+
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/seller/inventory" {
+			http.NotFound(w, r)
+			return
+		}
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+
+		var page int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+
+		keeper := benchmarkListing()
+		keeper.ID = page * 10
+		keeper.Release.ID = page * 10
+		keeper.Release.Stats.Community = DiscogsCommunityStats{InWantlist: 10, InCollection: 1}
+
+		cd := benchmarkListing()
+		cd.ID = page*10 + 1
+		cd.Release.ID = page*10 + 1
+		cd.Release.Format = "CD, Album"
+
+		json.NewEncoder(w).Encode(DiscogsInventoryResponse{
+			Listings:   []DiscogsListing{keeper, cd},
+			Pagination: DiscogsPagination{Page: page, Pages: 2},
+		})
+	}))
+	defer server.Close()
+
+	s, err := NewScraper("key", "secret", WithBaseURL(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.GetInventory("seller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRecords != 2 || result.PagesScanned != 2 || result.FailedPages != 0 {
+		t.Errorf("unexpected result: total=%d scanned=%d failed=%d",
+			result.TotalRecords, result.PagesScanned, result.FailedPages)
+	}
+	for _, listing := range result.Listings {
+		if listing.Format == "CD, Album" {
+			t.Errorf("non-keeper %d was returned", listing.ListingID)
+		}
+	}
+	for _, ua := range userAgents {
+		if ua != DefaultUserAgent {
+			t.Errorf("User-Agent = %q, want %q", ua, DefaultUserAgent)
+		}
+	}
+
+	// A second incremental scrape stops at the first record it has seen
+	result, err = s.GetInventory("seller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRecords != 0 || result.PagesScanned != 1 {
+		t.Errorf("incremental scrape: total=%d scanned=%d, want 0 and 1", result.TotalRecords, result.PagesScanned)
+	}
+}
@@ -6,13 +6,14 @@ import (
 
 // DiscogsListing represents a listing from the Discogs API
 type DiscogsListing struct {
-	ID        int                    `json:"id"`
-	Price     DiscogsPrice          `json:"price"`
-	Condition string                `json:"condition"`
-	Seller    DiscogsSeller         `json:"seller"`
-	Release   DiscogsRelease        `json:"release"`
-	URI       string                `json:"uri"`
-	Status    string                `json:"status"`
+	ID        int            `json:"id"`
+	Price     DiscogsPrice   `json:"price"`
+	Condition string         `json:"condition"`
+	Seller    DiscogsSeller  `json:"seller"`
+	Release   DiscogsRelease `json:"release"`
+	URI       string         `json:"uri"`
+	Status    string         `json:"status"`
+	ShipsFrom string         `json:"ships_from"` // Country the seller ships from
 }
 
 // DiscogsPrice represents price information
@@ -23,25 +24,43 @@ type DiscogsPrice struct {
 
 // DiscogsSeller represents seller information
 type DiscogsSeller struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	URI      string `json:"uri"`
+	ID       int                `json:"id"`
+	Username string             `json:"username"`
+	URI      string             `json:"uri"`
+	Stats    DiscogsSellerStats `json:"stats"`
+}
+
+// DiscogsSellerStats is the seller's marketplace feedback
+type DiscogsSellerStats struct {
+	Rating interface{} `json:"rating"` // Positive feedback percentage, as "99.8" or 99.8
+	Stars  float64     `json:"stars"`
+	Total  int         `json:"total"` // Number of feedback ratings
 }
 
 // DiscogsRelease represents release information
 type DiscogsRelease struct {
-	ID             int                    `json:"id"`
-	Title          string                 `json:"title"`
-	Artist         string                 `json:"artist"`
-	Year           int                    `json:"year"`
-	Format         interface{}            `json:"format"` // Can be string or []string
-	Label          interface{}            `json:"label"`  // Can be string or []string
-	CatalogNumber  string                 `json:"catno"`
-	Genres         interface{}            `json:"genre"`  // Can be string or []string
-	Styles         interface{}            `json:"style"`  // Can be string or []string
-	Stats          DiscogsStats           `json:"stats"`
+	ID               int                      `json:"id"`
+	Title            string                   `json:"title"`
+	Artist           string                   `json:"artist"`
+	Year             int                      `json:"year"`
+	Format           interface{}              `json:"format"` // String, []string or []DiscogsFormat; see parseFormats
+	Label            interface{}              `json:"label"`  // Can be string or []string
+	CatalogNumber    string                   `json:"catno"`
+	Genres           interface{}              `json:"genre"` // Can be string or []string
+	Styles           interface{}              `json:"style"` // Can be string or []string
+	Stats            DiscogsStats             `json:"stats"`
 	PriceSuggestions *DiscogsPriceSuggestions `json:"price_suggestions,omitempty"`
-	URI            string                 `json:"uri"`
+	URI              string                   `json:"uri"`
+}
+
+// DiscogsFormat is one entry of a release's format array, e.g. name "Vinyl",
+// qty "2" and descriptions "LP", "Album", "Reissue"
+type DiscogsFormat struct {
+	Name         string   `json:"name"`
+	Qty          string   `json:"qty,omitempty"`
+	Descriptions []string `json:"descriptions,omitempty"`
+	// Text is the free-text note Discogs shows beside the format, e.g. "180g"
+	Text string `json:"text,omitempty"`
 }
 
 // DiscogsStats represents community statistics
@@ -55,6 +74,22 @@ type DiscogsCommunityStats struct {
 	InCollection int `json:"in_collection"`
 }
 
+// DiscogsReleaseDetails is the part of GET /releases/{id} kept in a record's
+// metadata. The inventory's release summary carries none of it.
+type DiscogsReleaseDetails struct {
+	Country   string `json:"country"`
+	Released  string `json:"released"` // e.g. "1959-08-17"
+	Community struct {
+		Rating *DiscogsRating `json:"rating"`
+	} `json:"community"`
+}
+
+// DiscogsRating is the community's average rating of a release out of 5
+type DiscogsRating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
 // DiscogsPriceSuggestions represents price suggestions
 type DiscogsPriceSuggestions struct {
 	VeryGoodPlus *DiscogsPrice `json:"Very Good Plus (VG+)"`
@@ -86,23 +121,38 @@ type DiscogsPaginationURLs struct {
 
 // ParsedListing represents a processed listing ready for database storage
 type ParsedListing struct {
-	DiscogsID       int       `json:"discogs_id"`
-	MediaCondition  string    `json:"media_condition"`
-	RecordPrice     float64   `json:"record_price"`
-	Currency        string    `json:"currency"`
-	Seller          string    `json:"seller"`
-	Artist          string    `json:"artist"`
-	Title           string    `json:"title"`
-	Format          string    `json:"format"`
-	Label           string    `json:"label"`
-	Catno           string    `json:"catno"`
-	Wants           int       `json:"wants"`
-	Haves           int       `json:"haves"`
-	Genres          []string  `json:"genres"`
-	Styles          []string  `json:"styles"`
-	Year            int       `json:"year"`
-	SuggestedPrice  string    `json:"suggested_price"`
-	ScrapedAt       time.Time `json:"scraped_at"`
+	DiscogsID      int     `json:"discogs_id"`
+	ListingID      int     `json:"listing_id"` // Discogs marketplace listing ID
+	MediaCondition string  `json:"media_condition"`
+	RecordPrice    float64 `json:"record_price"`
+	Currency       string  `json:"currency"`
+	Seller         string  `json:"seller"`
+	// SellerRating is the seller's positive feedback percentage, nil when
+	// the listing didn't include one or the seller has no feedback
+	SellerRating   *float64 `json:"seller_rating"`
+	SellerFeedback int      `json:"seller_feedback"`
+	// ShipsFrom is the country the listing ships from, e.g. "Germany"
+	ShipsFrom string `json:"ships_from"`
+	Artist    string `json:"artist"`
+	Title     string `json:"title"`
+	Format    string `json:"format"`
+	// FormatDescriptions are the release's format descriptions, e.g. "Album"
+	// and "Reissue"
+	FormatDescriptions []string `json:"format_descriptions"`
+	Label              string   `json:"label"`
+	Catno              string   `json:"catno"`
+	Wants              int      `json:"wants"`
+	Haves              int      `json:"haves"`
+	Genres             []string `json:"genres"`
+	Styles             []string `json:"styles"`
+	Year               int      `json:"year"`
+	SuggestedPrice     string   `json:"suggested_price"`
+	// Metadata holds release fields stored in the record's metadata column
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	ScrapedAt time.Time              `json:"scraped_at"`
+	// IsNew is set when the release was not in the seller's inventory as of
+	// the previous scrape
+	IsNew bool `json:"is_new"`
 }
 
 // UserInventoryData represents stored inventory data for a user
@@ -118,16 +168,170 @@ type ScraperConfig struct {
 	ConsumerSecret string
 	MaxPages       int
 	PerPage        int
+
+	// MaxListings stops a scrape once it has collected this many keepers,
+	// after finishing the page that reached it, however many pages are left.
+	// Zero means no cap.
+	MaxListings int
+
 	BaseURL        string
 	UserAgent      string
+	RequestTimeout time.Duration // Per-request HTTP timeout, including reading the body
+
+	// Accept is sent as the Accept header to pin the Discogs API version and
+	// format; one of SupportedAcceptTypes. Empty sends none, leaving Discogs'
+	// default.
+	Accept string
+
+	// PageTimeout bounds the time spent on one inventory page, timeout
+	// retries included. A page that runs out of time is skipped and the
+	// scrape moves on. Zero disables it, leaving only RequestTimeout.
+	PageTimeout time.Duration
+
+	// ParseDelayMin and ParseDelayMax bound a random pause before each keeper is
+	// parsed. Parsing makes no HTTP calls and requests are already spaced by the
+	// rate limiter, so both default to zero (disabled).
+	ParseDelayMin time.Duration
+	ParseDelayMax time.Duration
+
+	// PageDelay is a fixed pause between inventory pages, added to the rate
+	// limiter's adaptive sleep. Zero disables it and leaves pacing entirely to
+	// the rate limiter.
+	PageDelay time.Duration
+
+	// ReleaseDetails fetches /releases/{id} for each keeper whose release
+	// is new to the seller, to fill the record's metadata. Each fetch is a
+	// rate limited request of its own, so it is off by default.
+	ReleaseDetails bool
+
+	// Sort, SortOrder and Status are passed to the Discogs inventory endpoint;
+	// empty leaves Discogs' default. Sorting by listed, desc puts the newest
+	// listings first, which is what the incremental short-circuit assumes.
+	Sort      string
+	SortOrder string
+	Status    string
+
+	// Keeper decides which listings are parsed and saved
+	Keeper KeeperCriteria
+
+	// RateLimit tunes the rate limiter's windows and sleep adjustment
+	RateLimit RateLimitConfig
+}
+
+// ScrapeOptions controls a single inventory scrape
+type ScrapeOptions struct {
+	// FullScan walks every page instead of stopping at the first previously
+	// seen record, so the result reflects the seller's whole inventory
+	FullScan bool
+	// Progress, if set, receives an update after every page
+	Progress chan<- PageProgress
+	// OnPage, if set, is called with each page's keepers as soon as the page
+	// is scraped, so they can be saved before the scrape finishes
+	OnPage func(listings []ParsedListing)
+}
+
+// PageProgress reports how far an inventory scrape has got
+type PageProgress struct {
+	Page         int  `json:"page"`
+	TotalPages   int  `json:"total_pages"`
+	Keepers      int  `json:"keepers"`       // Keepers found on this page
+	TotalKeepers int  `json:"total_keepers"` // Keepers found so far
+	Failed       bool `json:"failed"`
 }
 
 // ScraperResult represents the result of a scraping operation
 type ScraperResult struct {
-	Username      string          `json:"username"`
-	TotalRecords  int             `json:"total_records"`
-	NewRecords    int             `json:"new_records"`
-	Listings      []ParsedListing `json:"listings"`
-	Error         string          `json:"error,omitempty"`
-	Success       bool            `json:"success"`
+	Username     string          `json:"username"`
+	TotalRecords int             `json:"total_records"` // Keepers found
+	NewRecords   int             `json:"new_records"`   // Releases not seen by the previous scrape, keeper or not
+	NewKeepers   int             `json:"new_keepers"`   // Keepers among the new releases
+	Listings     []ParsedListing `json:"listings"`
+	Error        string          `json:"error,omitempty"`
+	Success      bool            `json:"success"`
+	FullScan     bool            `json:"full_scan"`
+	SeenIDs      []int           `json:"-"` // Every release ID seen, keeper or not
+	FailedPages  int             `json:"failed_pages"`
+	// TimedOutPages counts the failed pages skipped because they ran out of time
+	TimedOutPages int `json:"timed_out_pages"`
+	PagesScanned  int `json:"pages_scanned"`
+	TotalPages    int `json:"total_pages"`
+	// MarkedUnavailable counts listings removed by post-scan reconciliation
+	MarkedUnavailable int `json:"marked_unavailable"`
+	// Note explains a successful scrape that found nothing, such as an empty
+	// inventory, or that stopped short, such as a private inventory
+	Note string `json:"note,omitempty"`
+	// InventoryNotFound is set when Discogs has no inventory for the seller at
+	// all, e.g. a suspended or misspelled account
+	InventoryNotFound bool `json:"-"`
+	// InventoryNotAccessible is set when Discogs refused a page (401 or 403)
+	// after serving the page count, e.g. a private inventory. The listings
+	// from earlier pages are kept; BlockedAtPage is the page refused.
+	InventoryNotAccessible bool `json:"-"`
+	BlockedAtPage          int  `json:"blocked_at_page,omitempty"`
+	// ListingCapReached is set when the scrape stopped at MaxListings keepers
+	ListingCapReached bool `json:"listing_cap_reached"`
+}
+
+// CoversInventory reports whether the scrape saw every listing the seller has,
+// which is required before treating missing listings as sold. A missing or
+// inaccessible inventory proves nothing about the listings, so it never does.
+func (r *ScraperResult) CoversInventory() bool {
+	return r.FullScan && !r.InventoryNotFound && !r.InventoryNotAccessible &&
+		r.FailedPages == 0 && r.PagesScanned >= r.TotalPages
+}
+
+// ConfigSummary is a scraper's effective configuration, safe to show to
+// operators: credentials are reduced to whether they are set
+type ConfigSummary struct {
+	ConsumerKey    string           `json:"consumer_key"`
+	ConsumerSecret string           `json:"consumer_secret"`
+	BaseURL        string           `json:"base_url"`
+	UserAgent      string           `json:"user_agent"`
+	Accept         string           `json:"accept"`
+	MaxPages       int              `json:"max_pages"`
+	MaxListings    int              `json:"max_listings"`
+	PerPage        int              `json:"per_page"`
+	RequestTimeout string           `json:"request_timeout"`
+	PageTimeout    string           `json:"page_timeout"`
+	ParseDelayMin  string           `json:"parse_delay_min"`
+	ParseDelayMax  string           `json:"parse_delay_max"`
+	PageDelay      string           `json:"page_delay"`
+	ReleaseDetails bool             `json:"release_details"`
+	Sort           string           `json:"sort"`
+	SortOrder      string           `json:"sort_order"`
+	Status         string           `json:"status"`
+	DataDir        string           `json:"data_dir"`
+	Keeper         KeeperCriteria   `json:"keeper_criteria"`
+	RateLimit      RateLimitSummary `json:"rate_limit"`
+}
+
+// RateLimitState is the rate limiter's live window, cheap enough to poll
+type RateLimitState struct {
+	CurrentWindow      int       `json:"current_window"` // Requests in the window under way
+	WindowStartedAt    time.Time `json:"window_started_at"`
+	RecentWindows      []int     `json:"recent_windows"` // Completed windows, oldest first
+	RequestsLastMinute int       `json:"requests_last_minute"`
+	Window             string    `json:"window"`
+	MaxPerWindow       int       `json:"max_per_window"`
+	SleepTime          string    `json:"sleep_time"`
+	// Discogs is the quota from the last response's rate limit headers, null
+	// until a response carried them
+	Discogs *DiscogsQuota `json:"discogs"`
+}
+
+// DiscogsQuota is the rate limit Discogs reports on every response
+type DiscogsQuota struct {
+	Limit     int       `json:"limit"`     // X-Discogs-Ratelimit
+	Used      int       `json:"used"`      // X-Discogs-Ratelimit-Used
+	Remaining int       `json:"remaining"` // X-Discogs-Ratelimit-Remaining
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RateLimitSummary describes the rate limiter's settings and current state
+type RateLimitSummary struct {
+	Window             string  `json:"window"`
+	MaxPerWindow       int     `json:"max_per_window"`
+	TargetRate         float64 `json:"target_rate"`
+	RequestsLastMinute int     `json:"requests_last_minute"`
+	CurrentSleep       string  `json:"current_sleep"`
 }
@@ -3,8 +3,11 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"discogs-api/internal/config"
@@ -13,6 +16,37 @@ import (
 type ExternalService struct {
 	config     *config.Config
 	httpClient *http.Client
+
+	ratesMu sync.Mutex
+	rates   map[string]*exchangeRates
+}
+
+// exchangeRateTTL is how long fetched exchange rates are reused
+const exchangeRateTTL = time.Hour
+
+// exchangeRateFailureTTL is how long a failed fetch is reported before the
+// exchange rate service is asked again
+const exchangeRateFailureTTL = time.Minute
+
+// ErrExchangeRatesUnavailable is returned when no exchange rate API key is configured
+var ErrExchangeRatesUnavailable = errors.New("exchange rates unavailable: EXCHANGE_RATE_API_KEY not set")
+
+// exchangeRates is a fetch of one base currency's rates. done is closed once
+// the fetch has finished and rates, err and fetchedAt are set.
+type exchangeRates struct {
+	done      chan struct{}
+	rates     map[string]float64
+	err       error
+	fetchedAt time.Time
+}
+
+// fresh reports whether a finished fetch may still be reused
+func (r *exchangeRates) fresh() bool {
+	ttl := exchangeRateTTL
+	if r.err != nil {
+		ttl = exchangeRateFailureTTL
+	}
+	return time.Since(r.fetchedAt) < ttl
 }
 
 func NewExternalService(cfg *config.Config) *ExternalService {
@@ -150,9 +184,56 @@ func (s *ExternalService) TrainModel(listingIDs, keeperIDs []int) (*TrainingResp
 	return &trainResp, nil
 }
 
+// ForgetRequest asks the recommender to drop listings from its training samples
+type ForgetRequest struct {
+	ListingIDs []int `json:"listing_ids"`
+}
+
+// ForgetResponse represents a response from dropping training samples
+type ForgetResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ForgetSamples calls the Python recommendation microservice to drop the
+// listings' evaluations from the samples the model is trained on
+func (s *ExternalService) ForgetSamples(listingIDs []int) (*ForgetResponse, error) {
+	url := fmt.Sprintf("%s/forget", s.config.External.RecommenderServiceURL)
+
+	jsonData, err := json.Marshal(ForgetRequest{ListingIDs: listingIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call recommendation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("recommendation service returned status %d", resp.StatusCode)
+	}
+
+	var forgetResp ForgetResponse
+	if err := json.NewDecoder(resp.Body).Decode(&forgetResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !forgetResp.Success {
+		return &forgetResp, fmt.Errorf("recommendation service failed to forget samples: %s", forgetResp.Error)
+	}
+
+	return &forgetResp, nil
+}
+
 // ThermodynamicRequest represents a request for thermodynamic record selection
 type ThermodynamicRequest struct {
 	ForceRefresh bool `json:"force_refresh"`
+	// CandidatePool restricts the listings considered; nil considers all
+	CandidatePool *config.CandidatePool `json:"candidate_pool,omitempty"`
+	// CandidateIDs are the listings in CandidatePool, when one is set
+	CandidateIDs []uint `json:"candidate_ids,omitempty"`
 }
 
 // ThermodynamicResponse represents a response from thermodynamic selection
@@ -163,13 +244,19 @@ type ThermodynamicResponse struct {
 	Error       string                 `json:"error,omitempty"`
 }
 
-// GetThermodynamicSelection calls the Python thermodynamic recommendation service
-func (s *ExternalService) GetThermodynamicSelection(forceRefresh bool) (*ThermodynamicResponse, error) {
+// GetThermodynamicSelection calls the Python thermodynamic recommendation
+// service. candidateIDs are the listings in the configured candidate pool,
+// sent along with it when one is set.
+func (s *ExternalService) GetThermodynamicSelection(forceRefresh bool, candidateIDs []uint) (*ThermodynamicResponse, error) {
 	url := fmt.Sprintf("%s/thermodynamic", s.config.External.RecommenderServiceURL)
 	
 	reqBody := ThermodynamicRequest{
 		ForceRefresh: forceRefresh,
 	}
+	if pool := s.config.Recommendation.CandidatePool; pool != (config.CandidatePool{}) {
+		reqBody.CandidatePool = &pool
+		reqBody.CandidateIDs = candidateIDs
+	}
 	
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -186,6 +273,77 @@ func (s *ExternalService) GetThermodynamicSelection(forceRefresh bool) (*Thermod
 	if err := json.NewDecoder(resp.Body).Decode(&thermoResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	if thermoResp.Breakdown == nil {
+		thermoResp.Breakdown = make(map[string]interface{})
+	}
 	
 	return &thermoResp, nil
 }
+
+// ExchangeRateResponse represents a response from the exchange rate API
+type ExchangeRateResponse struct {
+	Result          string             `json:"result"`
+	BaseCode        string             `json:"base_code"`
+	ConversionRates map[string]float64 `json:"conversion_rates"`
+	ErrorType       string             `json:"error-type,omitempty"`
+}
+
+// GetExchangeRates returns how many units of each currency one unit of base
+// buys. Rates are cached for an hour per base currency, and failures for a
+// minute. Concurrent callers share one request to the exchange rate service.
+func (s *ExternalService) GetExchangeRates(base string) (map[string]float64, error) {
+	if s.config.External.ExchangeRateAPIKey == "" {
+		return nil, ErrExchangeRatesUnavailable
+	}
+	base = strings.ToUpper(base)
+
+	s.ratesMu.Lock()
+	if cached, ok := s.rates[base]; ok {
+		select {
+		case <-cached.done:
+			if cached.fresh() {
+				s.ratesMu.Unlock()
+				return cached.rates, cached.err
+			}
+		default:
+			// Another request is fetching them; wait for its result
+			s.ratesMu.Unlock()
+			<-cached.done
+			return cached.rates, cached.err
+		}
+	}
+	fetch := &exchangeRates{done: make(chan struct{})}
+	if s.rates == nil {
+		s.rates = make(map[string]*exchangeRates)
+	}
+	s.rates[base] = fetch
+	s.ratesMu.Unlock()
+
+	fetch.rates, fetch.err = s.fetchExchangeRates(base)
+	fetch.fetchedAt = time.Now()
+	close(fetch.done)
+
+	return fetch.rates, fetch.err
+}
+
+// fetchExchangeRates asks the exchange rate service for base's rates
+func (s *ExternalService) fetchExchangeRates(base string) (map[string]float64, error) {
+	url := fmt.Sprintf("%s/%s/latest/%s",
+		s.config.External.ExchangeRateServiceURL, s.config.External.ExchangeRateAPIKey, base)
+
+	resp, err := s.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call exchange rate service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var ratesResp ExchangeRateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ratesResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if ratesResp.Result != "success" {
+		return nil, fmt.Errorf("exchange rate service error: %s", ratesResp.ErrorType)
+	}
+
+	return ratesResp.ConversionRates, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"discogs-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetExchangeRatesSharesFetches(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		json.NewEncoder(w).Encode(ExchangeRateResponse{
+			Result:          "success",
+			BaseCode:        "USD",
+			ConversionRates: map[string]float64{"USD": 1, "EUR": 0.5},
+		})
+	}))
+	defer server.Close()
+
+	s := NewExternalService(&config.Config{External: config.ExternalConfig{
+		ExchangeRateServiceURL: server.URL,
+		ExchangeRateAPIKey:     "key",
+	}})
+
+	// Callers arriving while the rates are being fetched wait for that fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rates, err := s.GetExchangeRates("usd")
+			assert.NoError(t, err)
+			assert.Equal(t, 0.5, rates["EUR"])
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 1 }, 5*time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// Later callers get the cached rates
+	_, err := s.GetExchangeRates("USD")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestGetExchangeRatesCachesFailures(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(ExchangeRateResponse{Result: "error", ErrorType: "quota-reached"})
+	}))
+	defer server.Close()
+
+	s := NewExternalService(&config.Config{External: config.ExternalConfig{
+		ExchangeRateServiceURL: server.URL,
+		ExchangeRateAPIKey:     "key",
+	}})
+
+	_, err := s.GetExchangeRates("USD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quota-reached")
+
+	// The failure is reported again without asking the service
+	_, err = s.GetExchangeRates("USD")
+	require.Error(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// Once it expires the service is asked again
+	s.ratesMu.Lock()
+	s.rates["USD"].fetchedAt = time.Now().Add(-exchangeRateFailureTTL)
+	s.ratesMu.Unlock()
+	_, err = s.GetExchangeRates("USD")
+	require.Error(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+}
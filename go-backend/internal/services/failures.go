@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+	"discogs-api/internal/scraper"
+
+	"gorm.io/gorm"
+)
+
+// ErrFailedListingNotFound is returned when retrying a dead-letter entry that
+// doesn't exist
+var ErrFailedListingNotFound = errors.New("failed listing not found")
+
+// transientSQLStates are the Postgres errors that a retry can get past
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+}
+
+// isTransientDBError reports whether err is a database error worth retrying,
+// as opposed to one, like a constraint violation, that will happen again
+func isTransientDBError(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return transientSQLStates[pgErr.SQLState()]
+	}
+	// SQLite only tells a busy database apart by its message
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
+
+// saveListingWithRetry saves a listing, retrying transient database errors
+// with a doubling delay, SaveRetries times at most
+func (s *ScraperService) saveListingWithRetry(listing scraper.ParsedListing) error {
+	return s.withSaveRetries(func() error { return s.saveListing(listing) })
+}
+
+func (s *ScraperService) withSaveRetries(save func() error) error {
+	retries, delay := s.saveRetries()
+	err := save()
+	for attempt := 1; err != nil && attempt <= retries && isTransientDBError(err); attempt++ {
+		log.Printf("Save failed with a transient error, retrying (%d/%d) in %s: %v", attempt, retries, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+		err = save()
+	}
+	return err
+}
+
+// saveRetries is how often and how soon a failed save is retried
+func (s *ScraperService) saveRetries() (int, time.Duration) {
+	if s.config == nil {
+		return config.DefaultSaveRetries, config.DefaultSaveRetryDelay
+	}
+	retries, delay := s.config.Scraper.SaveRetries, s.config.Scraper.SaveRetryDelay
+	if retries < 0 {
+		retries = 0
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return retries, delay
+}
+
+// recordFailedListing keeps a listing that couldn't be saved, with the error,
+// in the dead-letter table once its retries ran out. A listing that failed
+// before has its entry updated rather than a second one added. Failures to
+// record are logged.
+func (s *ScraperService) recordFailedListing(listing scraper.ParsedListing, saveErr error) {
+	data, err := listingJSON(listing)
+	if err != nil {
+		log.Printf("Warning: failed to record failed listing %d: %v", listing.ListingID, err)
+		return
+	}
+
+	now := time.Now()
+	var entry models.FailedListing
+	result := s.db.Where("seller = ? AND listing_id = ?", listing.Seller, listing.ListingID).Limit(1).Find(&entry)
+	if result.Error != nil {
+		log.Printf("Warning: failed to record failed listing %d: %v", listing.ListingID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		entry = models.FailedListing{
+			Seller:        listing.Seller,
+			ListingID:     listing.ListingID,
+			FirstFailedAt: now,
+		}
+	}
+	entry.DiscogsID = listing.DiscogsID
+	entry.Listing = data
+	entry.Error = saveErr.Error()
+	entry.Failures++
+	entry.LastFailedAt = now
+
+	if err := s.db.Save(&entry).Error; err != nil {
+		log.Printf("Warning: failed to record failed listing %d: %v", listing.ListingID, err)
+	}
+}
+
+// clearFailedListing removes the dead-letter entry of a listing that a later
+// scrape has saved. Failures to remove it are logged.
+func (s *ScraperService) clearFailedListing(listing scraper.ParsedListing) {
+	err := s.db.Where("seller = ? AND listing_id = ? AND discogs_id = ?", listing.Seller, listing.ListingID, listing.DiscogsID).
+		Delete(&models.FailedListing{}).Error
+	if err != nil {
+		log.Printf("Warning: failed to clear failed listing %d: %v", listing.ListingID, err)
+	}
+}
+
+// RetryFailedListing saves a dead-letter entry's listing again. On success
+// the entry is removed; otherwise it is kept with the new error, which is
+// returned.
+func (s *ScraperService) RetryFailedListing(id uint) error {
+	var entry models.FailedListing
+	if err := s.db.First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFailedListingNotFound
+		}
+		return fmt.Errorf("failed to load failed listing: %w", err)
+	}
+
+	data, err := json.Marshal(entry.Listing)
+	if err != nil {
+		return fmt.Errorf("failed to read failed listing: %w", err)
+	}
+	var listing scraper.ParsedListing
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return fmt.Errorf("failed to read failed listing: %w", err)
+	}
+
+	if err := s.saveListingWithRetry(listing); err != nil {
+		s.recordFailedListing(listing, err)
+		return err
+	}
+	if err := s.db.Delete(&entry).Error; err != nil {
+		return fmt.Errorf("listing saved, but failed to remove it from the failures: %w", err)
+	}
+	return nil
+}
+
+// listingJSON turns a parsed listing into the map stored with its failure
+func listingJSON(listing scraper.ParsedListing) (models.JSONMap, error) {
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return nil, err
+	}
+	var m models.JSONMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"discogs-api/internal/condition"
+	"discogs-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ImportListing is one listing read from an imported CSV row
+type ImportListing struct {
+	// Line is the row's line number in the file, for error reports
+	Line int
+
+	// DiscogsID is the release ID; empty matches records by artist,
+	// title, label and format instead
+	DiscogsID string
+	Artist    string
+	Title     string
+	Label     string
+	Format    string
+	Year      *int
+
+	Seller string
+	// Currency is the seller's currency; empty leaves an existing seller's alone
+	Currency string
+
+	Price     float64
+	Condition string
+	ShipsFrom string
+	Score     float64
+	Kept      bool
+	Evaluated bool
+}
+
+// ImportError reports a row that couldn't be imported
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarises an import. Skipped rows duplicate a listing
+// already stored; errored rows are listed in Errors.
+type ImportResult struct {
+	Imported int           `json:"imported"`
+	Skipped  int           `json:"skipped"`
+	Errored  int           `json:"errored"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// importedIDPrefix marks the Discogs IDs given to imported records that
+// came without one
+const importedIDPrefix = "csv-"
+
+// ImportListings stores listings in one transaction. Each row is saved
+// under its own savepoint, so a row that fails is reported in the result
+// without undoing the others.
+func ImportListings(db *gorm.DB, listings []ImportListing) (*ImportResult, error) {
+	result := &ImportResult{Errors: []ImportError{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, listing := range listings {
+			var created bool
+			err := tx.Transaction(func(rowTx *gorm.DB) error {
+				var err error
+				created, err = importListing(rowTx, listing)
+				return err
+			})
+			switch {
+			case err != nil:
+				result.Errored++
+				result.Errors = append(result.Errors, ImportError{Line: listing.Line, Error: err.Error()})
+			case created:
+				result.Imported++
+			default:
+				result.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import listings: %w", err)
+	}
+	return result, nil
+}
+
+// importListing saves one listing, reporting false when the seller already
+// lists the record at that price and condition
+func importListing(tx *gorm.DB, listing ImportListing) (bool, error) {
+	record, err := importRecord(tx, listing)
+	if err != nil {
+		return false, err
+	}
+
+	seller, err := createOrGetSeller(tx, listing.Seller, listing.Currency)
+	if err != nil {
+		return false, err
+	}
+
+	mediaCondition := condition.Normalize(listing.Condition)
+	var existing int64
+	if err := tx.Model(&models.Listing{}).
+		Where("seller_id = ? AND record_id = ? AND record_price = ? AND media_condition = ?",
+			seller.ID, record.ID, listing.Price, mediaCondition).
+		Count(&existing).Error; err != nil {
+		return false, fmt.Errorf("failed to check existing listing: %w", err)
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	dbListing := models.Listing{
+		SellerID:       seller.ID,
+		RecordID:       record.ID,
+		RecordPrice:    listing.Price,
+		MediaCondition: mediaCondition,
+		ShipsFrom:      listing.ShipsFrom,
+		Score:          listing.Score,
+		Kept:           listing.Kept,
+		Evaluated:      listing.Evaluated,
+	}
+	if err := tx.Create(&dbListing).Error; err != nil {
+		return false, fmt.Errorf("failed to create listing: %w", err)
+	}
+
+	if err := tx.Create(&models.PriceHistory{
+		ListingID:  dbListing.ID,
+		Price:      listing.Price,
+		Currency:   seller.Currency,
+		ObservedAt: time.Now(),
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to record price history: %w", err)
+	}
+	return true, nil
+}
+
+// importRecord finds the listing's record or creates it. Unlike a scrape,
+// an import doesn't overwrite a stored record: the CSV has no wants, haves,
+// genres or styles to update it with.
+func importRecord(tx *gorm.DB, listing ImportListing) (*models.Record, error) {
+	query := tx.Where("discogs_id = ?", listing.DiscogsID)
+	if listing.DiscogsID == "" {
+		query = tx.Where("artist = ? AND title = ? AND label = ? AND format = ?",
+			listing.Artist, listing.Title, listing.Label, listing.Format)
+	}
+
+	var record models.Record
+	result := query.Order("id").First(&record)
+	if result.Error == nil {
+		return &record, nil
+	}
+	if result.Error != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to query record: %w", result.Error)
+	}
+
+	record = models.Record{
+		DiscogsID: listing.DiscogsID,
+		Artist:    listing.Artist,
+		Title:     listing.Title,
+		Label:     listing.Label,
+		Format:    listing.Format,
+		Year:      listing.Year,
+		Added:     time.Now(),
+		Genres:    models.StringSlice{},
+		Styles:    models.StringSlice{},
+	}
+	if record.DiscogsID == "" {
+		record.DiscogsID = importedRecordID(listing)
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to create record: %w", err)
+	}
+	return &record, nil
+}
+
+// importedRecordID derives a stable stand-in Discogs ID from a record's
+// details, so importing the same file twice finds the same record
+func importedRecordID(listing ImportListing) string {
+	sum := sha1.Sum([]byte(listing.Artist + "\x00" + listing.Title + "\x00" + listing.Label + "\x00" + listing.Format))
+	return importedIDPrefix + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"discogs-api/internal/models"
+	"discogs-api/internal/scraper"
+)
+
+// ErrRefreshInProgress is returned when a refresh of all sellers is already running
+var ErrRefreshInProgress = errors.New("a refresh of all sellers is already running")
+
+// Seller refresh states
+const (
+	RefreshPending = "pending"
+	RefreshRunning = "running"
+	RefreshDone    = "done"
+	// RefreshSkipped means another scrape of the seller was already running
+	RefreshSkipped = "skipped"
+	RefreshFailed  = "failed"
+)
+
+// SellerRefresh is one seller's scrape within a refresh of all sellers
+type SellerRefresh struct {
+	Seller        string     `json:"seller"`
+	Status        string     `json:"status"`
+	LastScrapedAt *time.Time `json:"last_scraped_at"`
+	TotalRecords  int        `json:"total_records"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// RefreshAllStatus is the progress of an incremental scrape of every known
+// seller, stalest first
+type RefreshAllStatus struct {
+	Running bool `json:"running"`
+	Total   int  `json:"total"`
+	// Queued is how many sellers wait for a free worker, Active how many are
+	// being scraped, at most MaxConcurrent at once
+	Queued        int             `json:"queued"`
+	Active        int             `json:"active"`
+	MaxConcurrent int             `json:"max_concurrent"`
+	Completed     int             `json:"completed"`
+	Skipped       int             `json:"skipped"`
+	Failed        int             `json:"failed"`
+	StartedAt     *time.Time      `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+	Sellers       []SellerRefresh `json:"sellers"`
+}
+
+// refreshAll tracks the current or last refresh of all sellers
+type refreshAll struct {
+	mu     sync.Mutex
+	status RefreshAllStatus
+}
+
+// snapshot copies the status so callers can't race the running refresh
+func (r *refreshAll) snapshot() RefreshAllStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status
+	status.Sellers = append([]SellerRefresh(nil), r.status.Sellers...)
+	for _, seller := range status.Sellers {
+		switch seller.Status {
+		case RefreshPending:
+			status.Queued++
+		case RefreshRunning:
+			status.Active++
+		}
+	}
+	return status
+}
+
+// StartRefreshAll queues an incremental scrape of every seller in the
+// database, least recently scraped first, and runs them in the background,
+// SCRAPE_MAX_CONCURRENT_JOBS at a time. Sellers being scraped already are
+// skipped; requests still go through the scraper's shared rate limiter. onScraped, if set, is called
+// after each successful scrape. It returns the queued job, or
+// ErrRefreshInProgress.
+func (s *ScraperService) StartRefreshAll(onScraped func(seller string, result *scraper.ScraperResult)) (RefreshAllStatus, error) {
+	return s.StartRefreshStale(0, onScraped)
+}
+
+// StartRefreshStale is StartRefreshAll limited to sellers never scraped or
+// last scraped more than staleAfter ago; zero includes every seller
+func (s *ScraperService) StartRefreshStale(staleAfter time.Duration, onScraped func(seller string, result *scraper.ScraperResult)) (RefreshAllStatus, error) {
+	r := &s.refreshAll
+	r.mu.Lock()
+	if r.status.Running {
+		r.mu.Unlock()
+		return RefreshAllStatus{}, ErrRefreshInProgress
+	}
+
+	query := s.db.Order("last_scraped_at IS NOT NULL, last_scraped_at ASC, name")
+	if staleAfter > 0 {
+		query = query.Where("last_scraped_at IS NULL OR last_scraped_at < ?", time.Now().Add(-staleAfter))
+	}
+	var sellers []models.Seller
+	if err := query.Find(&sellers).Error; err != nil {
+		r.mu.Unlock()
+		return RefreshAllStatus{}, fmt.Errorf("failed to load sellers: %w", err)
+	}
+
+	now := time.Now()
+	r.status = RefreshAllStatus{
+		Running:       true,
+		Total:         len(sellers),
+		MaxConcurrent: s.maxConcurrentJobs(),
+		StartedAt:     &now,
+		Sellers:       make([]SellerRefresh, len(sellers)),
+	}
+	for i, seller := range sellers {
+		r.status.Sellers[i] = SellerRefresh{
+			Seller:        seller.Name,
+			Status:        RefreshPending,
+			LastScrapedAt: seller.LastScrapedAt,
+		}
+	}
+	r.mu.Unlock()
+
+	go s.refreshSellers(onScraped)
+	return r.snapshot(), nil
+}
+
+// RefreshAllStatus returns the progress of the current or last refresh of
+// all sellers
+func (s *ScraperService) RefreshAllStatus() RefreshAllStatus {
+	return s.refreshAll.snapshot()
+}
+
+func (s *ScraperService) refreshSellers(onScraped func(seller string, result *scraper.ScraperResult)) {
+	r := &s.refreshAll
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		now := time.Now()
+		r.status.Running = false
+		r.status.FinishedAt = &now
+	}()
+
+	r.mu.Lock()
+	total := len(r.status.Sellers)
+	workers := r.status.MaxConcurrent
+	r.mu.Unlock()
+
+	// Sellers are handed out stalest first to a fixed pool of workers
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				s.refreshSeller(i, onScraped)
+			}
+		}()
+	}
+	for i := 0; i < total; i++ {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	log.Printf("Refreshed %d sellers", total)
+}
+
+// refreshSeller scrapes the i-th seller of the current refresh
+func (s *ScraperService) refreshSeller(i int, onScraped func(seller string, result *scraper.ScraperResult)) {
+	r := &s.refreshAll
+	r.mu.Lock()
+	r.status.Sellers[i].Status = RefreshRunning
+	name := r.status.Sellers[i].Seller
+	r.mu.Unlock()
+
+	result, err := s.ScrapeUserInventory(name, scraper.ScrapeOptions{})
+
+	r.mu.Lock()
+	entry := &r.status.Sellers[i]
+	switch {
+	case errors.Is(err, ErrScrapeInProgress):
+		entry.Status = RefreshSkipped
+		r.status.Skipped++
+	case err != nil:
+		entry.Status = RefreshFailed
+		entry.Error = err.Error()
+		r.status.Failed++
+	case !result.Success:
+		entry.Status = RefreshFailed
+		entry.Error = result.Error
+		r.status.Failed++
+	default:
+		entry.Status = RefreshDone
+		entry.TotalRecords = result.TotalRecords
+		r.status.Completed++
+	}
+	r.mu.Unlock()
+
+	if err == nil && result.Success && onScraped != nil {
+		onScraped(name, result)
+	}
+}
+
+// maxConcurrentJobs is how many sellers a refresh scrapes at once
+func (s *ScraperService) maxConcurrentJobs() int {
+	if s.config == nil || s.config.Scraper.MaxConcurrentJobs < 1 {
+		return 1
+	}
+	return s.config.Scraper.MaxConcurrentJobs
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"discogs-api/internal/scraper"
+)
+
+// Schedule decides when the next scheduled scrape runs; *schedule.Schedule
+// implements it
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// RunSchedule refreshes the sellers not scraped within staleAfter each time
+// sched fires, until ctx is done. A run that finds a refresh still going is
+// skipped; sellers scraped manually meanwhile are skipped through the
+// per-seller lock.
+func (s *ScraperService) RunSchedule(ctx context.Context, sched Schedule, staleAfter time.Duration, onScraped func(seller string, result *scraper.ScraperResult)) {
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Scrape schedule %v never fires, scheduler stopped", sched)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runScheduled(staleAfter, onScraped)
+	}
+}
+
+func (s *ScraperService) runScheduled(staleAfter time.Duration, onScraped func(seller string, result *scraper.ScraperResult)) {
+	job, err := s.StartRefreshStale(staleAfter, onScraped)
+	switch {
+	case errors.Is(err, ErrRefreshInProgress):
+		log.Printf("Scheduled scrape skipped: a refresh is already running")
+	case err != nil:
+		log.Printf("Scheduled scrape failed to start: %v", err)
+	default:
+		log.Printf("Scheduled scrape started for %d sellers not scraped in %s", job.Total, staleAfter)
+	}
+}
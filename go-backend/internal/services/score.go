@@ -0,0 +1,38 @@
+package services
+
+import (
+	"fmt"
+
+	"discogs-api/internal/models"
+	"discogs-api/internal/scoring"
+	"gorm.io/gorm"
+)
+
+// rescoreBatchSize is how many listings RescoreListings loads at a time
+const rescoreBatchSize = 500
+
+// RescoreListings recomputes the score of every current listing with scorer
+// and returns how many changed
+func RescoreListings(db *gorm.DB, scorer *scoring.Scorer) (int, error) {
+	changed := 0
+	var batch []models.Listing
+	result := db.Preload("Record").Preload("Seller").Order("id").
+		FindInBatches(&batch, rescoreBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, listing := range batch {
+				score := scorer.Score(listing)
+				if score == listing.Score {
+					continue
+				}
+				if err := db.Model(&models.Listing{}).Where("id = ?", listing.ID).
+					UpdateColumn("score", score).Error; err != nil {
+					return fmt.Errorf("failed to update listing %d: %w", listing.ID, err)
+				}
+				changed++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return changed, fmt.Errorf("failed to rescore listings: %w", result.Error)
+	}
+	return changed, nil
+}
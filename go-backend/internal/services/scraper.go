@@ -1,29 +1,80 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"discogs-api/internal/config"
+	"discogs-api/internal/currency"
 	"discogs-api/internal/models"
+	"discogs-api/internal/scoring"
 	"discogs-api/internal/scraper"
 	"gorm.io/gorm"
 )
 
+// ErrScrapeInProgress is returned when a scrape of the same seller is already running
+var ErrScrapeInProgress = errors.New("a scrape is already running for this seller")
+
 // ScraperService handles scraping operations and database persistence
 type ScraperService struct {
 	db      *gorm.DB
 	config  *config.Config
 	scraper *scraper.Scraper
+	scorer  *scoring.Scorer
+
+	// running holds the sellers being scraped, lower-cased
+	mu      sync.Mutex
+	running map[string]bool
+
+	refreshAll refreshAll
 }
 
 // NewScraperService creates a new scraper service
 func NewScraperService(db *gorm.DB, cfg *config.Config) (*ScraperService, error) {
-	scraperInstance, err := scraper.NewScraper(
+	// Authentication below already reads the token file
+	scraper.SetDataDir(cfg.Scraper.DataDir)
+
+	scraperConfig := scraper.DefaultConfig(
 		cfg.External.DiscogsConsumerKey,
 		cfg.External.DiscogsConsumerSecret,
 	)
+	scraperConfig.RequestTimeout = cfg.Scraper.RequestTimeout
+	scraperConfig.ParseDelayMin = cfg.Scraper.ParseDelayMin
+	scraperConfig.ParseDelayMax = cfg.Scraper.ParseDelayMax
+	scraperConfig.PageTimeout = cfg.Scraper.PageTimeout
+	scraperConfig.PageDelay = cfg.Scraper.PageDelay
+	scraperConfig.ReleaseDetails = cfg.Scraper.ReleaseDetails
+	scraperConfig.Sort = cfg.Scraper.InventorySort
+	scraperConfig.SortOrder = cfg.Scraper.InventorySortOrder
+	scraperConfig.Status = cfg.Scraper.InventoryStatus
+	if cfg.Scraper.UserAgent != "" {
+		scraperConfig.UserAgent = cfg.Scraper.UserAgent
+	}
+	scraperConfig.Accept = cfg.Scraper.Accept
+	scraperConfig.MaxListings = cfg.Scraper.MaxListings
+	if cfg.Scraper.KeeperWantsComparison != "" {
+		scraperConfig.Keeper.WantsComparison = scraper.WantsComparison(cfg.Scraper.KeeperWantsComparison)
+		scraperConfig.Keeper.MinWantsRatio = cfg.Scraper.KeeperMinWantsRatio
+	}
+	scraperConfig.Keeper.MaxKeeperPrice = cfg.Scraper.KeeperMaxPrice
+	scraperConfig.Keeper.MinSellerRating = cfg.Scraper.KeeperMinSellerRating
+	scraperConfig.Keeper.RequiredDescriptions = cfg.Scraper.KeeperRequiredDescriptions
+	scraperConfig.Keeper.ExcludedDescriptions = cfg.Scraper.KeeperExcludedDescriptions
+	scraperConfig.Keeper.AlwaysKeep = cfg.Scraper.KeeperAlwaysKeep
+	scraperConfig.Keeper.NeverKeep = cfg.Scraper.KeeperNeverKeep
+
+	var opts []scraper.Option
+	if cfg.Scraper.BaseURL != "" {
+		opts = append(opts, scraper.WithBaseURL(cfg.Scraper.BaseURL))
+	}
+
+	scraperInstance, err := scraper.NewScraperWithConfig(scraperConfig, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create scraper: %w", err)
 	}
@@ -32,42 +83,206 @@ func NewScraperService(db *gorm.DB, cfg *config.Config) (*ScraperService, error)
 		db:      db,
 		config:  cfg,
 		scraper: scraperInstance,
+		scorer:  scoring.NewScorer(cfg.Recommendation.ScoreWeights),
 	}, nil
 }
 
+// SetScorer makes saved listings use scorer, so weights changed through the
+// API apply to the next scrape
+func (s *ScraperService) SetScorer(scorer *scoring.Scorer) {
+	s.scorer = scorer
+}
+
 // ScrapeUserInventory scrapes a user's inventory and saves to database
-func (s *ScraperService) ScrapeUserInventory(username string) (*scraper.ScraperResult, error) {
+func (s *ScraperService) ScrapeUserInventory(username string, opts scraper.ScrapeOptions) (*scraper.ScraperResult, error) {
+	return s.ScrapeUserInventoryContext(context.Background(), username, opts)
+}
+
+// ScrapeUserInventoryContext is ScrapeUserInventory with cancellation.
+// Listings are saved page by page, so a cancelled or failed scrape keeps the
+// pages it finished; its run is recorded as failed with what was saved.
+func (s *ScraperService) ScrapeUserInventoryContext(ctx context.Context, username string, opts scraper.ScrapeOptions) (*scraper.ScraperResult, error) {
+	// Two scrapes of one seller would race on inventory tracking and
+	// create duplicate listings
+	if !s.lockSeller(username) {
+		return nil, ErrScrapeInProgress
+	}
+	defer s.unlockSeller(username)
+
 	log.Printf("Starting scrape for user: %s", username)
 
+	run := s.startRun(username)
+
+	// Save each page as it's scraped rather than once the scrape is done
+	kept, failed := 0, 0
+	onPage := opts.OnPage
+	opts.OnPage = func(listings []scraper.ParsedListing) {
+		n, err := s.saveListingsToDatabase(listings)
+		if err != nil {
+			log.Printf("Warning: failed to save some listings to database: %v", err)
+		}
+		kept += len(listings)
+		failed += n
+		if onPage != nil {
+			onPage(listings)
+		}
+	}
+
 	// Scrape the inventory
-	result, err := s.scraper.GetInventory(username)
+	result, err := s.scraper.GetInventoryContext(ctx, username, opts)
 	if err != nil {
+		if kept > 0 {
+			log.Printf("Kept %d listings for %s from pages scraped before: %v", kept-failed, username, err)
+		}
+		s.finishRun(run, &scraper.ScraperResult{TotalRecords: kept, Success: true}, failed, err)
 		return nil, fmt.Errorf("failed to scrape inventory: %w", err)
 	}
 
 	if !result.Success {
+		s.finishRun(run, result, failed, nil)
 		return result, nil
 	}
 
-	// Save listings to database
-	if err := s.saveListingsToDatabase(result.Listings); err != nil {
-		log.Printf("Warning: failed to save some listings to database: %v", err)
-		// Don't return error here, as scraping was successful
-	}
+	s.reconcileAvailability(username, result)
+
+	s.finishRun(run, result, failed, nil)
+	s.markSellerScraped(username)
 
 	log.Printf("Successfully scraped %d listings for user %s", len(result.Listings), username)
 	return result, nil
 }
 
-// saveListingsToDatabase saves parsed listings to the database
-func (s *ScraperService) saveListingsToDatabase(listings []scraper.ParsedListing) error {
+// lockSeller marks a seller as being scraped, reporting false if it already was
+func (s *ScraperService) lockSeller(username string) bool {
+	key := strings.ToLower(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[key] {
+		return false
+	}
+	if s.running == nil {
+		s.running = make(map[string]bool)
+	}
+	s.running[key] = true
+	return true
+}
+
+func (s *ScraperService) unlockSeller(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, strings.ToLower(username))
+}
+
+// startRun records the start of a scrape; failures are logged, not fatal
+func (s *ScraperService) startRun(username string) *models.ScrapeRun {
+	run := &models.ScrapeRun{
+		Seller:    username,
+		StartedAt: time.Now(),
+		Status:    models.ScrapeRunRunning,
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		log.Printf("Warning: failed to record scrape run for %s: %v", username, err)
+		return nil
+	}
+	return run
+}
+
+// finishRun stores the outcome of a scrape on its run record
+func (s *ScraperService) finishRun(run *models.ScrapeRun, result *scraper.ScraperResult, failed int, scrapeErr error) {
+	if run == nil {
+		return
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Errors = failed
+	run.Status = models.ScrapeRunSuccess
+
+	if result != nil {
+		run.Total = result.TotalRecords
+		run.New = result.NewRecords
+		if !result.Success {
+			run.Status = models.ScrapeRunFailed
+			run.Error = result.Error
+		}
+	}
+	if scrapeErr != nil {
+		run.Status = models.ScrapeRunFailed
+		run.Error = scrapeErr.Error()
+	}
+
+	if err := s.db.Save(run).Error; err != nil {
+		log.Printf("Warning: failed to update scrape run %d: %v", run.ID, err)
+	}
+}
+
+// reconcileAvailability marks the seller's listings that a full scan no longer
+// saw as unavailable. Incremental scrapes stop early and scans cut short by
+// MaxPages or page errors miss listings, so those are never reconciled.
+func (s *ScraperService) reconcileAvailability(username string, result *scraper.ScraperResult) {
+	if !result.FullScan {
+		return
+	}
+	if !result.CoversInventory() {
+		log.Printf("Skipping availability reconciliation for %s: scanned %d of %d pages with %d errors",
+			username, result.PagesScanned, result.TotalPages, result.FailedPages)
+		return
+	}
+
+	removed, err := s.removeMissingListings(username, result.SeenIDs)
+	if err != nil {
+		log.Printf("Warning: failed to reconcile availability for %s: %v", username, err)
+		return
+	}
+	result.MarkedUnavailable = int(removed)
+}
+
+// removeMissingListings soft-deletes the seller's listings whose release was
+// not seen in the scrape, keeping them available for historical queries
+func (s *ScraperService) removeMissingListings(username string, seenIDs []int) (int64, error) {
+	query := s.db.Where("seller_id IN (SELECT id FROM discogs_seller WHERE name = ?)", username)
+
+	if len(seenIDs) > 0 {
+		discogsIDs := make([]string, len(seenIDs))
+		for i, id := range seenIDs {
+			discogsIDs[i] = strconv.Itoa(id)
+		}
+		query = query.Where("record_id NOT IN (SELECT id FROM discogs_record WHERE discogs_id IN ?)", discogsIDs)
+	}
+
+	result := query.Delete(&models.Listing{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	log.Printf("Marked %d listings no longer in %s's inventory as unavailable", result.RowsAffected, username)
+	return result.RowsAffected, nil
+}
+
+// markSellerScraped stamps the seller's last successful scrape time. Sellers
+// without any saved listings have no row yet and are skipped.
+func (s *ScraperService) markSellerScraped(username string) {
+	if err := s.db.Model(&models.Seller{}).Where("name = ?", username).
+		Update("last_scraped_at", time.Now()).Error; err != nil {
+		log.Printf("Warning: failed to update last scraped time for %s: %v", username, err)
+	}
+}
+
+// saveListingsToDatabase saves parsed listings to the database and returns
+// how many could not be saved
+func (s *ScraperService) saveListingsToDatabase(listings []scraper.ParsedListing) (int, error) {
+	failed := 0
 	for _, listing := range listings {
-		if err := s.saveListing(listing); err != nil {
+		if err := s.saveListingWithRetry(listing); err != nil {
 			log.Printf("Failed to save listing %d: %v", listing.DiscogsID, err)
+			s.recordFailedListing(listing, err)
+			failed++
 			continue
 		}
+		s.clearFailedListing(listing)
 	}
-	return nil
+	return failed, nil
 }
 
 // saveListing saves a single listing to the database
@@ -93,39 +308,149 @@ func (s *ScraperService) saveListing(listing scraper.ParsedListing) error {
 		tx.Rollback()
 		return fmt.Errorf("failed to create/get seller: %w", err)
 	}
+	if err := updateSellerRating(tx, seller, listing.SellerRating, listing.SellerFeedback); err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	// Create the listing
 	dbListing := models.Listing{
-		SellerID:       seller.ID,
-		RecordID:       record.ID,
-		RecordPrice:    listing.RecordPrice,
-		MediaCondition: listing.MediaCondition,
-		Score:          0.0, // Will be calculated later
-		Kept:           true, // Since we only save "keeper" listings
-		Evaluated:      false,
+		SellerID:        seller.ID,
+		RecordID:        record.ID,
+		RecordPrice:     listing.RecordPrice,
+		MediaCondition:  listing.MediaCondition,
+		ShipsFrom:       listing.ShipsFrom,
+		Score:           s.scoreListing(listing.RecordPrice, listing.MediaCondition, *record, *seller),
+		Kept:            true, // Since we only save "keeper" listings
+		Evaluated:       false,
 		PredictedKeeper: false,
 	}
+	if listing.ListingID != 0 {
+		dbListing.DiscogsListingID = &listing.ListingID
+	}
 
-	// Check if listing already exists
-	var existingListing models.Listing
-	result := tx.Where("seller_id = ? AND record_id = ? AND record_price = ? AND media_condition = ?",
-		seller.ID, record.ID, listing.RecordPrice, listing.MediaCondition).First(&existingListing)
+	// Check if listing already exists, including ones previously removed
+	existingListing, err := s.findExistingListing(tx, listing, seller.ID, record.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to check existing listing: %w", err)
+	}
 
-	if result.Error == gorm.ErrRecordNotFound {
+	if existingListing == nil {
 		// Create new listing
 		if err := tx.Create(&dbListing).Error; err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to create listing: %w", err)
 		}
-	} else if result.Error != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to check existing listing: %w", result.Error)
+		if err := s.recordPrice(tx, dbListing.ID, listing); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit().Error
+	}
+
+	updates := map[string]interface{}{}
+	if existingListing.DeletedAt.Valid {
+		// Back in the inventory, restore it
+		updates["deleted_at"] = nil
+	}
+	if existingListing.DiscogsListingID == nil && listing.ListingID != 0 {
+		updates["discogs_listing_id"] = listing.ListingID
+	}
+	if listing.ShipsFrom != "" && existingListing.ShipsFrom != listing.ShipsFrom {
+		updates["ships_from"] = listing.ShipsFrom
+	}
+	if existingListing.RecordPrice != listing.RecordPrice {
+		updates["record_price"] = listing.RecordPrice
+		updates["score"] = dbListing.Score
+		if err := s.recordPrice(tx, existingListing.ID, listing); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Unscoped().Model(existingListing).Updates(updates).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update listing: %w", err)
+		}
 	}
-	// If listing exists, we don't need to do anything
 
 	return tx.Commit().Error
 }
 
+// scoreListing scores a listing about to be saved for record and seller
+func (s *ScraperService) scoreListing(price float64, mediaCondition string, record models.Record, seller models.Seller) float64 {
+	if s.scorer == nil {
+		return 0
+	}
+	return s.scorer.Score(models.Listing{
+		RecordPrice:    price,
+		MediaCondition: mediaCondition,
+		Record:         record,
+		Seller:         seller,
+	})
+}
+
+// findExistingListing looks up a stored listing by its Discogs listing ID.
+// Listings saved before those IDs were stored have none, so one of those for
+// the same seller, record and condition is taken instead, preferring one at
+// the same price; the price may have changed since. Returns nil when the
+// listing is new.
+func (s *ScraperService) findExistingListing(tx *gorm.DB, listing scraper.ParsedListing, sellerID, recordID uint) (*models.Listing, error) {
+	match := tx.Unscoped().
+		Where("seller_id = ? AND record_id = ? AND media_condition = ?", sellerID, recordID, listing.MediaCondition).
+		Session(&gorm.Session{})
+	if listing.ListingID == 0 {
+		return firstListing(match.Where("record_price = ?", listing.RecordPrice))
+	}
+
+	existing, err := firstListing(tx.Unscoped().Where("discogs_listing_id = ?", listing.ListingID))
+	if existing != nil || err != nil {
+		return existing, err
+	}
+
+	// Rows with another listing ID are other copies of the record
+	legacy := match.Where("discogs_listing_id IS NULL").Session(&gorm.Session{})
+	existing, err = firstListing(legacy.Where("record_price = ?", listing.RecordPrice))
+	if existing != nil || err != nil {
+		return existing, err
+	}
+	return firstListing(legacy.Order("id"))
+}
+
+// firstListing returns the first listing query finds, or nil when it finds
+// none
+func firstListing(query *gorm.DB) (*models.Listing, error) {
+	var listing models.Listing
+	result := query.First(&listing)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &listing, nil
+}
+
+// recordPrice appends a price observation for a listing
+func (s *ScraperService) recordPrice(tx *gorm.DB, listingID uint, listing scraper.ParsedListing) error {
+	observedAt := listing.ScrapedAt
+	if observedAt.IsZero() {
+		observedAt = time.Now()
+	}
+
+	if err := tx.Create(&models.PriceHistory{
+		ListingID:  listingID,
+		Price:      listing.RecordPrice,
+		Currency:   listing.Currency,
+		ObservedAt: observedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record price history: %w", err)
+	}
+	return nil
+}
+
 // createOrGetRecord creates a new record or returns existing one
 func (s *ScraperService) createOrGetRecord(tx *gorm.DB, listing scraper.ParsedListing) (*models.Record, error) {
 	var record models.Record
@@ -137,6 +462,7 @@ func (s *ScraperService) createOrGetRecord(tx *gorm.DB, listing scraper.ParsedLi
 		record.Artist = listing.Artist
 		record.Title = listing.Title
 		record.Format = listing.Format
+		record.FormatDescriptions = models.StringSlice(listing.FormatDescriptions)
 		record.Label = listing.Label
 		record.Catno = &listing.Catno
 		record.Wants = listing.Wants
@@ -144,6 +470,7 @@ func (s *ScraperService) createOrGetRecord(tx *gorm.DB, listing scraper.ParsedLi
 		record.Genres = models.StringSlice(listing.Genres)
 		record.Styles = models.StringSlice(listing.Styles)
 		record.SuggestedPrice = listing.SuggestedPrice
+		record.Metadata = mergeMetadata(record.Metadata, listing.Metadata)
 		if listing.Year > 0 {
 			record.Year = &listing.Year
 		}
@@ -160,18 +487,20 @@ func (s *ScraperService) createOrGetRecord(tx *gorm.DB, listing scraper.ParsedLi
 
 	// Create new record
 	record = models.Record{
-		DiscogsID:      fmt.Sprintf("%d", listing.DiscogsID),
-		Artist:         listing.Artist,
-		Title:          listing.Title,
-		Format:         listing.Format,
-		Label:          listing.Label,
-		Catno:          &listing.Catno,
-		Wants:          listing.Wants,
-		Haves:          listing.Haves,
-		Added:          time.Now(),
-		Genres:         models.StringSlice(listing.Genres),
-		Styles:         models.StringSlice(listing.Styles),
-		SuggestedPrice: listing.SuggestedPrice,
+		DiscogsID:          fmt.Sprintf("%d", listing.DiscogsID),
+		Artist:             listing.Artist,
+		Title:              listing.Title,
+		Format:             listing.Format,
+		FormatDescriptions: models.StringSlice(listing.FormatDescriptions),
+		Label:              listing.Label,
+		Catno:              &listing.Catno,
+		Wants:              listing.Wants,
+		Haves:              listing.Haves,
+		Added:              time.Now(),
+		Genres:             models.StringSlice(listing.Genres),
+		Styles:             models.StringSlice(listing.Styles),
+		SuggestedPrice:     listing.SuggestedPrice,
+		Metadata:           mergeMetadata(nil, listing.Metadata),
 	}
 
 	if listing.Year > 0 {
@@ -185,16 +514,41 @@ func (s *ScraperService) createOrGetRecord(tx *gorm.DB, listing scraper.ParsedLi
 	return &record, nil
 }
 
+// mergeMetadata overlays the fields a scrape saw on a record's metadata,
+// keeping fields this scrape didn't include
+func mergeMetadata(stored models.JSONMap, scraped map[string]interface{}) models.JSONMap {
+	merged := make(models.JSONMap, len(stored)+len(scraped))
+	for key, value := range stored {
+		merged[key] = value
+	}
+	for key, value := range scraped {
+		merged[key] = value
+	}
+	return merged
+}
+
 // createOrGetSeller creates a new seller or returns existing one
-func (s *ScraperService) createOrGetSeller(tx *gorm.DB, sellerName, currency string) (*models.Seller, error) {
+func (s *ScraperService) createOrGetSeller(tx *gorm.DB, sellerName, rawCurrency string) (*models.Seller, error) {
+	return createOrGetSeller(tx, sellerName, rawCurrency)
+}
+
+// createOrGetSeller finds a seller by name, creating it if needed. A known
+// currency replaces the stored one; an empty or unrecognized one doesn't.
+func createOrGetSeller(tx *gorm.DB, sellerName, rawCurrency string) (*models.Seller, error) {
 	var seller models.Seller
 
+	// Store ISO codes only, so "$" and "USD" don't flip a seller back and forth
+	code, known := currency.Normalize(rawCurrency)
+	if !known && rawCurrency != "" {
+		log.Printf("Warning: unrecognized currency %q for seller %s, not storing it", rawCurrency, sellerName)
+	}
+
 	// Try to find existing seller
 	result := tx.Where("name = ?", sellerName).First(&seller)
 	if result.Error == nil {
 		// Update currency if different
-		if seller.Currency != currency {
-			seller.Currency = currency
+		if known && seller.Currency != code {
+			seller.Currency = code
 			if err := tx.Save(&seller).Error; err != nil {
 				return nil, fmt.Errorf("failed to update seller: %w", err)
 			}
@@ -209,7 +563,7 @@ func (s *ScraperService) createOrGetSeller(tx *gorm.DB, sellerName, currency str
 	// Create new seller
 	seller = models.Seller{
 		Name:     sellerName,
-		Currency: currency,
+		Currency: code,
 	}
 
 	if err := tx.Create(&seller).Error; err != nil {
@@ -219,6 +573,37 @@ func (s *ScraperService) createOrGetSeller(tx *gorm.DB, sellerName, currency str
 	return &seller, nil
 }
 
+// updateSellerRating stores the feedback a scrape saw for seller. A listing
+// without a rating leaves the stored one alone.
+func updateSellerRating(tx *gorm.DB, seller *models.Seller, rating *float64, feedbackCount int) error {
+	if rating == nil {
+		return nil
+	}
+	if seller.Rating != nil && *seller.Rating == *rating && seller.FeedbackCount == feedbackCount {
+		return nil
+	}
+	if err := tx.Model(seller).Updates(map[string]interface{}{
+		"rating":         *rating,
+		"feedback_count": feedbackCount,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update seller rating: %w", err)
+	}
+	seller.Rating = rating
+	seller.FeedbackCount = feedbackCount
+	return nil
+}
+
+// GetScraperConfig returns the scraper's effective configuration with
+// credentials redacted
+func (s *ScraperService) GetScraperConfig() scraper.ConfigSummary {
+	return s.scraper.ConfigSummary()
+}
+
+// GetRateLimitState returns the scraper's current rate limit window
+func (s *ScraperService) GetRateLimitState() scraper.RateLimitState {
+	return s.scraper.RateLimitState()
+}
+
 // GetScrapingStats returns statistics about the scraping process
 func (s *ScraperService) GetScrapingStats() (map[string]interface{}, error) {
 	var totalListings int64
@@ -246,6 +631,9 @@ func (s *ScraperService) GetScrapingStats() (map[string]interface{}, error) {
 		"total_sellers":      totalSellers,
 		"current_requests":   requests,
 		"current_sleep_time": sleepTime.String(),
+		"keeper_criteria":    s.scraper.KeeperCriteria(),
+		"keeper_rejections":  s.scraper.KeeperRejections(),
+		"keeper_decisions":   s.scraper.KeeperDecisions(),
 	}
 
 	return stats, nil
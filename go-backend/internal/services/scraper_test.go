@@ -0,0 +1,761 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"discogs-api/internal/config"
+	"discogs-api/internal/models"
+	"discogs-api/internal/scraper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupServiceDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Record{},
+		&models.Seller{},
+		&models.Listing{},
+		&models.ScrapeRun{},
+		&models.PriceHistory{},
+		&models.FailedListing{},
+	))
+	return db
+}
+
+func seedSellerListings(t *testing.T, db *gorm.DB, sellerName string, discogsIDs ...string) {
+	seller := models.Seller{Name: sellerName, Currency: "USD"}
+	require.NoError(t, db.Create(&seller).Error)
+
+	for _, discogsID := range discogsIDs {
+		record := models.Record{DiscogsID: discogsID, Artist: "Artist " + discogsID, Title: "Title"}
+		require.NoError(t, db.Create(&record).Error)
+		require.NoError(t, db.Create(&models.Listing{
+			SellerID:       seller.ID,
+			RecordID:       record.ID,
+			RecordPrice:    10,
+			MediaCondition: "Very Good Plus (VG+)",
+		}).Error)
+	}
+}
+
+func TestRemoveMissingListings(t *testing.T) {
+	db := setupServiceDB(t)
+	seedSellerListings(t, db, "alice", "1", "2", "3")
+	seedSellerListings(t, db, "bob", "4")
+
+	s := &ScraperService{db: db}
+	removed, err := s.removeMissingListings("alice", []int{1, 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	var remaining []models.Listing
+	require.NoError(t, db.Preload("Record").Preload("Seller").Find(&remaining).Error)
+	require.Len(t, remaining, 3)
+	for _, listing := range remaining {
+		assert.NotEqual(t, "2", listing.Record.DiscogsID)
+	}
+
+	var deleted int64
+	db.Unscoped().Model(&models.Listing{}).Where("deleted_at IS NOT NULL").Count(&deleted)
+	assert.Equal(t, int64(1), deleted)
+
+	t.Run("relisted records are restored", func(t *testing.T) {
+		require.NoError(t, s.saveListing(scraper.ParsedListing{
+			DiscogsID:      2,
+			Seller:         "alice",
+			Currency:       "USD",
+			Artist:         "Artist 2",
+			Title:          "Title",
+			RecordPrice:    10,
+			MediaCondition: "Very Good Plus (VG+)",
+		}))
+
+		var count int64
+		db.Model(&models.Listing{}).Count(&count)
+		assert.Equal(t, int64(4), count)
+		db.Unscoped().Model(&models.Listing{}).Count(&count)
+		assert.Equal(t, int64(4), count, "restored rather than duplicated")
+	})
+
+	t.Run("an empty inventory removes everything", func(t *testing.T) {
+		_, err := s.removeMissingListings("bob", nil)
+		require.NoError(t, err)
+
+		var count int64
+		db.Model(&models.Listing{}).Where("seller_id IN (SELECT id FROM discogs_seller WHERE name = ?)", "bob").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestReconcileAvailability(t *testing.T) {
+	db := setupServiceDB(t)
+	seedSellerListings(t, db, "alice", "1", "2")
+	s := &ScraperService{db: db}
+
+	countAvailable := func() int64 {
+		var count int64
+		db.Model(&models.Listing{}).Count(&count)
+		return count
+	}
+
+	tests := []struct {
+		name   string
+		result scraper.ScraperResult
+	}{
+		{"incremental scrape", scraper.ScraperResult{PagesScanned: 1, TotalPages: 1}},
+		{"truncated by max pages", scraper.ScraperResult{FullScan: true, PagesScanned: 5, TotalPages: 8}},
+		{"page errors", scraper.ScraperResult{FullScan: true, PagesScanned: 2, TotalPages: 2, FailedPages: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" is not reconciled", func(t *testing.T) {
+			result := tt.result
+			result.SeenIDs = []int{1}
+			s.reconcileAvailability("alice", &result)
+			assert.Equal(t, 0, result.MarkedUnavailable)
+			assert.Equal(t, int64(2), countAvailable())
+		})
+	}
+
+	t.Run("complete full scan is reconciled", func(t *testing.T) {
+		result := scraper.ScraperResult{FullScan: true, PagesScanned: 1, TotalPages: 1, SeenIDs: []int{1}}
+		s.reconcileAvailability("alice", &result)
+		assert.Equal(t, 1, result.MarkedUnavailable)
+		assert.Equal(t, int64(1), countAvailable())
+	})
+}
+
+func TestSaveListingTracksPriceChanges(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+
+	parsed := scraper.ParsedListing{
+		DiscogsID:      7,
+		ListingID:      9001,
+		Seller:         "alice",
+		Currency:       "USD",
+		Artist:         "Artist",
+		Title:          "Title",
+		RecordPrice:    30,
+		MediaCondition: "Near Mint (NM or M-)",
+	}
+	require.NoError(t, s.saveListing(parsed))
+
+	// Same price again adds no observation
+	require.NoError(t, s.saveListing(parsed))
+
+	parsed.RecordPrice = 24.5
+	require.NoError(t, s.saveListing(parsed))
+
+	var listings []models.Listing
+	require.NoError(t, db.Find(&listings).Error)
+	require.Len(t, listings, 1)
+	assert.Equal(t, 24.5, listings[0].RecordPrice)
+
+	var history []models.PriceHistory
+	require.NoError(t, db.Order("id").Find(&history).Error)
+	require.Len(t, history, 2)
+	assert.Equal(t, 30.0, history[0].Price)
+	assert.Equal(t, 24.5, history[1].Price)
+	assert.Equal(t, listings[0].ID, history[1].ListingID)
+}
+
+func TestSaveListingMatchesListingsWithoutIDs(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+	seedSellerListings(t, db, "alice", "7")
+
+	// A listing saved before listing IDs were stored is matched even though
+	// its price has changed, and takes the ID
+	parsed := scraper.ParsedListing{
+		DiscogsID:      7,
+		ListingID:      9001,
+		Seller:         "alice",
+		Currency:       "USD",
+		Artist:         "Artist",
+		Title:          "Title",
+		RecordPrice:    8,
+		MediaCondition: "Very Good Plus (VG+)",
+	}
+	require.NoError(t, s.saveListing(parsed))
+
+	var listings []models.Listing
+	require.NoError(t, db.Order("id").Find(&listings).Error)
+	require.Len(t, listings, 1)
+	assert.Equal(t, 8.0, listings[0].RecordPrice)
+	require.NotNil(t, listings[0].DiscogsListingID)
+	assert.Equal(t, 9001, *listings[0].DiscogsListingID)
+
+	// Another copy of the record at the same price is a listing of its own
+	parsed.ListingID = 9002
+	require.NoError(t, s.saveListing(parsed))
+	require.NoError(t, db.Order("id").Find(&listings).Error)
+	require.Len(t, listings, 2)
+	assert.Equal(t, 9002, *listings[1].DiscogsListingID)
+}
+
+func TestCreateOrGetSellerNormalizesCurrency(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+
+	seller, err := s.createOrGetSeller(db, "alice", "US$")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", seller.Currency)
+
+	// The same currency written differently leaves the seller alone
+	seller, err = s.createOrGetSeller(db, "alice", "$")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", seller.Currency)
+
+	// An unrecognized currency is not stored over a known one
+	seller, err = s.createOrGetSeller(db, "alice", "dollars")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", seller.Currency)
+
+	seller, err = s.createOrGetSeller(db, "alice", "eur")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", seller.Currency)
+
+	seller, err = s.createOrGetSeller(db, "bob", "dollars")
+	require.NoError(t, err)
+	assert.Equal(t, "", seller.Currency)
+
+	var count int64
+	db.Model(&models.Seller{}).Count(&count)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestSaveListingStoresSellerRating(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+
+	rating := 99.2
+	parsed := scraper.ParsedListing{
+		DiscogsID:      7,
+		ListingID:      9001,
+		Seller:         "alice",
+		Currency:       "USD",
+		Artist:         "Artist",
+		Title:          "Title",
+		RecordPrice:    30,
+		MediaCondition: "Near Mint (NM or M-)",
+		SellerRating:   &rating,
+		SellerFeedback: 420,
+	}
+	require.NoError(t, s.saveListing(parsed))
+
+	// A listing without feedback keeps the last rating seen
+	parsed.DiscogsID, parsed.ListingID = 8, 9002
+	parsed.SellerRating, parsed.SellerFeedback = nil, 0
+	require.NoError(t, s.saveListing(parsed))
+
+	var seller models.Seller
+	require.NoError(t, db.Where("name = ?", "alice").First(&seller).Error)
+	require.NotNil(t, seller.Rating)
+	assert.Equal(t, 99.2, *seller.Rating)
+	assert.Equal(t, 420, seller.FeedbackCount)
+}
+
+func TestSaveListingMergesRecordMetadata(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+
+	parsed := scraper.ParsedListing{
+		DiscogsID:      7,
+		ListingID:      9001,
+		Seller:         "alice",
+		Currency:       "USD",
+		Artist:         "Artist",
+		Title:          "Title",
+		RecordPrice:    30,
+		MediaCondition: "Near Mint (NM or M-)",
+		Metadata:       map[string]interface{}{"country": "UK", "community_rating": 4.5},
+	}
+	require.NoError(t, s.saveListing(parsed))
+
+	// A later scrape updates the rating without dropping the country
+	parsed.ListingID = 9002
+	parsed.Metadata = map[string]interface{}{"community_rating": 4.25}
+	require.NoError(t, s.saveListing(parsed))
+
+	var record models.Record
+	require.NoError(t, db.First(&record).Error)
+	assert.Equal(t, models.JSONMap{"country": "UK", "community_rating": 4.25}, record.Metadata)
+
+	// Records saved without metadata read back as an empty object
+	require.NoError(t, db.Create(&models.Record{DiscogsID: "8", Artist: "A", Title: "T"}).Error)
+	var bare models.Record
+	require.NoError(t, db.Where("discogs_id = ?", "8").First(&bare).Error)
+	assert.Equal(t, models.JSONMap{}, bare.Metadata)
+}
+
+func TestSaveListingShipsFrom(t *testing.T) {
+	db := setupServiceDB(t)
+	s := &ScraperService{db: db}
+
+	parsed := scraper.ParsedListing{
+		DiscogsID:      7,
+		ListingID:      9001,
+		Seller:         "alice",
+		Currency:       "EUR",
+		Artist:         "Artist",
+		Title:          "Title",
+		RecordPrice:    30,
+		MediaCondition: "Near Mint (NM or M-)",
+		ShipsFrom:      "Germany",
+	}
+	require.NoError(t, s.saveListing(parsed))
+
+	var listing models.Listing
+	require.NoError(t, db.First(&listing).Error)
+	assert.Equal(t, "Germany", listing.ShipsFrom)
+
+	// A listing that moved is updated, one seen without a country kept as is
+	parsed.ShipsFrom = "Netherlands"
+	require.NoError(t, s.saveListing(parsed))
+	parsed.ShipsFrom = ""
+	require.NoError(t, s.saveListing(parsed))
+
+	var count int64
+	db.Model(&models.Listing{}).Count(&count)
+	assert.EqualValues(t, 1, count)
+	require.NoError(t, db.First(&listing).Error)
+	assert.Equal(t, "Netherlands", listing.ShipsFrom)
+}
+
+func TestFailedSavesAreDeadLettered(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	s := &ScraperService{db: db}
+
+	// Release 13 can't be stored
+	require.NoError(t, db.Exec(`CREATE TRIGGER reject_13 BEFORE INSERT ON discogs_record
+		WHEN NEW.discogs_id = '13' BEGIN SELECT RAISE(ABORT, 'release 13 rejected'); END`).Error)
+
+	listing := func(discogsID, listingID int) scraper.ParsedListing {
+		return scraper.ParsedListing{
+			DiscogsID: discogsID, ListingID: listingID, Seller: "alice", Currency: "USD",
+			Artist: "Artist", Title: "Title", RecordPrice: 20, MediaCondition: "Near Mint (NM or M-)",
+		}
+	}
+	batch := []scraper.ParsedListing{listing(12, 1012), listing(13, 1013)}
+
+	failed, err := s.saveListingsToDatabase(batch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+
+	var entry models.FailedListing
+	require.NoError(t, db.First(&entry).Error)
+	assert.Equal(t, "alice", entry.Seller)
+	assert.Equal(t, 1013, entry.ListingID)
+	assert.Equal(t, 13, entry.DiscogsID)
+	assert.Contains(t, entry.Error, "release 13 rejected")
+	assert.Equal(t, 1, entry.Failures)
+	assert.Equal(t, "Title", entry.Listing["title"])
+
+	// The next failure updates the same entry
+	_, err = s.saveListingsToDatabase(batch)
+	require.NoError(t, err)
+	assert.ErrorContains(t, s.RetryFailedListing(entry.ID), "release 13 rejected")
+	var entries []models.FailedListing
+	require.NoError(t, db.Find(&entries).Error)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 3, entries[0].Failures)
+	assert.Equal(t, entry.FirstFailedAt.Unix(), entries[0].FirstFailedAt.Unix())
+
+	// Once the cause is gone a retry saves the listing and clears the entry
+	require.NoError(t, db.Exec("DROP TRIGGER reject_13").Error)
+	require.NoError(t, s.RetryFailedListing(entry.ID))
+	var count int64
+	require.NoError(t, db.Model(&models.FailedListing{}).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, db.Model(&models.Listing{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+
+	assert.ErrorIs(t, s.RetryFailedListing(entry.ID), ErrFailedListingNotFound)
+}
+
+func TestSavedListingClearsItsFailure(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	s := &ScraperService{db: db}
+
+	require.NoError(t, db.Exec(`CREATE TRIGGER reject_13 BEFORE INSERT ON discogs_record
+		WHEN NEW.discogs_id = '13' BEGIN SELECT RAISE(ABORT, 'release 13 rejected'); END`).Error)
+	listing := func(seller string) scraper.ParsedListing {
+		return scraper.ParsedListing{
+			DiscogsID: 13, ListingID: 1013, Seller: seller, Currency: "USD",
+			Artist: "Artist", Title: "Title", RecordPrice: 20, MediaCondition: "Near Mint (NM or M-)",
+		}
+	}
+	_, err = s.saveListingsToDatabase([]scraper.ParsedListing{listing("alice"), listing("bob")})
+	require.NoError(t, err)
+
+	// A later scrape saving alice's listing clears her entry but not bob's
+	require.NoError(t, db.Exec("DROP TRIGGER reject_13").Error)
+	failed, err := s.saveListingsToDatabase([]scraper.ParsedListing{listing("alice")})
+	require.NoError(t, err)
+	assert.Zero(t, failed)
+
+	var entries []models.FailedListing
+	require.NoError(t, db.Find(&entries).Error)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "bob", entries[0].Seller)
+}
+
+// sqlStateError is a database error carrying a Postgres SQLSTATE
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pg error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestSaveRetriesTransientErrors(t *testing.T) {
+	s := &ScraperService{config: &config.Config{Scraper: config.ScraperConfig{SaveRetries: 3, SaveRetryDelay: time.Millisecond}}}
+
+	failing := func(errs ...error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	// A deadlock and a serialization failure clear on the third attempt
+	save, calls := failing(fmt.Errorf("failed to create listing: %w", sqlStateError("40P01")), sqlStateError("40001"))
+	assert.NoError(t, s.withSaveRetries(save))
+	assert.Equal(t, 3, *calls)
+
+	// A constraint violation isn't retried
+	save, calls = failing(sqlStateError("23505"))
+	assert.Error(t, s.withSaveRetries(save))
+	assert.Equal(t, 1, *calls)
+
+	// A database that stays locked gives up after the retries
+	locked := errors.New("database is locked")
+	save, calls = failing(locked, locked, locked, locked, locked)
+	assert.ErrorIs(t, s.withSaveRetries(save), locked)
+	assert.Equal(t, 4, *calls)
+
+	// Retries can be turned off
+	s.config.Scraper.SaveRetries = 0
+	save, calls = failing(locked)
+	assert.ErrorIs(t, s.withSaveRetries(save), locked)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestScrapeLocksPerSeller(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// Every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+
+	// Discogs has no inventory for anyone; the first request waits for release
+	var hits int32
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	discogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(arrived)
+			<-release
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer discogs.Close()
+
+	scr, err := scraper.NewScraper("key", "secret", scraper.WithBaseURL(discogs.URL), scraper.WithHTTPClient(discogs.Client()))
+	require.NoError(t, err)
+	s := &ScraperService{db: db, scraper: scr}
+
+	first := make(chan error)
+	go func() {
+		_, err := s.ScrapeUserInventory("crate", scraper.ScrapeOptions{})
+		first <- err
+	}()
+	<-arrived
+
+	_, err = s.ScrapeUserInventory("Crate", scraper.ScrapeOptions{FullScan: true})
+	assert.ErrorIs(t, err, ErrScrapeInProgress, "seller names match case-insensitively")
+
+	result, err := s.ScrapeUserInventory("other", scraper.ScrapeOptions{})
+	require.NoError(t, err, "other sellers aren't blocked")
+	assert.True(t, result.Success)
+
+	close(release)
+	require.NoError(t, <-first)
+
+	_, err = s.ScrapeUserInventory("crate", scraper.ScrapeOptions{})
+	assert.NoError(t, err, "the lock is released when the scrape finishes")
+
+	var runs int64
+	require.NoError(t, db.Model(&models.ScrapeRun{}).Where("seller = ?", "Crate").Count(&runs).Error)
+	assert.Zero(t, runs, "a refused scrape records no run")
+}
+
+func TestCancelledScrapeKeepsScrapedPages(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	scraper.SetDataDir(t.TempDir())
+	t.Cleanup(func() { scraper.SetDataDir("") })
+
+	// Three pages, each holding one keeper whose release ID is the page number
+	discogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		json.NewEncoder(w).Encode(scraper.DiscogsInventoryResponse{
+			Listings: []scraper.DiscogsListing{{
+				ID:        100 + page,
+				Price:     scraper.DiscogsPrice{Value: 20, Currency: "USD"},
+				Condition: "Very Good Plus (VG+)",
+				Seller:    scraper.DiscogsSeller{Username: "crate"},
+				Release: scraper.DiscogsRelease{
+					ID: page, Title: "Title", Artist: "Artist", Format: "LP, Album",
+					Stats: scraper.DiscogsStats{Community: scraper.DiscogsCommunityStats{InWantlist: 10, InCollection: 1}},
+				},
+			}},
+			Pagination: scraper.DiscogsPagination{Pages: 3},
+		})
+	}))
+	defer discogs.Close()
+
+	scr, err := scraper.NewScraper("key", "secret", scraper.WithBaseURL(discogs.URL), scraper.WithHTTPClient(discogs.Client()))
+	require.NoError(t, err)
+	s := &ScraperService{db: db, scraper: scr}
+
+	// The scrape is cancelled once its first page is in
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pages := 0
+	_, err = s.ScrapeUserInventoryContext(ctx, "crate", scraper.ScrapeOptions{
+		FullScan: true,
+		OnPage: func(listings []scraper.ParsedListing) {
+			pages++
+			cancel()
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, pages)
+
+	var listings int64
+	require.NoError(t, db.Model(&models.Listing{}).Count(&listings).Error)
+	assert.EqualValues(t, 1, listings, "the first page was saved")
+
+	var run models.ScrapeRun
+	require.NoError(t, db.Where("seller = ?", "crate").First(&run).Error)
+	assert.Equal(t, models.ScrapeRunFailed, run.Status)
+	assert.Equal(t, 1, run.Total)
+
+	inventory, err := scraper.GetUserInventory("crate")
+	require.NoError(t, err)
+	assert.Empty(t, inventory.RecordIDs, "a cancelled scrape isn't tracked")
+
+	// So the next incremental scrape doesn't stop on the first page and
+	// still reaches the pages the cancelled one never got to
+	result, err := s.ScrapeUserInventoryContext(context.Background(), "crate", scraper.ScrapeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.PagesScanned)
+	require.NoError(t, db.Model(&models.Listing{}).Count(&listings).Error)
+	assert.EqualValues(t, 3, listings)
+
+	inventory, err = scraper.GetUserInventory("crate")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 3}, inventory.RecordIDs)
+}
+
+func TestRefreshAllScrapesStalestFirst(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	// Every seller's inventory is empty
+	var mu sync.Mutex
+	var requested []string
+	discogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer discogs.Close()
+
+	scr, err := scraper.NewScraper("key", "secret", scraper.WithBaseURL(discogs.URL), scraper.WithHTTPClient(discogs.Client()))
+	require.NoError(t, err)
+	s := &ScraperService{db: db, scraper: scr}
+
+	scraped := func(days int) *time.Time {
+		at := time.Now().AddDate(0, 0, -days)
+		return &at
+	}
+	require.NoError(t, db.Create(&[]models.Seller{
+		{Name: "recent", Currency: "USD", LastScrapedAt: scraped(1)},
+		{Name: "busy", Currency: "USD", LastScrapedAt: scraped(10)},
+		{Name: "never", Currency: "USD"},
+		{Name: "old", Currency: "USD", LastScrapedAt: scraped(30)},
+	}).Error)
+
+	// A scrape of busy is already running
+	require.True(t, s.lockSeller("busy"))
+	defer s.unlockSeller("busy")
+
+	var notified []string
+	job, err := s.StartRefreshAll(func(seller string, _ *scraper.ScraperResult) {
+		notified = append(notified, seller)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, job.Total)
+	var queued []string
+	for _, seller := range job.Sellers {
+		queued = append(queued, seller.Seller)
+	}
+	assert.Equal(t, []string{"never", "old", "busy", "recent"}, queued)
+
+	_, err = s.StartRefreshAll(nil)
+	assert.ErrorIs(t, err, ErrRefreshInProgress)
+
+	require.Eventually(t, func() bool { return !s.RefreshAllStatus().Running }, 5*time.Second, 10*time.Millisecond)
+
+	status := s.RefreshAllStatus()
+	assert.Equal(t, 3, status.Completed)
+	assert.Equal(t, 1, status.Skipped)
+	assert.Zero(t, status.Failed)
+	assert.Equal(t, RefreshSkipped, status.Sellers[2].Status)
+	assert.NotNil(t, status.FinishedAt)
+	assert.Equal(t, []string{"never", "old", "recent"}, notified)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/users/never/inventory", "/users/old/inventory", "/users/recent/inventory"}, requested)
+}
+
+func TestRefreshAllBoundsConcurrentScrapes(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	// Inventory requests hang until released, recording how many overlap
+	var inFlight, maxInFlight int32
+	release := make(chan struct{})
+	discogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		<-release
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer discogs.Close()
+
+	scr, err := scraper.NewScraper("key", "secret", scraper.WithBaseURL(discogs.URL), scraper.WithHTTPClient(discogs.Client()))
+	require.NoError(t, err)
+	s := &ScraperService{db: db, scraper: scr, config: &config.Config{Scraper: config.ScraperConfig{MaxConcurrentJobs: 2}}}
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, db.Create(&models.Seller{Name: name, Currency: "USD"}).Error)
+	}
+
+	job, err := s.StartRefreshAll(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.MaxConcurrent)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 }, 5*time.Second, 10*time.Millisecond)
+	status := s.RefreshAllStatus()
+	assert.Equal(t, 2, status.Active)
+	assert.Equal(t, 3, status.Queued)
+
+	close(release)
+	require.Eventually(t, func() bool { return !s.RefreshAllStatus().Running }, 5*time.Second, 10*time.Millisecond)
+
+	status = s.RefreshAllStatus()
+	assert.Equal(t, 5, status.Completed)
+	assert.Zero(t, status.Active)
+	assert.Zero(t, status.Queued)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&maxInFlight), "never more than two scrapes at once")
+}
+
+// onceSchedule fires once, right away, then never again
+type onceSchedule struct{ fired bool }
+
+func (o *onceSchedule) Next(after time.Time) time.Time {
+	if o.fired {
+		return time.Time{}
+	}
+	o.fired = true
+	return after
+}
+
+func TestRunScheduleScrapesStaleSellers(t *testing.T) {
+	db := setupServiceDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	var mu sync.Mutex
+	var requested []string
+	discogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer discogs.Close()
+
+	scr, err := scraper.NewScraper("key", "secret", scraper.WithBaseURL(discogs.URL), scraper.WithHTTPClient(discogs.Client()))
+	require.NoError(t, err)
+	s := &ScraperService{db: db, scraper: scr}
+
+	fresh := time.Now().Add(-time.Hour)
+	stale := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, db.Create(&[]models.Seller{
+		{Name: "fresh", Currency: "USD", LastScrapedAt: &fresh},
+		{Name: "stale", Currency: "USD", LastScrapedAt: &stale},
+		{Name: "never", Currency: "USD"},
+	}).Error)
+
+	done := make(chan struct{})
+	go func() {
+		s.RunSchedule(context.Background(), &onceSchedule{}, 24*time.Hour, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler didn't stop after its last run")
+	}
+
+	require.Eventually(t, func() bool { return !s.RefreshAllStatus().Running }, 5*time.Second, 10*time.Millisecond)
+	status := s.RefreshAllStatus()
+	assert.Equal(t, 2, status.Total)
+	assert.Equal(t, 2, status.Completed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/users/never/inventory", "/users/stale/inventory"}, requested)
+}
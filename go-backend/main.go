@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"net/http"
+	"net/http/pprof"
 	"os"
+	"strings"
 
 	"discogs-api/internal/config"
 	"discogs-api/internal/database"
@@ -11,18 +16,30 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
+	envFile := flag.String("env", "", "Path to the .env file (overrides ENV_FILE; defaults to ../.env)")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load("../.env"); err != nil {
-		log.Println("Warning: .env file not found, using system environment variables")
+	if err := config.LoadEnvFile(*envFile, "../.env"); err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize configuration
 	cfg := config.Load()
 
+	// The Go scraper can't authenticate without Discogs credentials; say so
+	// up front rather than only through 503s
+	if missing := cfg.MissingDiscogsCredentials(); len(missing) > 0 {
+		log.Printf("Warning: %s not set. The Go scraper endpoints (/api/scraper/...) will answer 503 "+
+			"until they are set in the environment or .env file and the server is restarted. "+
+			"Create a consumer key and secret at https://www.discogs.com/settings/developers, "+
+			"then run the scraper CLI once to authorize it and save the token",
+			strings.Join(missing, " and "))
+	}
+
 	// Initialize database
 	db, err := database.Initialize(cfg.Database)
 	if err != nil {
@@ -52,6 +69,18 @@ func main() {
 		"Authorization",
 		"X-Requested-With",
 		"X-CSRF-Token",
+		"Idempotency-Key",
+		"Cache-Control",
+	}
+	// Let browser clients read the pagination, truncation, retry and cache headers
+	corsConfig.ExposeHeaders = []string{
+		"X-Total-Count",
+		"X-Page",
+		"Link",
+		"X-Result-Truncated",
+		"X-Result-Limit",
+		"Retry-After",
+		"X-Cache",
 	}
 	corsConfig.AllowMethods = []string{
 		"GET",
@@ -66,12 +95,32 @@ func main() {
 	// Add logging middleware
 	router.Use(middleware.Logger())
 
+	// Compress large JSON/CSV responses
+	router.Use(middleware.Gzip(middleware.DefaultGzipMinSize))
+
 	// Initialize handlers
 	h := handlers.New(db, cfg)
 
+	// Scrape stale sellers on SCRAPE_SCHEDULE, if set
+	h.StartScrapeScheduler(context.Background())
+
+	// Writes may change what cached responses report
+	router.Use(h.ResponseCache().ClearOnWrite())
+
 	// Setup routes
 	setupRoutes(router, h)
 
+	// Profiling endpoints, for operators only, on their own listener so
+	// they stay off the public port
+	if cfg.Server.PprofEnabled {
+		log.Printf("Profiling endpoints are served on http://%s/debug/pprof/", cfg.Server.PprofAddr)
+		go func() {
+			if err := http.ListenAndServe(cfg.Server.PprofAddr, pprofHandler()); err != nil {
+				log.Printf("Warning: Failed to serve profiling endpoints: %v", err)
+			}
+		}()
+	}
+
 	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -85,9 +134,12 @@ func main() {
 }
 
 func setupRoutes(router *gin.Engine, h *handlers.Handler) {
+	// Expensive read endpoints are served from the response cache
+	cached := h.ResponseCache().Handler()
+
 	// Dashboard routes
-	router.GET("/dashboard/", h.GetDashboard)
-	router.GET("/api/dashboard/listings/", h.GetDashboardListings)
+	router.GET("/dashboard/", cached, h.GetDashboard)
+	router.GET("/api/dashboard/listings/", cached, h.GetDashboardListings)
 	router.POST("/api/refresh-record-of-the-day/", h.RefreshRecordOfTheDay)
 
 	// Search routes
@@ -95,31 +147,91 @@ func setupRoutes(router *gin.Engine, h *handlers.Handler) {
 	router.GET("/autocomplete/genre/", h.GetGenreAutocomplete)
 	router.GET("/autocomplete/condition/", h.GetConditionAutocomplete)
 	router.GET("/autocomplete/styles/", h.GetStylesAutocomplete)
+	router.GET("/autocomplete/format/", h.GetFormatAutocomplete)
+	router.GET("/autocomplete/ships-from/", h.GetShipsFromAutocomplete)
+	router.GET("/autocomplete/tag/", h.GetTagAutocomplete)
+	router.POST("/searches/", h.CreateSavedSearch)
+	router.GET("/searches/", h.GetSavedSearches)
+	router.GET("/searches/:id/run", h.RunSavedSearch)
+
+	// Listing routes
+	router.GET("/listings/", h.GetListingsByIDs)
+	router.GET("/listings/count/", h.CountListings)
+	router.GET("/listings/:id/price-history/", h.GetListingPriceHistory)
+	router.GET("/api/price-drops/", h.GetPriceDrops)
 
 	// Seller routes
 	router.POST("/by-seller/search/", h.SearchSellerListings)
 	router.POST("/data/:seller", h.TriggerSellerScrape)
+	router.GET("/records/", h.GetRecords)
 	router.GET("/records/seller/:seller/", h.GetRecordsBySeller)
+	router.PATCH("/records/:id/notes", h.UpdateRecordNotes)
+	router.GET("/api/sellers/stats/", cached, h.GetSellerStats)
+	router.GET("/api/unevaluated/by-seller/", cached, h.GetUnevaluatedBySeller)
+	router.GET("/sellers/:seller", h.GetSeller)
 
 	// Recommendation routes
 	router.GET("/recommendation-predictions/", h.GetRecommendationPredictions)
+	router.GET("/recommendations/queue/", h.GetReviewQueue)
 	router.POST("/submit-scoring-selections/", h.SubmitRecommendations)
-	router.GET("/model-performance-stats/", h.GetModelPerformanceStats)
-
-	// Export routes
+	router.POST("/recommendations/undo/", h.UndoRecommendations)
+	router.GET("/recommendations/sessions/", h.GetReviewSessions)
+	router.GET("/model-performance-stats/", cached, h.GetModelPerformanceStats)
+	router.GET("/api/predictions/refresh", h.GetPredictionRefresh)
+	router.POST("/api/predictions/refresh", h.StartPredictionRefresh)
+	router.GET("/api/score/weights", h.GetScoreWeights)
+	router.PUT("/api/score/weights", h.UpdateScoreWeights)
+
+	// Export and import routes
 	router.GET("/export-listings", h.ExportListingsCsv)
+	router.POST("/api/import/csv", h.ImportListingsCsv)
 
 	// Wantlist routes
 	router.POST("/add-to-wantlist/", h.AddToWantlist)
 
 	// Record of the Day voting
+	router.POST("/vote-record-of-the-day/bulk/", h.VoteRecordsOfTheDay)
 	router.POST("/vote-record-of-the-day/:id/", h.VoteRecordOfTheDay)
+	router.POST("/record-of-the-day/:id/recompute", h.RecomputeRecordOfTheDay)
 
 	// Go Scraper routes
 	router.POST("/api/scraper/go/:seller", h.TriggerGoScraper)
+	router.GET("/api/scraper/go/:seller/stream", h.StreamGoScraper)
+	router.POST("/api/scraper/refresh-all", h.StartRefreshAll)
+	router.GET("/api/scraper/refresh-all", h.GetRefreshAll)
 	router.GET("/api/scraper/stats", h.GetScraperStats)
+	router.GET("/api/scraper/config", h.GetScraperConfig)
+	router.GET("/api/scraper/ratelimit", h.GetScraperRateLimit)
 	router.GET("/api/scraper/test", h.TestScraperConnection)
+	router.GET("/api/scraper/runs/", h.GetScrapeRuns)
+	router.GET("/api/scraper/failures", h.GetScraperFailures)
+	router.POST("/api/scraper/failures/:id/retry", h.RetryScraperFailure)
+	router.DELETE("/api/scraper/inventory/:seller", h.ClearScraperInventory)
+
+	// Catalog stats
+	router.GET("/api/stats/genres/", cached, h.GetGenreStats)
+	router.GET("/api/stats/decades/", cached, h.GetDecadeStats)
+	router.GET("/api/stats/tags/", cached, h.GetTagStats)
+	router.GET("/api/stats/prices/", cached, h.GetPriceStats)
+	router.GET("/api/conditions/", h.GetConditions)
+
+	// API schema
+	router.GET("/api/schema/", h.GetAPISchema)
 
 	// Legacy compatibility routes
-	router.GET("/api-dashboard/", h.GetDashboard)
+	router.GET("/api-dashboard/", cached, h.GetDashboard)
+}
+
+// pprofHandler serves net/http/pprof's handlers under /debug/pprof/, so CPU,
+// heap and other profiles can be captured from a running server with
+// go tool pprof
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	// Index also serves the named profiles, e.g. /debug/pprof/heap
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
 }
@@ -99,6 +99,30 @@ def train_model():
             'error': f'Internal server error: {str(e)}'
         }), 500
 
+@app.route('/forget', methods=['POST'])
+def forget_samples():
+    """
+    Endpoint to drop listings from the training samples after a review batch
+    was undone. In the real implementation, this would remove the Django
+    training examples and retrain.
+    """
+    try:
+        data = request.get_json()
+        listing_ids = data.get('listing_ids', [])
+
+        logger.info(f"Forgetting {len(listing_ids)} training samples")
+        return jsonify({
+            'success': True,
+            'message': f'Forgot {len(listing_ids)} samples'
+        })
+
+    except Exception as e:
+        logger.error(f"Error in forget endpoint: {str(e)}")
+        return jsonify({
+            'success': False,
+            'error': f'Internal server error: {str(e)}'
+        }), 500
+
 @app.route('/thermodynamic', methods=['POST'])
 def thermodynamic_selection():
     """
@@ -114,8 +138,21 @@ def thermodynamic_selection():
         # Simulate thermodynamic computation
         # time.sleep(0.8)  # Removed to reduce delay for testing
         
+        # A candidate pool restricts the pick to the listings the backend sent
+        candidate_ids = data.get('candidate_ids') or []
+        if data.get('candidate_pool') and not candidate_ids:
+            return jsonify({
+                'success': False,
+                'error': 'no listings in the candidate pool'
+            })
+        
         # Simulate thermodynamic selection results
-        listing_id = random.randint(1, 1000)
+        if candidate_ids:
+            listing_id = random.choice(candidate_ids)
+            total_candidates = len(candidate_ids)
+        else:
+            listing_id = random.randint(1, 1000)
+            total_candidates = random.randint(50, 500)
         model_score = random.uniform(0.5, 1.0)
         entropy_measure = random.uniform(0.0, 1.0)
         system_temperature = random.uniform(0.3, 0.7)
@@ -134,7 +171,7 @@ def thermodynamic_selection():
             'entropy_term': round(entropy_term, 4),
             'free_energy': round(free_energy, 4),
             'selection_probability': round(selection_probability, 4),
-            'total_candidates': random.randint(50, 500),
+            'total_candidates': total_candidates,
             'cluster_count': random.randint(5, 20),
             'selection_method': 'thermodynamic_boltzmann'
         }